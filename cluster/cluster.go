@@ -41,6 +41,8 @@ type NodeInfo struct {
 	DiscoveryService  []DiscoveryService //筛选发现的服务，如果不配置，不进行筛选
 	status            NodeStatus
 	Retire            bool
+
+	mapServiceState map[string]*rpc.ServiceState //非Healthy的服务状态
 }
 
 type NodeRpcInfo struct {
@@ -57,7 +59,12 @@ type Cluster struct {
 	rpcMode       RpcMode
 	globalCfg     interface{} //全局配置
 
+	globalCfgLocker    sync.RWMutex       //全局配置保护锁
+	globalCfgVersion   uint64             //全局配置版本号，配置文件中的初始配置为0
+	globalCfgValidator GlobalCfgValidator //全局配置校验函数
+
 	localServiceCfg  map[string]interface{} //map[serviceName]配置数据*
+	offeredCfg       map[string]interface{} //map[serviceName]最近一次通知服务但尚未生效的配置
 	serviceDiscovery IServiceDiscovery      //服务发现接口
 
 	locker                 sync.RWMutex                   //结点与服务关系保护锁
@@ -71,6 +78,9 @@ type Cluster struct {
 
 	rpcEventLocker           sync.RWMutex        //Rpc事件监听保护锁
 	mapServiceListenRpcEvent map[string]struct{} //ServiceName
+
+	configWatcher configWatcher //配置文件变化监听
+	healthChecker healthChecker //服务健康检查
 }
 
 func GetCluster() *Cluster {
@@ -86,11 +96,22 @@ func SetServiceDiscovery(serviceDiscovery IServiceDiscovery) {
 }
 
 func (cls *Cluster) Start() error {
-	return cls.rpcServer.Start()
+	err := cls.rpcServer.Start()
+	if err != nil {
+		return err
+	}
+
+	cls.configWatcher.addListener(cls.reloadServiceCfg)
+	cls.configWatcher.start()
+	cls.healthChecker.start()
+	return nil
 }
 
 func (cls *Cluster) Stop() {
+	cls.healthChecker.stop()
+	cls.configWatcher.stop()
 	cls.rpcServer.Stop()
+	cls.callSet.UnRegVirtualClock()
 }
 
 func (cls *Cluster) DiscardNode(nodeId string) {
@@ -175,6 +196,12 @@ func (cls *Cluster) serviceDiscoverySetNodeInfo(nodeInfo *NodeInfo) {
 		for _, serviceName := range lastNodeInfo.nodeInfo.ServiceList {
 			cls.delServiceNode(serviceName, nodeInfo.NodeId)
 		}
+
+		//已经下线的服务通知取消发现
+		removeServiceList := diffServiceList(lastNodeInfo.nodeInfo.PublicServiceList, nodeInfo.PublicServiceList)
+		if len(removeServiceList) > 0 {
+			cls.TriggerDiscoveryEvent(false, nodeInfo.NodeId, removeServiceList)
+		}
 	}
 
 	cluster.TriggerDiscoveryEvent(true, nodeInfo.NodeId, nodeInfo.PublicServiceList)
@@ -217,7 +244,7 @@ func (cls *Cluster) serviceDiscoverySetNodeInfo(nodeInfo *NodeInfo) {
 	rpcInfo.nodeInfo = *nodeInfo
 
 	if cls.IsNatsMode() {
-		rpcInfo.client = cls.rpcNats.NewNatsClient(nodeInfo.NodeId, cls.GetLocalNodeInfo().NodeId, &cls.callSet, cls.NotifyAllService)
+		rpcInfo.client = cls.rpcNats.NewNatsClient(nodeInfo.NodeId, cls.localNodeInfo.NodeId, &cls.callSet, cls.NotifyAllService)
 	} else {
 		rpcInfo.client = rpc.NewRClient(nodeInfo.NodeId, nodeInfo.ListenAddr, nodeInfo.MaxRpcParamLen, cls.localNodeInfo.CompressBytesLen, &cls.callSet, cls.NotifyAllService)
 	}
@@ -229,6 +256,26 @@ func (cls *Cluster) serviceDiscoverySetNodeInfo(nodeInfo *NodeInfo) {
 	}
 }
 
+// diffServiceList 返回在oldServiceList中而不在newServiceList中的服务
+func diffServiceList(oldServiceList []string, newServiceList []string) []string {
+	var diffList []string
+	for _, oldService := range oldServiceList {
+		bFind := false
+		for _, newService := range newServiceList {
+			if oldService == newService {
+				bFind = true
+				break
+			}
+		}
+
+		if bFind == false {
+			diffList = append(diffList, oldService)
+		}
+	}
+
+	return diffList
+}
+
 func (cls *Cluster) Init(localNodeId string, setupServiceFun SetupServiceFun) error {
 	//1.初始化配置
 	err := cls.InitCfg(localNodeId)
@@ -237,6 +284,7 @@ func (cls *Cluster) Init(localNodeId string, setupServiceFun SetupServiceFun) er
 	}
 
 	cls.callSet.Init()
+	rpc.SetLocalNodeId(localNodeId)
 	if cls.IsNatsMode() {
 		cls.rpcNats.Init(cls.rpcMode.Nats.NatsUrl, cls.rpcMode.Nats.NoRandomize, cls.GetLocalNodeInfo().NodeId, cls.localNodeInfo.CompressBytesLen, cls, cluster.NotifyAllService)
 		cls.rpcServer = &cls.rpcNats
@@ -254,6 +302,7 @@ func (cls *Cluster) Init(localNodeId string, setupServiceFun SetupServiceFun) er
 	}
 	service.RegRpcEventFun = cls.RegRpcEvent
 	service.UnRegRpcEventFun = cls.UnRegRpcEvent
+	service.RemoteServiceReadyFun = cls.IsServiceReachable
 
 	err = cls.serviceDiscovery.InitDiscovery(localNodeId, cls.serviceDiscoveryDelNode, cls.serviceDiscoverySetNodeInfo)
 	if err != nil {
@@ -333,6 +382,11 @@ func (cls *Cluster) IsNodeRetire(nodeId string) bool {
 	return retire
 }
 
+// GetPendingCallNum 获取本结点未完成的Rpc调用数
+func (cls *Cluster) GetPendingCallNum() int {
+	return cls.callSet.GetPendingNum()
+}
+
 func (cls *Cluster) NotifyAllService(event event.IEvent) {
 	cls.rpcEventLocker.Lock()
 	defer cls.rpcEventLocker.Unlock()
@@ -357,8 +411,13 @@ func (cls *Cluster) TriggerDiscoveryEvent(bDiscovery bool, nodeId string, servic
 	cls.NotifyAllService(&eventData)
 }
 
+// GetLocalNodeInfo 返回本结点信息的副本，服务列表会在运行时加入或移除服务时变化，需要重新获取
 func (cls *Cluster) GetLocalNodeInfo() *NodeInfo {
-	return &cls.localNodeInfo
+	cls.locker.RLock()
+	defer cls.locker.RUnlock()
+
+	nodeInfo := cls.localNodeInfo
+	return &nodeInfo
 }
 
 func (cls *Cluster) RegRpcEvent(serviceName string) {
@@ -429,10 +488,16 @@ func GetNodeByTemplateServiceName(templateServiceName string) map[string]string
 }
 
 func (cls *Cluster) GetGlobalCfg() interface{} {
+	cls.globalCfgLocker.RLock()
+	defer cls.globalCfgLocker.RUnlock()
+
 	return cls.globalCfg
 }
 
 func (cls *Cluster) ParseGlobalCfg(cfg interface{}) error {
+	cls.globalCfgLocker.RLock()
+	defer cls.globalCfgLocker.RUnlock()
+
 	if cls.globalCfg == nil {
 		return errors.New("no service configuration found")
 	}
@@ -470,16 +535,16 @@ func (cls *Cluster) CanDiscoveryService(fromMasterNodeId string, serviceName str
 		serviceName = splitServiceName[0]
 	}
 
-	for i := 0; i < len(cls.GetLocalNodeInfo().DiscoveryService); i++ {
-		masterNodeId := cls.GetLocalNodeInfo().DiscoveryService[i].MasterNodeId
+	for i := 0; i < len(cls.localNodeInfo.DiscoveryService); i++ {
+		masterNodeId := cls.localNodeInfo.DiscoveryService[i].MasterNodeId
 		//无效的配置，则跳过
-		if masterNodeId == rpc.NodeIdNull && len(cls.GetLocalNodeInfo().DiscoveryService[i].ServiceList) == 0 {
+		if masterNodeId == rpc.NodeIdNull && len(cls.localNodeInfo.DiscoveryService[i].ServiceList) == 0 {
 			continue
 		}
 
 		canDiscovery = false
 		if masterNodeId == fromMasterNodeId || masterNodeId == rpc.NodeIdNull {
-			for _, discoveryService := range cls.GetLocalNodeInfo().DiscoveryService[i].ServiceList {
+			for _, discoveryService := range cls.localNodeInfo.DiscoveryService[i].ServiceList {
 				if discoveryService == serviceName {
 					return true
 				}
@@ -1,17 +1,29 @@
 package cluster
 
-import "github.com/duanhf2012/origin/v2/rpc"
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/rpc"
+)
 
 type ConfigDiscovery struct {
 	funDelNode  FunDelNode
 	funSetNode  FunSetNode
 	localNodeId string
+
+	locker        sync.Mutex
+	localNodeInfo NodeInfo            //本结点在配置中的信息
+	mapNodeInfo   map[string]NodeInfo //map[nodeId]NodeInfo 当前生效的其他结点
 }
 
 func (discovery *ConfigDiscovery) InitDiscovery(localNodeId string, funDelNode FunDelNode, funSetNode FunSetNode) error {
 	discovery.localNodeId = localNodeId
 	discovery.funDelNode = funDelNode
 	discovery.funSetNode = funSetNode
+	discovery.mapNodeInfo = map[string]NodeInfo{}
 
 	//解析本地其他服务配置
 	_, nodeInfoList, _, err := GetCluster().readLocalClusterConfig(rpc.NodeIdNull)
@@ -21,11 +33,113 @@ func (discovery *ConfigDiscovery) InitDiscovery(localNodeId string, funDelNode F
 
 	for _, nodeInfo := range nodeInfoList {
 		if nodeInfo.NodeId == localNodeId {
+			discovery.localNodeInfo = nodeInfo
+			continue
+		}
+
+		discovery.mapNodeInfo[nodeInfo.NodeId] = nodeInfo
+		discovery.funSetNode(&nodeInfo)
+	}
+
+	//监听配置变化
+	GetCluster().configWatcher.addListener(discovery.onConfigChanged)
+	return nil
+}
+
+// onConfigChanged 配置文件发生变化，与当前结点比对后增删结点
+func (discovery *ConfigDiscovery) onConfigChanged() {
+	discovery.locker.Lock()
+	defer discovery.locker.Unlock()
+
+	discoveryInfo, nodeInfoList, rpcMode, err := GetCluster().readLocalClusterConfig(rpc.NodeIdNull)
+	if err == nil {
+		err = discovery.checkClusterConfig(&discoveryInfo, nodeInfoList, &rpcMode)
+	}
+
+	if err != nil {
+		log.Error("cluster config is invalid, the old config remains active", log.ErrorField("err", err))
+		return
+	}
+
+	mapNewNodeInfo := make(map[string]NodeInfo, len(nodeInfoList))
+	for _, nodeInfo := range nodeInfoList {
+		if nodeInfo.NodeId == discovery.localNodeId {
+			if reflect.DeepEqual(nodeInfo, discovery.localNodeInfo) == false {
+				log.Warn("local node config has changed, it will take effect after restart", log.String("nodeId", nodeInfo.NodeId))
+			}
+			continue
+		}
+
+		mapNewNodeInfo[nodeInfo.NodeId] = nodeInfo
+	}
+
+	//删除已经移除的结点
+	for nodeId := range discovery.mapNodeInfo {
+		if _, ok := mapNewNodeInfo[nodeId]; ok == false {
+			log.Info("config discovery remove node", log.String("nodeId", nodeId))
+			discovery.funDelNode(nodeId)
+			delete(discovery.mapNodeInfo, nodeId)
+		}
+	}
+
+	//新增或者修改的结点
+	for nodeId, nodeInfo := range mapNewNodeInfo {
+		lastNodeInfo, ok := discovery.mapNodeInfo[nodeId]
+		if ok == true && reflect.DeepEqual(lastNodeInfo, nodeInfo) == true {
 			continue
 		}
 
+		//连接信息变化，需要重新建立连接
+		if ok == true && (lastNodeInfo.ListenAddr != nodeInfo.ListenAddr || lastNodeInfo.MaxRpcParamLen != nodeInfo.MaxRpcParamLen) {
+			discovery.funDelNode(nodeId)
+		}
+
+		log.Info("config discovery set node", log.String("nodeId", nodeId), log.Any("services", nodeInfo.PublicServiceList))
+		discovery.mapNodeInfo[nodeId] = nodeInfo
 		discovery.funSetNode(&nodeInfo)
 	}
+}
+
+// checkClusterConfig 检查新的集群配置，服务发现与Rpc模式不允许在运行中修改
+func (discovery *ConfigDiscovery) checkClusterConfig(discoveryInfo *DiscoveryInfo, nodeInfoList []NodeInfo, rpcMode *RpcMode) error {
+	if discoveryInfo.getDiscoveryType() != InvalidType {
+		return fmt.Errorf("discovery type cannot be changed at runtime")
+	}
+
+	if rpcMode.Typ != GetCluster().rpcMode.Typ {
+		return fmt.Errorf("rpc mode cannot be changed at runtime")
+	}
+
+	bFindLocal := false
+	mapNodeId := make(map[string]struct{}, len(nodeInfoList))
+	mapListenAddr := make(map[string]struct{}, len(nodeInfoList))
+	for _, nodeInfo := range nodeInfoList {
+		if nodeInfo.NodeId == rpc.NodeIdNull {
+			return fmt.Errorf("nodeid cannot be empty")
+		}
+
+		if _, ok := mapNodeId[nodeInfo.NodeId]; ok == true {
+			return fmt.Errorf("nodeid %s is repeat", nodeInfo.NodeId)
+		}
+		mapNodeId[nodeInfo.NodeId] = struct{}{}
+
+		if nodeInfo.NodeId == discovery.localNodeId {
+			bFindLocal = true
+		}
+
+		if GetCluster().IsNatsMode() == true || nodeInfo.ListenAddr == "" {
+			continue
+		}
+
+		if _, ok := mapListenAddr[nodeInfo.ListenAddr]; ok == true {
+			return fmt.Errorf("ListenAddr %s is repeat", nodeInfo.ListenAddr)
+		}
+		mapListenAddr[nodeInfo.ListenAddr] = struct{}{}
+	}
+
+	if bFindLocal == false {
+		return fmt.Errorf("local nodeid %s is not found in NodeList", discovery.localNodeId)
+	}
 
 	return nil
 }
@@ -0,0 +1,135 @@
+package cluster
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/log"
+)
+
+// DefaultConfigWatchInterval 默认配置目录检查间隔
+const DefaultConfigWatchInterval = 5 * time.Second
+
+var configWatchInterval = DefaultConfigWatchInterval
+
+// SetConfigWatchInterval 设置配置目录变化的检查间隔，设置为0时关闭配置热加载，需要在结点启动前设置
+func SetConfigWatchInterval(interval time.Duration) {
+	configWatchInterval = interval
+}
+
+// configWatcher 定时检查配置目录中的配置文件，发生变化时通知所有监听者
+type configWatcher struct {
+	locker      sync.Mutex
+	fingerprint uint64
+	listeners   []func()
+	closeSig    chan struct{}
+}
+
+// addListener 增加配置变化监听，回调在检查协程中执行
+func (cw *configWatcher) addListener(listener func()) {
+	cw.locker.Lock()
+	defer cw.locker.Unlock()
+
+	if len(cw.listeners) == 0 {
+		fingerprint, err := getConfigFingerprint()
+		if err != nil {
+			log.Error("get config fingerprint fail", log.ErrorField("err", err))
+		}
+		cw.fingerprint = fingerprint
+	}
+
+	cw.listeners = append(cw.listeners, listener)
+}
+
+func (cw *configWatcher) start() {
+	cw.locker.Lock()
+	defer cw.locker.Unlock()
+
+	if configWatchInterval <= 0 || len(cw.listeners) == 0 || cw.closeSig != nil {
+		return
+	}
+
+	cw.closeSig = make(chan struct{})
+	go cw.run(cw.closeSig)
+}
+
+func (cw *configWatcher) stop() {
+	cw.locker.Lock()
+	defer cw.locker.Unlock()
+
+	if cw.closeSig == nil {
+		return
+	}
+
+	close(cw.closeSig)
+	cw.closeSig = nil
+}
+
+func (cw *configWatcher) run(closeSig chan struct{}) {
+	ticker := time.NewTicker(configWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closeSig:
+			return
+		case <-ticker.C:
+			cw.check()
+		}
+	}
+}
+
+func (cw *configWatcher) check() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.StackError(fmt.Sprint(r))
+		}
+	}()
+
+	fingerprint, err := getConfigFingerprint()
+	if err != nil {
+		log.Error("get config fingerprint fail", log.ErrorField("err", err))
+		return
+	}
+
+	cw.locker.Lock()
+	if fingerprint == cw.fingerprint {
+		cw.locker.Unlock()
+		return
+	}
+	cw.fingerprint = fingerprint
+	listeners := append([]func(){}, cw.listeners...)
+	cw.locker.Unlock()
+
+	log.Info("config files have changed", log.String("configDir", configDir))
+	for _, listener := range listeners {
+		listener()
+	}
+}
+
+// getConfigFingerprint 根据配置目录下所有配置文件的路径、大小与修改时间计算指纹
+func getConfigFingerprint() (uint64, error) {
+	h := fnv.New64a()
+	err := filepath.Walk(configDir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || !validConfigFile(info.Name()) {
+			return nil
+		}
+
+		_, err = fmt.Fprintf(h, "%s|%d|%d;", path, info.Size(), info.ModTime().UnixNano())
+		return err
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return h.Sum64(), nil
+}
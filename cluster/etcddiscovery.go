@@ -37,6 +37,7 @@ type EtcdDiscoveryService struct {
 
 	byteLocalNodeInfo  string
 	mapClient          map[*clientv3.Client]*etcdClientInfo
+	mainClient         *clientv3.Client //分布式锁与全局配置使用EtcdList中的第一个连接
 	isClose            int32
 	bRetire            bool
 	mapDiscoveryNodeId map[string]map[string]struct{} //map[networkName]map[nodeId]
@@ -62,9 +63,10 @@ func (ed *EtcdDiscoveryService) InitDiscovery(localNodeId string, funDelNode Fun
 }
 
 const (
-	eeGets   = 0
-	eePut    = 1
-	eeDelete = 2
+	eeGets      = 0
+	eePut       = 1
+	eeDelete    = 2
+	eeGlobalCfg = 3
 )
 
 type etcdDiscoveryEvent struct {
@@ -82,6 +84,7 @@ func (ed *EtcdDiscoveryService) OnInit() error {
 	ed.mapDiscoveryNodeId = make(map[string]map[string]struct{})
 
 	ed.GetEventProcessor().RegEventReceiverFunc(event.Sys_Event_EtcdDiscovery, ed.GetEventHandler(), ed.OnEtcdDiscovery)
+	ed.GetEventProcessor().RegEventReceiverFunc(event.Sys_Event_RefreshNodeInfo, ed.GetEventHandler(), ed.onRefreshNodeInfo)
 
 	err := ed.marshalNodeInfo()
 	if err != nil {
@@ -146,6 +149,9 @@ func (ed *EtcdDiscoveryService) OnInit() error {
 		}
 
 		ed.mapClient[client] = ec
+		if ed.mainClient == nil {
+			ed.mainClient = client
+		}
 	}
 
 	return nil
@@ -218,17 +224,22 @@ func (ed *EtcdDiscoveryService) tryWatch(client *clientv3.Client, etcdClient *et
 	})
 }
 
-func (ed *EtcdDiscoveryService) tryLaterRetire() {
+func (ed *EtcdDiscoveryService) tryLaterPutNodeInfo() {
 	ed.AfterFunc(time.Second, func(*timer.Timer) {
-		if ed.retire() != nil {
-			ed.tryLaterRetire()
+		if ed.putNodeInfo() != nil {
+			ed.tryLaterPutNodeInfo()
 		}
 	})
 }
 
-func (ed *EtcdDiscoveryService) retire() error {
+func (ed *EtcdDiscoveryService) putNodeInfo() error {
 	//从etcd中更新
 	for c, ec := range ed.mapClient {
+		//尚未注册成功，注册时会写入最新的结点信息
+		if ec.leaseID == 0 {
+			continue
+		}
+
 		for _, watchKey := range ec.watchKeys {
 			// 注册服务节点到 etcd
 			_, err := c.Put(context.Background(), ed.getRegisterKey(watchKey), ed.byteLocalNodeInfo, clientv3.WithLease(ec.leaseID))
@@ -246,8 +257,25 @@ func (ed *EtcdDiscoveryService) OnRetire() {
 	ed.bRetire = true
 	ed.marshalNodeInfo()
 
-	if ed.retire() != nil {
-		ed.tryLaterRetire()
+	if ed.putNodeInfo() != nil {
+		ed.tryLaterPutNodeInfo()
+	}
+}
+
+// RefreshLocalNodeInfo 本结点信息变化，重新写入etcd
+func (ed *EtcdDiscoveryService) RefreshLocalNodeInfo() {
+	ed.NotifyEvent(&refreshNodeInfoEvent{})
+}
+
+func (ed *EtcdDiscoveryService) onRefreshNodeInfo(_ event.IEvent) {
+	err := ed.marshalNodeInfo()
+	if err != nil {
+		log.Error("marshal node info fail", log.ErrorField("err", err))
+		return
+	}
+
+	if ed.putNodeInfo() != nil {
+		ed.tryLaterPutNodeInfo()
 	}
 }
 
@@ -265,18 +293,15 @@ func (ed *EtcdDiscoveryService) OnStart() {
 		ed.tryRegisterService(c, ec)
 		ed.tryWatch(c, ec)
 	}
+
+	if ed.mainClient != nil {
+		go ed.globalCfgWatcher(ed.mainClient)
+	}
 }
 
 func (ed *EtcdDiscoveryService) marshalNodeInfo() error {
-	nInfo := cluster.GetLocalNodeInfo()
-	var nodeInfo rpc.NodeInfo
-	nodeInfo.NodeId = nInfo.NodeId
-	nodeInfo.ListenAddr = nInfo.ListenAddr
-	nodeInfo.Retire = ed.bRetire
-	nodeInfo.PublicServiceList = nInfo.PublicServiceList
-	nodeInfo.MaxRpcParamLen = nInfo.MaxRpcParamLen
-
-	byteLocalNodeInfo, err := proto.Marshal(&nodeInfo)
+	nodeInfo := cluster.newLocalRpcNodeInfo(ed.bRetire)
+	byteLocalNodeInfo, err := proto.Marshal(nodeInfo)
 	if err == nil {
 		ed.byteLocalNodeInfo = string(byteLocalNodeInfo)
 	}
@@ -309,6 +334,7 @@ func (ed *EtcdDiscoveryService) setNodeInfo(networkName string, nodeInfo *rpc.No
 	nInfo.MaxRpcParamLen = nodeInfo.MaxRpcParamLen
 	nInfo.Retire = nodeInfo.Retire
 	nInfo.Private = nodeInfo.Private
+	nInfo.mapServiceState = newServiceStateMap(nodeInfo.ServiceStateList)
 
 	ed.funSetNode(&nInfo)
 
@@ -428,6 +454,10 @@ func (ed *EtcdDiscoveryService) OnEtcdDiscovery(ev event.IEvent) {
 		if len(disEvent.Kvs) == 1 {
 			ed.OnEventDelete(disEvent.watchKey, disEvent.Kvs[0])
 		}
+	case eeGlobalCfg:
+		if len(disEvent.Kvs) == 1 {
+			ed.onGlobalCfg(disEvent.Kvs[0])
+		}
 	}
 }
 
@@ -483,6 +513,11 @@ func (ed *EtcdDiscoveryService) OnEventPut(watchKey string, Kv *mvccpb.KeyValue)
 func (ed *EtcdDiscoveryService) OnEventDelete(watchKey string, Kv *mvccpb.KeyValue) {
 	nodeId := ed.delNode(string(Kv.Key))
 	delete(ed.mapDiscoveryNodeId[watchKey], nodeId)
+
+	//结点下线，释放其持有的分布式锁
+	if nodeId != "" && ed.mainClient != nil {
+		go ed.releaseNodeLocks(ed.mainClient, nodeId)
+	}
 }
 
 func (ed *EtcdDiscoveryService) addNodeId(watchKey string, nodeId string) {
@@ -0,0 +1,235 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/rpc"
+	"github.com/duanhf2012/origin/v2/util/timer"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/client/v3"
+)
+
+const globalCfgDir = "_config"
+
+// globalCfgAckInterval 推送后轮询结点确认的间隔
+const globalCfgAckInterval = 200 * time.Millisecond
+
+func getEtcdGlobalCfgKey() string {
+	return path.Join(originDir, globalCfgDir, "global")
+}
+
+func getEtcdGlobalCfgAckDir() string {
+	return path.Join(originDir, globalCfgDir, "global_ack") + "/"
+}
+
+// RPC_PushGlobalCfg 写入新版本的全局配置，各结点通过watch收到后写入确认，等待确认结果后返回
+func (ed *EtcdDiscoveryService) RPC_PushGlobalCfg(responder rpc.Responder, doc *GlobalCfgDoc) {
+	//需要确认的结点为当前发现的所有结点
+	mapNodeId := map[string]struct{}{ed.localNodeId: {}}
+	for _, mapDiscoveryNodeId := range ed.mapDiscoveryNodeId {
+		for nodeId := range mapDiscoveryNodeId {
+			if nodeId != "" {
+				mapNodeId[nodeId] = struct{}{}
+			}
+		}
+	}
+
+	client := ed.mainClient
+	go func() {
+		res, err := etcdPushGlobalCfg(client, doc, mapNodeId)
+		responder(res, rpc.ConvertError(err))
+	}()
+}
+
+func etcdPushGlobalCfg(client *clientv3.Client, doc *GlobalCfgDoc, mapNodeId map[string]struct{}) (*PushGlobalCfgRes, error) {
+	res := newPushGlobalCfgRes(doc.Version)
+	if client == nil {
+		return res, errors.New("etcd client is not ready")
+	}
+
+	byteDoc, err := json.Marshal(doc)
+	if err != nil {
+		return res, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	key := getEtcdGlobalCfgKey()
+	resp, err := client.Get(ctx, key)
+	if err != nil {
+		return res, err
+	}
+
+	var modRevision int64
+	if len(resp.Kvs) > 0 {
+		var lastDoc GlobalCfgDoc
+		err = json.Unmarshal(resp.Kvs[0].Value, &lastDoc)
+		if err != nil {
+			return res, err
+		}
+
+		if doc.Version <= lastDoc.Version {
+			return res, fmt.Errorf("global config version %d is not newer than version %d", doc.Version, lastDoc.Version)
+		}
+		modRevision = resp.Kvs[0].ModRevision
+	}
+
+	//只有在读取之后未被修改时才写入，避免并发推送时版本回退
+	txnResp, err := client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", modRevision)).
+		Then(clientv3.OpPut(key, string(byteDoc))).
+		Commit()
+	if err != nil {
+		return res, err
+	}
+
+	if txnResp.Succeeded == false {
+		return res, errors.New("global config is modified by others at the same time")
+	}
+
+	log.Info("push global config", log.Uint64("version", doc.Version))
+
+	//轮询等待各结点的确认
+	mapAck := map[string]*GlobalCfgAck{}
+	deadline := time.Now().Add(DefaultGlobalCfgAckTimeout)
+	for {
+		getAckCtx, getAckCancel := context.WithTimeout(context.Background(), time.Second*3)
+		ackResp, err := client.Get(getAckCtx, getEtcdGlobalCfgAckDir(), clientv3.WithPrefix())
+		getAckCancel()
+		if err != nil {
+			log.Warn("etcd get global config ack fail", log.ErrorField("err", err))
+		} else {
+			for _, kv := range ackResp.Kvs {
+				var ack GlobalCfgAck
+				if json.Unmarshal(kv.Value, &ack) == nil {
+					mapAck[ack.NodeId] = &ack
+				}
+			}
+		}
+
+		bFinish := true
+		for nodeId := range mapNodeId {
+			ack, ok := mapAck[nodeId]
+			if ok == false || (ack.Version < doc.Version) {
+				bFinish = false
+				break
+			}
+		}
+
+		if bFinish == true || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(globalCfgAckInterval)
+	}
+
+	for nodeId := range mapNodeId {
+		ack, ok := mapAck[nodeId]
+		switch {
+		case ok == false || ack.Version < doc.Version:
+			res.FailNodeList[nodeId] = "ack timeout"
+		case ack.isAcked(doc.Version) == false:
+			res.FailNodeList[nodeId] = ack.Err
+		default:
+			res.AckNodeList = append(res.AckNodeList, nodeId)
+		}
+	}
+
+	return res, nil
+}
+
+func (ed *EtcdDiscoveryService) tryWatchGlobalCfg(client *clientv3.Client) {
+	if ed.isStop() {
+		return
+	}
+
+	ed.AfterFunc(time.Second*3, func(t *timer.Timer) {
+		go ed.globalCfgWatcher(client)
+	})
+}
+
+// globalCfgWatcher 监听全局配置，启动时先读取一次已推送的配置
+func (ed *EtcdDiscoveryService) globalCfgWatcher(client *clientv3.Client) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.StackError(fmt.Sprint(r))
+			ed.tryWatchGlobalCfg(client)
+		}
+	}()
+
+	key := getEtcdGlobalCfgKey()
+	rch := client.Watch(context.Background(), key)
+
+	resp, err := client.Get(context.Background(), key)
+	if err != nil {
+		log.Error("etcd Get fail", log.ErrorField("err", err))
+		ed.tryWatchGlobalCfg(client)
+		return
+	}
+
+	if len(resp.Kvs) > 0 {
+		ed.notifyGlobalCfg(resp.Kvs[0])
+	}
+
+	for wresp := range rch {
+		for _, ev := range wresp.Events {
+			if ev.Type == clientv3.EventTypePut {
+				ed.notifyGlobalCfg(ev.Kv)
+			}
+		}
+	}
+
+	ed.tryWatchGlobalCfg(client)
+}
+
+func (ed *EtcdDiscoveryService) notifyGlobalCfg(kv *mvccpb.KeyValue) {
+	var ev etcdDiscoveryEvent
+	ev.typ = eeGlobalCfg
+	ev.Kvs = append(ev.Kvs, kv)
+	ed.NotifyEvent(&ev)
+}
+
+// onGlobalCfg 收到全局配置，应用后写入确认
+func (ed *EtcdDiscoveryService) onGlobalCfg(kv *mvccpb.KeyValue) {
+	var doc GlobalCfgDoc
+	err := json.Unmarshal(kv.Value, &doc)
+	if err != nil {
+		log.Error("unmarshal global config fail", log.ErrorField("err", err))
+		return
+	}
+
+	ack := GlobalCfgAck{NodeId: ed.localNodeId, Version: doc.Version}
+	err = cluster.applyGlobalCfg(&doc)
+	if err != nil {
+		log.Error("apply global config fail", log.Uint64("version", doc.Version), log.ErrorField("err", err))
+		ack.Err = err.Error()
+	}
+
+	byteAck, err := json.Marshal(&ack)
+	if err != nil {
+		log.Error("marshal global config ack fail", log.ErrorField("err", err))
+		return
+	}
+
+	//确认信息与结点注册使用同一个租约，结点下线后自动删除
+	var opts []clientv3.OpOption
+	if ec, ok := ed.mapClient[ed.mainClient]; ok == true && ec.leaseID != 0 {
+		opts = append(opts, clientv3.WithLease(ec.leaseID))
+	}
+
+	client := ed.mainClient
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+		defer cancel()
+
+		_, err := client.Put(ctx, getEtcdGlobalCfgAckDir()+ed.localNodeId, string(byteAck), opts...)
+		if err != nil {
+			log.Error("etcd put global config ack fail", log.ErrorField("err", err))
+		}
+	}()
+}
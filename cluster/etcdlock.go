@@ -0,0 +1,228 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/rpc"
+	"go.etcd.io/etcd/client/v3"
+)
+
+const lockDir = "_lock"
+
+type etcdLockValue struct {
+	NodeId  string
+	Owner   string
+	LeaseId int64
+}
+
+func getEtcdLockKey(lockName string) string {
+	return path.Join(originDir, lockDir, lockName)
+}
+
+// RPC_AcquireLock 获取分布式锁，etcd操作在独立协程中完成，不阻塞服务发现
+func (ed *EtcdDiscoveryService) RPC_AcquireLock(responder rpc.Responder, req *LockReq) {
+	client := ed.mainClient
+	go func() {
+		var res LockRes
+		err := etcdAcquireLock(client, req, &res)
+		responder(&res, rpc.ConvertError(err))
+	}()
+}
+
+// RPC_RenewLock 分布式锁续约
+func (ed *EtcdDiscoveryService) RPC_RenewLock(responder rpc.Responder, req *LockReq) {
+	client := ed.mainClient
+	go func() {
+		var res LockRes
+		err := etcdRenewLock(client, req, &res)
+		responder(&res, rpc.ConvertError(err))
+	}()
+}
+
+// RPC_ReleaseLock 释放分布式锁
+func (ed *EtcdDiscoveryService) RPC_ReleaseLock(responder rpc.Responder, req *LockReq) {
+	client := ed.mainClient
+	go func() {
+		var res LockRes
+		err := etcdReleaseLock(client, req)
+		responder(&res, rpc.ConvertError(err))
+	}()
+}
+
+// getEtcdLock 查询锁当前的持有信息，不存在时返回nil
+func getEtcdLock(ctx context.Context, client *clientv3.Client, lockName string) (*etcdLockValue, int64, error) {
+	resp, err := client.Get(ctx, getEtcdLockKey(lockName))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(resp.Kvs) == 0 {
+		return nil, 0, nil
+	}
+
+	var lockValue etcdLockValue
+	err = json.Unmarshal(resp.Kvs[0].Value, &lockValue)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return &lockValue, resp.Kvs[0].CreateRevision, nil
+}
+
+func etcdAcquireLock(client *clientv3.Client, req *LockReq, res *LockRes) error {
+	if client == nil {
+		return errors.New("etcd client is not ready")
+	}
+
+	if req.LockName == "" || req.TTLMillisecond <= 0 {
+		return fmt.Errorf("invalid lock request %s", req.LockName)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultLockRpcTimeout)
+	defer cancel()
+
+	lockValue, createRevision, err := getEtcdLock(ctx, client, req.LockName)
+	if err != nil {
+		return err
+	}
+
+	//已被持有，重复获取视为续约
+	if lockValue != nil {
+		if lockValue.NodeId != req.NodeId || lockValue.Owner != req.Owner {
+			res.HolderNode = lockValue.NodeId
+			res.HolderOwner = lockValue.Owner
+			return nil
+		}
+
+		_, err = client.KeepAliveOnce(ctx, clientv3.LeaseID(lockValue.LeaseId))
+		if err != nil {
+			return err
+		}
+
+		res.Acquired = true
+		res.Token = uint64(createRevision)
+		return nil
+	}
+
+	//etcd租约最小单位为秒
+	ttlSecond := (req.TTLMillisecond + 999) / 1000
+	lease, err := client.Grant(ctx, ttlSecond)
+	if err != nil {
+		return err
+	}
+
+	byteValue, err := json.Marshal(&etcdLockValue{NodeId: req.NodeId, Owner: req.Owner, LeaseId: int64(lease.ID)})
+	if err != nil {
+		client.Revoke(ctx, lease.ID)
+		return err
+	}
+
+	key := getEtcdLockKey(req.LockName)
+	txnResp, err := client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, string(byteValue), clientv3.WithLease(lease.ID))).
+		Else(clientv3.OpGet(key)).
+		Commit()
+	if err != nil {
+		client.Revoke(ctx, lease.ID)
+		return err
+	}
+
+	//被其他结点抢先获取
+	if txnResp.Succeeded == false {
+		client.Revoke(ctx, lease.ID)
+		if len(txnResp.Responses) > 0 && len(txnResp.Responses[0].GetResponseRange().Kvs) > 0 {
+			var holder etcdLockValue
+			if json.Unmarshal(txnResp.Responses[0].GetResponseRange().Kvs[0].Value, &holder) == nil {
+				res.HolderNode = holder.NodeId
+				res.HolderOwner = holder.Owner
+			}
+		}
+		return nil
+	}
+
+	//使用key的创建版本号作为fencing token
+	res.Acquired = true
+	res.Token = uint64(txnResp.Header.Revision)
+	return nil
+}
+
+func etcdRenewLock(client *clientv3.Client, req *LockReq, res *LockRes) error {
+	if client == nil {
+		return errors.New("etcd client is not ready")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultLockRpcTimeout)
+	defer cancel()
+
+	lockValue, createRevision, err := getEtcdLock(ctx, client, req.LockName)
+	if err != nil {
+		return err
+	}
+
+	if lockValue == nil || uint64(createRevision) != req.Token {
+		return fmt.Errorf("lock %s lease is lost", req.LockName)
+	}
+
+	_, err = client.KeepAliveOnce(ctx, clientv3.LeaseID(lockValue.LeaseId))
+	if err != nil {
+		return err
+	}
+
+	res.Acquired = true
+	res.Token = req.Token
+	return nil
+}
+
+func etcdReleaseLock(client *clientv3.Client, req *LockReq) error {
+	if client == nil {
+		return errors.New("etcd client is not ready")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultLockRpcTimeout)
+	defer cancel()
+
+	lockValue, createRevision, err := getEtcdLock(ctx, client, req.LockName)
+	if err != nil {
+		return err
+	}
+
+	if lockValue == nil || uint64(createRevision) != req.Token {
+		return fmt.Errorf("lock %s lease is lost", req.LockName)
+	}
+
+	//撤销租约同时删除key
+	_, err = client.Revoke(ctx, clientv3.LeaseID(lockValue.LeaseId))
+	return err
+}
+
+// releaseNodeLocks 结点下线后，释放其持有的所有锁
+func (ed *EtcdDiscoveryService) releaseNodeLocks(client *clientv3.Client, nodeId string) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultLockRpcTimeout)
+	defer cancel()
+
+	resp, err := client.Get(ctx, path.Join(originDir, lockDir)+"/", clientv3.WithPrefix())
+	if err != nil {
+		log.Error("etcd get locks fail", log.ErrorField("err", err))
+		return
+	}
+
+	for _, kv := range resp.Kvs {
+		var lockValue etcdLockValue
+		if json.Unmarshal(kv.Value, &lockValue) != nil || lockValue.NodeId != nodeId {
+			continue
+		}
+
+		_, err = client.Revoke(ctx, clientv3.LeaseID(lockValue.LeaseId))
+		if err != nil {
+			log.Error("etcd revoke lock lease fail", log.String("lockKey", string(kv.Key)), log.ErrorField("err", err))
+			continue
+		}
+
+		log.Info("release lock of offline node", log.String("lockKey", string(kv.Key)), log.String("nodeId", nodeId))
+	}
+}
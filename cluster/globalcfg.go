@@ -0,0 +1,212 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/rpc"
+	"github.com/duanhf2012/origin/v2/service"
+)
+
+// DefaultGlobalCfgAckTimeout 推送全局配置时等待各结点确认的超时时间
+var DefaultGlobalCfgAckTimeout = 5 * time.Second
+
+var ErrGlobalCfgPushNotSupported = errors.New("global config push requires etcd or origin discovery")
+
+const (
+	etcdPushGlobalCfgMethod     = "EtcdDiscoveryService.RPC_PushGlobalCfg"
+	originPushGlobalCfgMethod   = OriginDiscoveryMasterName + ".RPC_PushGlobalCfg"
+	originGetGlobalCfgMethod    = OriginDiscoveryMasterName + ".RPC_GetGlobalCfg"
+	originUpdateGlobalCfgMethod = OriginDiscoveryClientName + ".RPC_UpdateGlobalCfg"
+)
+
+// GlobalCfgValidator 全局配置校验函数，返回错误时拒绝该配置
+type GlobalCfgValidator func(globalCfg interface{}) error
+
+// GlobalCfgDoc 带版本号的全局配置
+type GlobalCfgDoc struct {
+	Version uint64
+	Global  interface{}
+}
+
+// GlobalCfgAck 结点对全局配置的应答
+type GlobalCfgAck struct {
+	NodeId  string
+	Version uint64 //应答的配置版本号
+	Err     string //应用失败原因，为空表示成功
+}
+
+// PushGlobalCfgRes 全局配置推送结果
+type PushGlobalCfgRes struct {
+	Version      uint64
+	AckNodeList  []string          //已确认的结点
+	FailNodeList map[string]string //map[nodeId]失败原因，包括校验失败与超时未确认
+}
+
+func newPushGlobalCfgRes(version uint64) *PushGlobalCfgRes {
+	return &PushGlobalCfgRes{Version: version, FailNodeList: map[string]string{}}
+}
+
+// isAcked 结点是否已确认该版本，结点已应用更新的版本也视为确认
+func (ack *GlobalCfgAck) isAcked(version uint64) bool {
+	return ack.Version > version || (ack.Version == version && ack.Err == "")
+}
+
+// SetGlobalCfgValidator 设置全局配置校验函数，推送方与接收方都会进行校验
+func (cls *Cluster) SetGlobalCfgValidator(validator GlobalCfgValidator) {
+	cls.globalCfgLocker.Lock()
+	defer cls.globalCfgLocker.Unlock()
+
+	cls.globalCfgValidator = validator
+}
+
+// GetGlobalCfgVersion 获取当前生效的全局配置版本号
+func (cls *Cluster) GetGlobalCfgVersion() uint64 {
+	cls.globalCfgLocker.RLock()
+	defer cls.globalCfgLocker.RUnlock()
+
+	return cls.globalCfgVersion
+}
+
+func (cls *Cluster) checkGlobalCfg(globalCfg interface{}) error {
+	cls.globalCfgLocker.RLock()
+	validator := cls.globalCfgValidator
+	cls.globalCfgLocker.RUnlock()
+
+	if globalCfg == nil {
+		return errors.New("global config is nil")
+	}
+
+	if validator == nil {
+		return nil
+	}
+
+	return validator(globalCfg)
+}
+
+// applyGlobalCfg 应用新版本的全局配置，并通知所有服务，重复的版本直接确认
+func (cls *Cluster) applyGlobalCfg(doc *GlobalCfgDoc) error {
+	currentVersion := cls.GetGlobalCfgVersion()
+	if doc.Version == currentVersion {
+		return nil
+	}
+
+	if doc.Version < currentVersion {
+		return fmt.Errorf("global config version %d is older than current version %d", doc.Version, currentVersion)
+	}
+
+	err := cls.checkGlobalCfg(doc.Global)
+	if err != nil {
+		return err
+	}
+
+	cls.globalCfgLocker.Lock()
+	if doc.Version <= cls.globalCfgVersion {
+		cls.globalCfgLocker.Unlock()
+		return nil
+	}
+	oldCfg := cls.globalCfg
+	cls.globalCfg = doc.Global
+	cls.globalCfgVersion = doc.Version
+	cls.globalCfgLocker.Unlock()
+
+	log.Info("global config has changed", log.Uint64("version", doc.Version))
+	service.NotifyAllServiceGlobalConfigChanged(oldCfg, doc.Global)
+	return nil
+}
+
+// PushGlobalCfg 推送新的全局配置到集群所有结点，version为0时使用本结点当前版本号加1
+// 推送结果在调用服务的协程中回调，返回已确认与未确认的结点
+func PushGlobalCfg(rpcHandler rpc.IRpcHandler, version uint64, globalCfg interface{}, cb func(res *PushGlobalCfgRes, err error)) error {
+	if version == 0 {
+		version = cluster.GetGlobalCfgVersion() + 1
+	}
+
+	err := cluster.checkGlobalCfg(globalCfg)
+	if err != nil {
+		return err
+	}
+
+	doc := &GlobalCfgDoc{Version: version, Global: globalCfg}
+	timeout := DefaultGlobalCfgAckTimeout + 3*time.Second
+	switch cluster.discoveryInfo.getDiscoveryType() {
+	case EtcdType:
+		_, err = rpcHandler.AsyncCallNodeWithTimeout(timeout, cluster.GetLocalNodeInfo().NodeId, etcdPushGlobalCfgMethod, doc, cb)
+		return err
+	case OriginType:
+		return pushGlobalCfgToMaster(rpcHandler, timeout, doc, cb)
+	}
+
+	return ErrGlobalCfgPushNotSupported
+}
+
+// pushGlobalCfgToMaster 推送到所有Master结点，由Master广播到各自注册的结点，最后合并结果
+func pushGlobalCfgToMaster(rpcHandler rpc.IRpcHandler, timeout time.Duration, doc *GlobalCfgDoc, cb func(res *PushGlobalCfgRes, err error)) error {
+	res := newPushGlobalCfgRes(doc.Version)
+	mapAck := map[string]struct{}{}
+	mapPending := map[string]struct{}{}
+	bCallFinish := false
+	bDone := false
+	var lastErr error
+
+	finish := func() {
+		if bDone == true || bCallFinish == false || len(mapPending) > 0 {
+			return
+		}
+		bDone = true
+
+		if len(mapAck) == 0 && lastErr != nil {
+			cb(nil, lastErr)
+			return
+		}
+
+		//同一结点可能注册在多个Master，任一Master确认即可
+		for nodeId := range mapAck {
+			res.AckNodeList = append(res.AckNodeList, nodeId)
+			delete(res.FailNodeList, nodeId)
+		}
+		cb(res, nil)
+	}
+
+	masterNodeList := cluster.GetOriginDiscovery().MasterNodeList
+	for i := 0; i < len(masterNodeList); i++ {
+		mapPending[masterNodeList[i].NodeId] = struct{}{}
+	}
+
+	for i := 0; i < len(masterNodeList); i++ {
+		masterNodeId := masterNodeList[i].NodeId
+		_, err := rpcHandler.AsyncCallNodeWithTimeout(timeout, masterNodeId, originPushGlobalCfgMethod, doc, func(masterRes *PushGlobalCfgRes, err error) {
+			if _, ok := mapPending[masterNodeId]; ok == false {
+				return
+			}
+			delete(mapPending, masterNodeId)
+
+			if err != nil {
+				log.Warn("push global config to master fail", log.String("masterNodeId", masterNodeId), log.ErrorField("err", err))
+				lastErr = err
+			} else {
+				for _, nodeId := range masterRes.AckNodeList {
+					mapAck[nodeId] = struct{}{}
+				}
+				for nodeId, reason := range masterRes.FailNodeList {
+					res.FailNodeList[nodeId] = reason
+				}
+			}
+
+			finish()
+		})
+
+		if err != nil {
+			bDone = true
+			return err
+		}
+	}
+
+	//Master的结果可能在调用中同步返回，全部发起后再检查是否完成
+	bCallFinish = true
+	finish()
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package cluster
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/duanhf2012/origin/v2/event"
+	"github.com/duanhf2012/origin/v2/rpc"
+)
+
+type refreshNodeInfoEvent struct {
+}
+
+func (ev *refreshNodeInfoEvent) GetEventType() event.EventType {
+	return event.Sys_Event_RefreshNodeInfo
+}
+
+// newLocalRpcNodeInfo 生成本结点对外发布的结点信息
+func (cls *Cluster) newLocalRpcNodeInfo(retire bool) *rpc.NodeInfo {
+	cls.locker.RLock()
+	defer cls.locker.RUnlock()
+
+	var nodeInfo rpc.NodeInfo
+	nodeInfo.NodeId = cls.localNodeInfo.NodeId
+	nodeInfo.ListenAddr = cls.localNodeInfo.ListenAddr
+	nodeInfo.MaxRpcParamLen = cls.localNodeInfo.MaxRpcParamLen
+	nodeInfo.PublicServiceList = cls.localNodeInfo.PublicServiceList
+	nodeInfo.Private = cls.localNodeInfo.Private
+	nodeInfo.Retire = retire
+	nodeInfo.ServiceStateList = cls.getLocalServiceStateList()
+
+	return &nodeInfo
+}
+
+// newNodeInfo 通过服务发现同步过来的结点信息生成NodeInfo
+func newNodeInfo(rpcNodeInfo *rpc.NodeInfo) *NodeInfo {
+	var nodeInfo NodeInfo
+	nodeInfo.NodeId = rpcNodeInfo.NodeId
+	nodeInfo.Private = rpcNodeInfo.Private
+	nodeInfo.ServiceList = rpcNodeInfo.PublicServiceList
+	nodeInfo.PublicServiceList = rpcNodeInfo.PublicServiceList
+	nodeInfo.ListenAddr = rpcNodeInfo.ListenAddr
+	nodeInfo.MaxRpcParamLen = rpcNodeInfo.MaxRpcParamLen
+	nodeInfo.Retire = rpcNodeInfo.Retire
+	nodeInfo.mapServiceState = newServiceStateMap(rpcNodeInfo.ServiceStateList)
+
+	return &nodeInfo
+}
+
+// AddLocalService 运行时加入本地服务，templateServiceName为空表示非模板服务
+func (cls *Cluster) AddLocalService(serviceName string, templateServiceName string, bPublic bool, serviceCfg interface{}) error {
+	cls.locker.Lock()
+	localNodeId := cls.localNodeInfo.NodeId
+	if _, ok := cls.mapServiceNode[serviceName][localNodeId]; ok == true {
+		cls.locker.Unlock()
+		return fmt.Errorf("duplicate service %s is configured in node %s", serviceName, localNodeId)
+	}
+
+	cfgServiceName := serviceName
+	if templateServiceName != "" {
+		cfgServiceName = serviceName + ":" + templateServiceName
+		if _, ok := cls.mapTemplateServiceNode[templateServiceName]; ok == false {
+			cls.mapTemplateServiceNode[templateServiceName] = map[string]struct{}{}
+		}
+		cls.mapTemplateServiceNode[templateServiceName][serviceName] = struct{}{}
+	}
+
+	if _, ok := cls.mapServiceNode[serviceName]; ok == false {
+		cls.mapServiceNode[serviceName] = map[string]struct{}{}
+	}
+	cls.mapServiceNode[serviceName][localNodeId] = struct{}{}
+
+	//重新分配切片，避免与正在读取的旧列表冲突
+	cls.localNodeInfo.ServiceList = append(append([]string{}, cls.localNodeInfo.ServiceList...), cfgServiceName)
+	if bPublic == true && cls.localNodeInfo.Private == false {
+		cls.localNodeInfo.PublicServiceList = append(append([]string{}, cls.localNodeInfo.PublicServiceList...), cfgServiceName)
+	}
+
+	if serviceCfg != nil {
+		cls.localServiceCfg[serviceName] = serviceCfg
+	}
+
+	if rpcInfo, ok := cls.mapRpc[localNodeId]; ok == true {
+		rpcInfo.nodeInfo = cls.localNodeInfo
+	}
+	cls.locker.Unlock()
+
+	cls.RefreshLocalNodeInfo()
+	return nil
+}
+
+// RemoveLocalService 运行时移除本地服务
+func (cls *Cluster) RemoveLocalService(serviceName string) error {
+	cls.locker.Lock()
+	localNodeId := cls.localNodeInfo.NodeId
+	if _, ok := cls.mapServiceNode[serviceName][localNodeId]; ok == false {
+		cls.locker.Unlock()
+		return fmt.Errorf("service %s is not found in node %s", serviceName, localNodeId)
+	}
+
+	cls.localNodeInfo.ServiceList = removeServiceName(cls.localNodeInfo.ServiceList, serviceName)
+	cls.localNodeInfo.PublicServiceList = removeServiceName(cls.localNodeInfo.PublicServiceList, serviceName)
+
+	for templateServiceName, mapService := range cls.mapTemplateServiceNode {
+		delete(mapService, serviceName)
+		if len(mapService) == 0 {
+			delete(cls.mapTemplateServiceNode, templateServiceName)
+		}
+	}
+
+	delete(cls.mapServiceNode[serviceName], localNodeId)
+	if len(cls.mapServiceNode[serviceName]) == 0 {
+		delete(cls.mapServiceNode, serviceName)
+	}
+	delete(cls.localServiceCfg, serviceName)
+	delete(cls.offeredCfg, serviceName)
+
+	if rpcInfo, ok := cls.mapRpc[localNodeId]; ok == true {
+		rpcInfo.nodeInfo = cls.localNodeInfo
+	}
+	cls.locker.Unlock()
+
+	cls.RefreshLocalNodeInfo()
+	return nil
+}
+
+// IsLocalTemplateService 是否为本结点的模板服务实例
+func (cls *Cluster) IsLocalTemplateService(serviceName string) bool {
+	cls.locker.RLock()
+	defer cls.locker.RUnlock()
+
+	for _, s := range cls.localNodeInfo.ServiceList {
+		if strings.HasPrefix(s, serviceName+":") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RefreshLocalNodeInfo 通知服务发现重新发布本结点信息
+func (cls *Cluster) RefreshLocalNodeInfo() {
+	refresher, ok := cls.serviceDiscovery.(INodeInfoRefresher)
+	if ok == false {
+		return
+	}
+
+	refresher.RefreshLocalNodeInfo()
+}
+
+// removeServiceName 从服务列表中移除服务，兼容name:template格式，返回新的切片
+func removeServiceName(serviceList []string, serviceName string) []string {
+	newServiceList := make([]string, 0, len(serviceList))
+	for _, s := range serviceList {
+		if s == serviceName || strings.HasPrefix(s, serviceName+":") {
+			continue
+		}
+		newServiceList = append(newServiceList, s)
+	}
+
+	return newServiceList
+}
@@ -0,0 +1,285 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/rpc"
+	"github.com/duanhf2012/origin/v2/util/timer"
+)
+
+// DefaultLockRpcTimeout 分布式锁Rpc调用超时时间
+var DefaultLockRpcTimeout = 3 * time.Second
+
+var ErrLockHeld = errors.New("lock is held by others")
+var ErrLockNotSupported = errors.New("distributed lock requires etcd or origin discovery")
+
+const (
+	etcdAcquireLockMethod   = "EtcdDiscoveryService.RPC_AcquireLock"
+	etcdRenewLockMethod     = "EtcdDiscoveryService.RPC_RenewLock"
+	etcdReleaseLockMethod   = "EtcdDiscoveryService.RPC_ReleaseLock"
+	originAcquireLockMethod = OriginDiscoveryMasterName + ".RPC_AcquireLock"
+	originRenewLockMethod   = OriginDiscoveryMasterName + ".RPC_RenewLock"
+	originReleaseLockMethod = OriginDiscoveryMasterName + ".RPC_ReleaseLock"
+)
+
+type LockReq struct {
+	LockName       string
+	NodeId         string //持有者结点
+	Owner          string //持有者标识，默认为服务名
+	TTLMillisecond int64
+	Token          uint64 //续约与释放时校验的fencing token
+}
+
+type LockRes struct {
+	Acquired    bool
+	Token       uint64 //fencing token，单调递增
+	HolderNode  string //未获取到锁时，当前的持有结点
+	HolderOwner string
+}
+
+// LockLease 获取到的锁租约
+type LockLease struct {
+	LockName   string
+	Owner      string
+	Token      uint64        //fencing token，写入外部资源时带上，拒绝比已知更小的token
+	TTL        time.Duration //租约时长
+	ExpireTime time.Time     //本地估算的过期时间，需要在此之前续约
+}
+
+// getLockTarget 获取锁服务所在结点与方法
+func getLockTarget(etcdMethod string, originMethod string) (string, string, error) {
+	switch cluster.discoveryInfo.getDiscoveryType() {
+	case EtcdType:
+		return cluster.GetLocalNodeInfo().NodeId, etcdMethod, nil
+	case OriginType:
+		nodeId, err := selectLockMaster(cluster.GetOriginDiscovery().MasterNodeList, cluster.GetLocalNodeInfo().NodeId, cluster.IsNodeConnected)
+		return nodeId, originMethod, err
+	}
+
+	return rpc.NodeIdNull, "", ErrLockNotSupported
+}
+
+// selectLockMaster 按配置顺序选择第一个可连接的Master结点管理锁，都不可连接时使用第一个
+// Master之间不同步锁表，切换Master后原持有者续约失败，新Master发放的token仍然更大
+func selectLockMaster(masterNodeList []NodeInfo, localNodeId string, isConnected func(nodeId string) bool) (string, error) {
+	if len(masterNodeList) == 0 {
+		return rpc.NodeIdNull, ErrLockNotSupported
+	}
+
+	for i := range masterNodeList {
+		nodeId := masterNodeList[i].NodeId
+		if nodeId == localNodeId || isConnected(nodeId) == true {
+			return nodeId, nil
+		}
+	}
+
+	return masterNodeList[0].NodeId, nil
+}
+
+func newLockReq(rpcHandler rpc.IRpcHandler, lockName string, ttl time.Duration, token uint64) *LockReq {
+	return &LockReq{
+		LockName:       lockName,
+		NodeId:         cluster.GetLocalNodeInfo().NodeId,
+		Owner:          rpcHandler.GetName(),
+		TTLMillisecond: ttl.Milliseconds(),
+		Token:          token,
+	}
+}
+
+func newLockLease(req *LockReq, res *LockRes, startTime time.Time) (*LockLease, error) {
+	if res.Acquired == false {
+		return nil, fmt.Errorf("%w: lock %s holder node %s owner %s", ErrLockHeld, req.LockName, res.HolderNode, res.HolderOwner)
+	}
+
+	ttl := time.Duration(req.TTLMillisecond) * time.Millisecond
+	return &LockLease{LockName: req.LockName, Owner: req.Owner, Token: res.Token, TTL: ttl, ExpireTime: startTime.Add(ttl)}, nil
+}
+
+// AcquireLock 同步获取分布式锁，锁被他人持有时返回ErrLockHeld
+func AcquireLock(rpcHandler rpc.IRpcHandler, lockName string, ttl time.Duration) (*LockLease, error) {
+	nodeId, serviceMethod, err := getLockTarget(etcdAcquireLockMethod, originAcquireLockMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	req := newLockReq(rpcHandler, lockName, ttl, 0)
+	var res LockRes
+	startTime := timer.Now()
+	err = rpcHandler.CallNodeWithTimeout(DefaultLockRpcTimeout, nodeId, serviceMethod, req, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return newLockLease(req, &res, startTime)
+}
+
+// AsyncAcquireLock 异步获取分布式锁，结果在调用服务的协程中回调
+func AsyncAcquireLock(rpcHandler rpc.IRpcHandler, lockName string, ttl time.Duration, cb func(lease *LockLease, err error)) error {
+	nodeId, serviceMethod, err := getLockTarget(etcdAcquireLockMethod, originAcquireLockMethod)
+	if err != nil {
+		return err
+	}
+
+	req := newLockReq(rpcHandler, lockName, ttl, 0)
+	startTime := timer.Now()
+	_, err = rpcHandler.AsyncCallNodeWithTimeout(DefaultLockRpcTimeout, nodeId, serviceMethod, req, func(res *LockRes, err error) {
+		if err != nil {
+			cb(nil, err)
+			return
+		}
+
+		cb(newLockLease(req, res, startTime))
+	})
+
+	return err
+}
+
+// RenewLock 同步续约，成功后更新lease的过期时间
+func RenewLock(rpcHandler rpc.IRpcHandler, lease *LockLease) error {
+	nodeId, serviceMethod, err := getLockTarget(etcdRenewLockMethod, originRenewLockMethod)
+	if err != nil {
+		return err
+	}
+
+	req := newLockReq(rpcHandler, lease.LockName, lease.TTL, lease.Token)
+	req.Owner = lease.Owner
+	var res LockRes
+	startTime := timer.Now()
+	err = rpcHandler.CallNodeWithTimeout(DefaultLockRpcTimeout, nodeId, serviceMethod, req, &res)
+	if err != nil {
+		return err
+	}
+
+	lease.ExpireTime = startTime.Add(lease.TTL)
+	return nil
+}
+
+// AsyncRenewLock 异步续约，结果在调用服务的协程中回调
+func AsyncRenewLock(rpcHandler rpc.IRpcHandler, lease *LockLease, cb func(err error)) error {
+	nodeId, serviceMethod, err := getLockTarget(etcdRenewLockMethod, originRenewLockMethod)
+	if err != nil {
+		return err
+	}
+
+	req := newLockReq(rpcHandler, lease.LockName, lease.TTL, lease.Token)
+	req.Owner = lease.Owner
+	startTime := timer.Now()
+	_, err = rpcHandler.AsyncCallNodeWithTimeout(DefaultLockRpcTimeout, nodeId, serviceMethod, req, func(res *LockRes, err error) {
+		if err == nil {
+			lease.ExpireTime = startTime.Add(lease.TTL)
+		}
+		cb(err)
+	})
+
+	return err
+}
+
+// ReleaseLock 释放分布式锁
+func ReleaseLock(rpcHandler rpc.IRpcHandler, lease *LockLease) error {
+	nodeId, serviceMethod, err := getLockTarget(etcdReleaseLockMethod, originReleaseLockMethod)
+	if err != nil {
+		return err
+	}
+
+	req := newLockReq(rpcHandler, lease.LockName, lease.TTL, lease.Token)
+	req.Owner = lease.Owner
+	var res LockRes
+	return rpcHandler.CallNodeWithTimeout(DefaultLockRpcTimeout, nodeId, serviceMethod, req, &res)
+}
+
+type lockInfo struct {
+	nodeId     string
+	owner      string
+	token      uint64
+	expireTime time.Time
+}
+
+// lockTable origin Master结点中维护的锁表，只在Master服务协程中访问
+type lockTable struct {
+	mapLock   map[string]*lockInfo
+	seedToken uint64
+}
+
+func (lt *lockTable) init() {
+	lt.mapLock = map[string]*lockInfo{}
+	lt.seedToken = uint64(time.Now().UnixNano())
+}
+
+// nextToken token不小于当前时间，Master重启或切换到其他Master后token仍然递增
+func (lt *lockTable) nextToken() uint64 {
+	lt.seedToken = max(lt.seedToken+1, uint64(time.Now().UnixNano()))
+	return lt.seedToken
+}
+
+func (lt *lockTable) getLock(lockName string) *lockInfo {
+	info, ok := lt.mapLock[lockName]
+	if ok == false {
+		return nil
+	}
+
+	if timer.Now().After(info.expireTime) {
+		delete(lt.mapLock, lockName)
+		return nil
+	}
+
+	return info
+}
+
+func (lt *lockTable) acquire(req *LockReq, res *LockRes) error {
+	if req.LockName == "" || req.TTLMillisecond <= 0 {
+		return fmt.Errorf("invalid lock request %s", req.LockName)
+	}
+
+	ttl := time.Duration(req.TTLMillisecond) * time.Millisecond
+	info := lt.getLock(req.LockName)
+	if info != nil && (info.nodeId != req.NodeId || info.owner != req.Owner) {
+		res.Acquired = false
+		res.HolderNode = info.nodeId
+		res.HolderOwner = info.owner
+		return nil
+	}
+
+	//重复获取视为续约
+	if info == nil {
+		info = &lockInfo{nodeId: req.NodeId, owner: req.Owner, token: lt.nextToken()}
+		lt.mapLock[req.LockName] = info
+	}
+	info.expireTime = timer.Now().Add(ttl)
+
+	res.Acquired = true
+	res.Token = info.token
+	return nil
+}
+
+func (lt *lockTable) renew(req *LockReq, res *LockRes) error {
+	info := lt.getLock(req.LockName)
+	if info == nil || info.token != req.Token {
+		return fmt.Errorf("lock %s lease is lost", req.LockName)
+	}
+
+	info.expireTime = timer.Now().Add(time.Duration(req.TTLMillisecond) * time.Millisecond)
+	res.Acquired = true
+	res.Token = info.token
+	return nil
+}
+
+func (lt *lockTable) release(req *LockReq) error {
+	info := lt.getLock(req.LockName)
+	if info == nil || info.token != req.Token {
+		return fmt.Errorf("lock %s lease is lost", req.LockName)
+	}
+
+	delete(lt.mapLock, req.LockName)
+	return nil
+}
+
+// releaseNode 结点被判定为下线，释放其持有的所有锁
+func (lt *lockTable) releaseNode(nodeId string) {
+	for lockName, info := range lt.mapLock {
+		if info.nodeId == nodeId {
+			delete(lt.mapLock, lockName)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package cluster
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/util/timer"
+)
+
+func TestLockTable(t *testing.T) {
+	timer.EnableVirtualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	timer.StartTimer(time.Millisecond, 100)
+	defer timer.DisableVirtualClock()
+
+	var lt lockTable
+	lt.init()
+
+	req := &LockReq{LockName: "bag", NodeId: "node-1", Owner: "GameService", TTLMillisecond: 1000}
+	var res LockRes
+	if err := lt.acquire(req, &res); err != nil || res.Acquired == false {
+		t.Fatalf("acquire fail %v %+v", err, res)
+	}
+	token := res.Token
+
+	//其他持有者获取失败，返回当前持有者
+	other := &LockReq{LockName: "bag", NodeId: "node-2", Owner: "GameService", TTLMillisecond: 1000}
+	var otherRes LockRes
+	if err := lt.acquire(other, &otherRes); err != nil || otherRes.Acquired == true || otherRes.HolderNode != "node-1" {
+		t.Fatalf("acquire by others %v %+v", err, otherRes)
+	}
+
+	//续约延长过期时间，token不变
+	timer.Advance(800 * time.Millisecond)
+	renewReq := &LockReq{LockName: "bag", NodeId: "node-1", Owner: "GameService", TTLMillisecond: 1000, Token: token}
+	if err := lt.renew(renewReq, &res); err != nil || res.Token != token {
+		t.Fatalf("renew fail %v %+v", err, res)
+	}
+	timer.Advance(800 * time.Millisecond)
+	if lt.getLock("bag") == nil {
+		t.Fatal("lock should not expire after renew")
+	}
+
+	//token不匹配不能续约与释放
+	if err := lt.renew(&LockReq{LockName: "bag", Token: token + 1, TTLMillisecond: 1000}, &res); err == nil {
+		t.Fatal("renew with wrong token should fail")
+	}
+	if err := lt.release(&LockReq{LockName: "bag", Token: token + 1}); err == nil {
+		t.Fatal("release with wrong token should fail")
+	}
+
+	//释放后其他持有者获取到更大的token
+	if err := lt.release(renewReq); err != nil {
+		t.Fatal(err)
+	}
+	if err := lt.acquire(other, &otherRes); err != nil || otherRes.Acquired == false || otherRes.Token <= token {
+		t.Fatalf("acquire after release %v %+v", err, otherRes)
+	}
+
+	//过期后其他持有者可以获取
+	timer.Advance(1500 * time.Millisecond)
+	if err := lt.acquire(req, &res); err != nil || res.Acquired == false || res.Token <= otherRes.Token {
+		t.Fatalf("acquire after expire %v %+v", err, res)
+	}
+
+	//结点下线释放其持有的所有锁
+	lt.releaseNode("node-1")
+	if lt.getLock("bag") != nil {
+		t.Fatal("lock should be released with node")
+	}
+}
+
+func TestLockTokenAfterMasterSwitch(t *testing.T) {
+	var oldMaster lockTable
+	oldMaster.init()
+	oldToken := oldMaster.nextToken()
+	time.Sleep(time.Millisecond)
+
+	//切换到之后启动的Master，token以当前时间为下限，仍然大于旧Master发放的token
+	var newMaster lockTable
+	newMaster.init()
+	if token := newMaster.nextToken(); token <= oldToken {
+		t.Fatalf("token %d should be greater than %d", token, oldToken)
+	}
+}
+
+func TestSelectLockMaster(t *testing.T) {
+	masterList := []NodeInfo{{NodeId: "master-1"}, {NodeId: "master-2"}, {NodeId: "master-3"}}
+	mapConnected := map[string]bool{"master-3": true}
+	isConnected := func(nodeId string) bool {
+		return mapConnected[nodeId]
+	}
+
+	//跳过不可连接的Master
+	if nodeId, err := selectLockMaster(masterList, "node-1", isConnected); err != nil || nodeId != "master-3" {
+		t.Fatalf("select %s %v", nodeId, err)
+	}
+
+	//本结点为Master时直接使用
+	if nodeId, err := selectLockMaster(masterList, "master-2", isConnected); err != nil || nodeId != "master-2" {
+		t.Fatalf("select %s %v", nodeId, err)
+	}
+
+	//都不可连接时使用第一个，由Rpc返回错误
+	mapConnected = map[string]bool{}
+	if nodeId, err := selectLockMaster(masterList, "node-1", isConnected); err != nil || nodeId != "master-1" {
+		t.Fatalf("select %s %v", nodeId, err)
+	}
+
+	if _, err := selectLockMaster(nil, "node-1", isConnected); errors.Is(err, ErrLockNotSupported) == false {
+		t.Fatalf("empty master list err %v", err)
+	}
+}
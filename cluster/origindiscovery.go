@@ -2,6 +2,8 @@ package cluster
 
 import (
 	"errors"
+	"fmt"
+	"github.com/duanhf2012/origin/v2/event"
 	"github.com/duanhf2012/origin/v2/log"
 	"github.com/duanhf2012/origin/v2/rpc"
 	"github.com/duanhf2012/origin/v2/service"
@@ -17,6 +19,7 @@ const SubServiceDiscover = OriginDiscoveryClientName + ".RPC_SubServiceDiscover"
 const NodeRetireRpcMethod = OriginDiscoveryMasterName + ".RPC_NodeRetire"
 const RpcPingMethod = OriginDiscoveryMasterName + ".RPC_Ping"
 const UnRegServiceDiscover = OriginDiscoveryMasterName + ".RPC_UnRegServiceDiscover"
+const UpdateNodeInfoRpcMethod = OriginDiscoveryMasterName + ".RPC_UpdateNodeInfo"
 
 type OriginDiscoveryMaster struct {
 	service.Service
@@ -24,7 +27,11 @@ type OriginDiscoveryMaster struct {
 	mapNodeInfo map[string]struct{}
 	nodeInfo    []*rpc.NodeInfo
 
-	nsTTL nodeSetTTL
+	nsTTL     nodeSetTTL
+	lockTable lockTable //分布式锁
+
+	mapRegNode   map[string]struct{} //所有注册过来的结点，包括没有公开服务的结点
+	globalCfgDoc *GlobalCfgDoc       //最近一次推送的全局配置
 }
 
 type OriginDiscoveryClient struct {
@@ -103,10 +110,12 @@ func (ds *OriginDiscoveryMaster) removeNodeInfo(nodeId string) {
 
 func (ds *OriginDiscoveryMaster) OnInit() error {
 	ds.mapNodeInfo = make(map[string]struct{}, 20)
+	ds.mapRegNode = make(map[string]struct{}, 20)
 	ds.RegNodeConnListener(ds)
 	ds.RegNatsConnListener(ds)
 
 	ds.nsTTL.init(time.Duration(cluster.GetOriginDiscovery().TTLSecond) * time.Second)
+	ds.lockTable.init()
 
 	return nil
 }
@@ -133,15 +142,8 @@ func (ds *OriginDiscoveryMaster) checkTTL() {
 }
 
 func (ds *OriginDiscoveryMaster) OnStart() {
-	var nodeInfo rpc.NodeInfo
-	localNodeInfo := cluster.GetLocalNodeInfo()
-	nodeInfo.NodeId = localNodeInfo.NodeId
-	nodeInfo.ListenAddr = localNodeInfo.ListenAddr
-	nodeInfo.PublicServiceList = localNodeInfo.PublicServiceList
-	nodeInfo.MaxRpcParamLen = localNodeInfo.MaxRpcParamLen
-	nodeInfo.Private = localNodeInfo.Private
-	nodeInfo.Retire = localNodeInfo.Retire
-	ds.addNodeInfo(&nodeInfo)
+	nodeInfo := cluster.newLocalRpcNodeInfo(cluster.GetLocalNodeInfo().Retire)
+	ds.addNodeInfo(nodeInfo)
 
 	ds.checkTTL()
 }
@@ -168,11 +170,13 @@ func (ds *OriginDiscoveryMaster) OnNodeConnected(nodeId string) {
 }
 
 func (ds *OriginDiscoveryMaster) OnNodeDisconnect(nodeId string) {
+	delete(ds.mapRegNode, nodeId)
 	if ds.isRegNode(nodeId) == false {
 		return
 	}
 
 	ds.removeNodeInfo(nodeId)
+	ds.lockTable.releaseNode(nodeId)
 
 	//主动删除已经存在的结点,确保先断开，再连接
 	var notifyDiscover rpc.SubscribeDiscoverNotify
@@ -232,6 +236,7 @@ func (ds *OriginDiscoveryMaster) RPC_RegServiceDiscover(req *rpc.RegServiceDisco
 	if req.NodeInfo.NodeId != cluster.GetLocalNodeInfo().NodeId {
 		ds.nsTTL.addAndRefreshNode(req.NodeInfo.NodeId)
 	}
+	ds.mapRegNode[req.NodeInfo.NodeId] = struct{}{}
 
 	//广播给其他所有结点
 	var notifyDiscover rpc.SubscribeDiscoverNotify
@@ -243,20 +248,13 @@ func (ds *OriginDiscoveryMaster) RPC_RegServiceDiscover(req *rpc.RegServiceDisco
 	ds.addNodeInfo(req.NodeInfo)
 
 	//初始化结点信息
-	var nodeInfo NodeInfo
-	nodeInfo.NodeId = req.NodeInfo.NodeId
-	nodeInfo.Private = req.NodeInfo.Private
-	nodeInfo.ServiceList = req.NodeInfo.PublicServiceList
-	nodeInfo.PublicServiceList = req.NodeInfo.PublicServiceList
-	nodeInfo.ListenAddr = req.NodeInfo.ListenAddr
-	nodeInfo.MaxRpcParamLen = req.NodeInfo.MaxRpcParamLen
-	nodeInfo.Retire = req.NodeInfo.Retire
+	nodeInfo := newNodeInfo(req.NodeInfo)
 
 	//主动删除已经存在的结点,确保先断开，再连接
 	cluster.serviceDiscoveryDelNode(nodeInfo.NodeId)
 
 	//加入到本地Cluster模块中，将连接该结点
-	cluster.serviceDiscoverySetNodeInfo(&nodeInfo)
+	cluster.serviceDiscoverySetNodeInfo(nodeInfo)
 
 	res.IsFull = true
 	res.NodeInfo = ds.nodeInfo
@@ -264,6 +262,119 @@ func (ds *OriginDiscoveryMaster) RPC_RegServiceDiscover(req *rpc.RegServiceDisco
 	return nil
 }
 
+// RPC_UpdateNodeInfo 已注册结点的信息发生变化，如运行时增加或移除服务
+func (ds *OriginDiscoveryMaster) RPC_UpdateNodeInfo(req *rpc.UpdateNodeInfoReq, _ *rpc.Empty) error {
+	if req.NodeInfo == nil {
+		err := errors.New("RPC_UpdateNodeInfo req is error.")
+		log.Error(err.Error())
+
+		return err
+	}
+
+	//未注册的结点，注册时会带上最新的结点信息
+	if ds.isRegNode(req.NodeInfo.NodeId) == false {
+		return nil
+	}
+
+	log.Info("update node info", log.String("nodeId", req.NodeInfo.NodeId), log.Any("services", req.NodeInfo.PublicServiceList))
+	ds.updateNodeInfo(req.NodeInfo)
+
+	var notifyDiscover rpc.SubscribeDiscoverNotify
+	notifyDiscover.MasterNodeId = cluster.GetLocalNodeInfo().NodeId
+	notifyDiscover.NodeInfo = append(notifyDiscover.NodeInfo, req.NodeInfo)
+	ds.RpcCastGo(SubServiceDiscover, &notifyDiscover)
+
+	//同步到本地Cluster模块中
+	if req.NodeInfo.NodeId != cluster.GetLocalNodeInfo().NodeId {
+		cluster.serviceDiscoverySetNodeInfo(newNodeInfo(req.NodeInfo))
+	}
+
+	return nil
+}
+
+// RPC_AcquireLock 获取分布式锁
+func (ds *OriginDiscoveryMaster) RPC_AcquireLock(req *LockReq, res *LockRes) error {
+	return ds.lockTable.acquire(req, res)
+}
+
+// RPC_RenewLock 分布式锁续约
+func (ds *OriginDiscoveryMaster) RPC_RenewLock(req *LockReq, res *LockRes) error {
+	return ds.lockTable.renew(req, res)
+}
+
+// RPC_ReleaseLock 释放分布式锁
+func (ds *OriginDiscoveryMaster) RPC_ReleaseLock(req *LockReq, _ *LockRes) error {
+	return ds.lockTable.release(req)
+}
+
+// RPC_PushGlobalCfg 推送全局配置，广播到所有注册的结点，收集各结点的确认结果后返回
+func (ds *OriginDiscoveryMaster) RPC_PushGlobalCfg(responder rpc.Responder, doc *GlobalCfgDoc) {
+	if ds.globalCfgDoc != nil && doc.Version <= ds.globalCfgDoc.Version {
+		err := fmt.Errorf("global config version %d is not newer than version %d", doc.Version, ds.globalCfgDoc.Version)
+		responder(newPushGlobalCfgRes(doc.Version), rpc.ConvertError(err))
+		return
+	}
+
+	log.Info("push global config", log.Uint64("version", doc.Version))
+	ds.globalCfgDoc = doc
+
+	mapPending := make(map[string]struct{}, len(ds.mapRegNode)+1)
+	for nodeId := range ds.mapRegNode {
+		mapPending[nodeId] = struct{}{}
+	}
+	mapPending[cluster.GetLocalNodeInfo().NodeId] = struct{}{}
+
+	res := newPushGlobalCfgRes(doc.Version)
+	bCallFinish := false
+	onAck := func(nodeId string, ack *GlobalCfgAck, err error) {
+		if _, ok := mapPending[nodeId]; ok == false {
+			return
+		}
+		delete(mapPending, nodeId)
+
+		if err != nil {
+			res.FailNodeList[nodeId] = err.Error()
+		} else if ack.isAcked(doc.Version) == false {
+			res.FailNodeList[nodeId] = ack.Err
+		} else {
+			res.AckNodeList = append(res.AckNodeList, nodeId)
+		}
+
+		if bCallFinish == true && len(mapPending) == 0 {
+			responder(res, rpc.NilError)
+		}
+	}
+
+	nodeIdList := make([]string, 0, len(mapPending))
+	for nodeId := range mapPending {
+		nodeIdList = append(nodeIdList, nodeId)
+	}
+
+	for _, nodeId := range nodeIdList {
+		_, err := ds.AsyncCallNodeWithTimeout(DefaultGlobalCfgAckTimeout, nodeId, originUpdateGlobalCfgMethod, doc, func(ack *GlobalCfgAck, err error) {
+			onAck(nodeId, ack, err)
+		})
+		if err != nil {
+			onAck(nodeId, nil, err)
+		}
+	}
+
+	//结点的结果可能在调用中同步返回，全部发起后再检查是否完成
+	bCallFinish = true
+	if len(mapPending) == 0 {
+		responder(res, rpc.NilError)
+	}
+}
+
+// RPC_GetGlobalCfg 获取比请求结点更新的全局配置，没有时返回空配置
+func (ds *OriginDiscoveryMaster) RPC_GetGlobalCfg(req *GlobalCfgAck, res *GlobalCfgDoc) error {
+	if ds.globalCfgDoc != nil && ds.globalCfgDoc.Version > req.Version {
+		*res = *ds.globalCfgDoc
+	}
+
+	return nil
+}
+
 func (ds *OriginDiscoveryMaster) RPC_UnRegServiceDiscover(req *rpc.UnRegServiceDiscoverReq, _ *rpc.Empty) error {
 	log.Debug("RPC_UnRegServiceDiscover", log.String("nodeId", req.NodeId))
 	ds.OnNodeDisconnect(req.NodeId)
@@ -273,6 +384,7 @@ func (ds *OriginDiscoveryMaster) RPC_UnRegServiceDiscover(req *rpc.UnRegServiceD
 func (dc *OriginDiscoveryClient) OnInit() error {
 	dc.RegNodeConnListener(dc)
 	dc.RegNatsConnListener(dc)
+	dc.GetEventProcessor().RegEventReceiverFunc(event.Sys_Event_RefreshNodeInfo, dc.GetEventHandler(), dc.onRefreshNodeInfo)
 
 	dc.mapDiscovery = map[string]map[string][]string{}
 	//dc.mapMasterNetwork = map[string]string{}
@@ -412,6 +524,7 @@ func (dc *OriginDiscoveryClient) RPC_SubServiceDiscover(req *rpc.SubscribeDiscov
 				nInfo.MaxRpcParamLen = nodeInfo.MaxRpcParamLen
 				nInfo.Retire = nodeInfo.Retire
 				nInfo.Private = nodeInfo.Private
+				nInfo.ServiceStateList = nodeInfo.ServiceStateList
 
 				mapNodeInfo[nodeInfo.NodeId] = nInfo
 			}
@@ -482,14 +595,7 @@ func (dc *OriginDiscoveryClient) OnRetire() {
 	masterNodeList := cluster.GetOriginDiscovery()
 	for i := 0; i < len(masterNodeList.MasterNodeList); i++ {
 		var nodeRetireReq rpc.NodeRetireReq
-
-		nodeRetireReq.NodeInfo = &rpc.NodeInfo{}
-		nodeRetireReq.NodeInfo.NodeId = cluster.localNodeInfo.NodeId
-		nodeRetireReq.NodeInfo.ListenAddr = cluster.localNodeInfo.ListenAddr
-		nodeRetireReq.NodeInfo.MaxRpcParamLen = cluster.localNodeInfo.MaxRpcParamLen
-		nodeRetireReq.NodeInfo.PublicServiceList = cluster.localNodeInfo.PublicServiceList
-		nodeRetireReq.NodeInfo.Retire = dc.bRetire
-		nodeRetireReq.NodeInfo.Private = cluster.localNodeInfo.Private
+		nodeRetireReq.NodeInfo = cluster.newLocalRpcNodeInfo(dc.bRetire)
 
 		err := dc.GoNode(masterNodeList.MasterNodeList[i].NodeId, NodeRetireRpcMethod, &nodeRetireReq)
 		if err != nil {
@@ -498,6 +604,24 @@ func (dc *OriginDiscoveryClient) OnRetire() {
 	}
 }
 
+// RefreshLocalNodeInfo 本结点信息变化，向所有Master同步
+func (dc *OriginDiscoveryClient) RefreshLocalNodeInfo() {
+	dc.NotifyEvent(&refreshNodeInfoEvent{})
+}
+
+func (dc *OriginDiscoveryClient) onRefreshNodeInfo(_ event.IEvent) {
+	var req rpc.UpdateNodeInfoReq
+	req.NodeInfo = cluster.newLocalRpcNodeInfo(dc.bRetire)
+
+	masterNodeList := cluster.GetOriginDiscovery()
+	for i := 0; i < len(masterNodeList.MasterNodeList); i++ {
+		err := dc.GoNode(masterNodeList.MasterNodeList[i].NodeId, UpdateNodeInfoRpcMethod, &req)
+		if err != nil {
+			log.Error("call "+UpdateNodeInfoRpcMethod+" is fail", log.ErrorField("err", err))
+		}
+	}
+}
+
 func (dc *OriginDiscoveryClient) tryRegServiceDiscover(nodeId string) {
 	dc.AfterFunc(time.Second*3, func(timer *timer.Timer) {
 		dc.regServiceDiscover(nodeId)
@@ -514,13 +638,7 @@ func (dc *OriginDiscoveryClient) regServiceDiscover(nodeId string) {
 	}
 
 	var req rpc.RegServiceDiscoverReq
-	req.NodeInfo = &rpc.NodeInfo{}
-	req.NodeInfo.NodeId = cluster.localNodeInfo.NodeId
-	req.NodeInfo.ListenAddr = cluster.localNodeInfo.ListenAddr
-	req.NodeInfo.MaxRpcParamLen = cluster.localNodeInfo.MaxRpcParamLen
-	req.NodeInfo.PublicServiceList = cluster.localNodeInfo.PublicServiceList
-	req.NodeInfo.Retire = dc.bRetire
-	req.NodeInfo.Private = cluster.localNodeInfo.Private
+	req.NodeInfo = cluster.newLocalRpcNodeInfo(dc.bRetire)
 	log.Debug("regServiceDiscover", log.String("nodeId", nodeId))
 	//向Master服务同步本Node服务信息
 	_, err := dc.AsyncCallNodeWithTimeout(3*time.Second, nodeId, RegServiceDiscover, &req, func(res *rpc.SubscribeDiscoverNotify, err error) {
@@ -532,6 +650,7 @@ func (dc *OriginDiscoveryClient) regServiceDiscover(nodeId string) {
 
 		dc.isRegisterOk = true
 		dc.RPC_SubServiceDiscover(res)
+		dc.syncGlobalCfg(nodeId)
 	})
 
 	if err != nil {
@@ -540,6 +659,44 @@ func (dc *OriginDiscoveryClient) regServiceDiscover(nodeId string) {
 	}
 }
 
+// syncGlobalCfg 注册成功后，从Master同步在此之前推送的全局配置
+func (dc *OriginDiscoveryClient) syncGlobalCfg(masterNodeId string) {
+	req := GlobalCfgAck{NodeId: dc.localNodeId, Version: cluster.GetGlobalCfgVersion()}
+	_, err := dc.AsyncCallNodeWithTimeout(3*time.Second, masterNodeId, originGetGlobalCfgMethod, &req, func(doc *GlobalCfgDoc, err error) {
+		if err != nil {
+			log.Error("call "+originGetGlobalCfgMethod+" is fail", log.String("masterNodeId", masterNodeId), log.ErrorField("err", err))
+			return
+		}
+
+		if doc.Version <= cluster.GetGlobalCfgVersion() {
+			return
+		}
+
+		err = cluster.applyGlobalCfg(doc)
+		if err != nil {
+			log.Error("apply global config fail", log.Uint64("version", doc.Version), log.ErrorField("err", err))
+		}
+	})
+
+	if err != nil {
+		log.Error("call "+originGetGlobalCfgMethod+" is fail", log.String("masterNodeId", masterNodeId), log.ErrorField("err", err))
+	}
+}
+
+// RPC_UpdateGlobalCfg Master推送过来的全局配置
+func (dc *OriginDiscoveryClient) RPC_UpdateGlobalCfg(doc *GlobalCfgDoc, ack *GlobalCfgAck) error {
+	ack.NodeId = dc.localNodeId
+	ack.Version = doc.Version
+
+	err := cluster.applyGlobalCfg(doc)
+	if err != nil {
+		log.Error("apply global config fail", log.Uint64("version", doc.Version), log.ErrorField("err", err))
+		ack.Err = err.Error()
+	}
+
+	return nil
+}
+
 func (dc *OriginDiscoveryClient) setNodeInfo(masterNodeId string, nodeInfo *rpc.NodeInfo) bool {
 	if nodeInfo == nil || nodeInfo.Private == true || nodeInfo.NodeId == dc.localNodeId {
 		return false
@@ -565,6 +722,7 @@ func (dc *OriginDiscoveryClient) setNodeInfo(masterNodeId string, nodeInfo *rpc.
 	nInfo.MaxRpcParamLen = nodeInfo.MaxRpcParamLen
 	nInfo.Retire = nodeInfo.Retire
 	nInfo.Private = nodeInfo.Private
+	nInfo.mapServiceState = newServiceStateMap(nodeInfo.ServiceStateList)
 
 	dc.funSetNode(&nInfo)
 
@@ -335,9 +335,25 @@ func (cls *Cluster) readLocalClusterConfig(nodeId string) (DiscoveryInfo, []Node
 }
 
 func (cls *Cluster) readLocalService(localNodeId string) error {
+	globalCfg, mapServiceCfg, err := cls.loadLocalServiceCfg(localNodeId, cls.localNodeInfo.ServiceList)
+	if err != nil {
+		return err
+	}
+
+	for serviceName, serviceCfg := range mapServiceCfg {
+		cls.localServiceCfg[serviceName] = serviceCfg
+	}
+	cls.globalCfg = globalCfg
+
+	return nil
+}
+
+// loadLocalServiceCfg 读取配置目录中的Global配置与本结点服务的配置
+func (cls *Cluster) loadLocalServiceCfg(localNodeId string, serviceList []string) (interface{}, map[string]interface{}, error) {
 	var globalCfg interface{}
 	publicService := map[string]interface{}{}
 	nodeService := map[string]interface{}{}
+	mapServiceCfg := map[string]interface{}{}
 
 	//读取任何文件,只读符合格式的配置,目录下的文件可以自定义分文件
 	err := filepath.Walk(configDir, func(path string, info fs.FileInfo, err error)error{
@@ -366,7 +382,7 @@ func (cls *Cluster) readLocalService(localNodeId string) error {
 		}
 
 		//保存公共配置
-		for _, s := range cls.localNodeInfo.ServiceList {
+		for _, s := range serviceList {
 			for {
 				splitServiceName := strings.Split(s, ":")
 				if len(splitServiceName) == 2 {
@@ -402,11 +418,11 @@ func (cls *Cluster) readLocalService(localNodeId string) error {
 	})
 
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	//组合所有的配置
-	for _, s := range cls.localNodeInfo.ServiceList {
+	for _, s := range serviceList {
 		splitServiceName := strings.Split(s, ":")
 		if len(splitServiceName) == 2 {
 			s = splitServiceName[0]
@@ -417,19 +433,18 @@ func (cls *Cluster) readLocalService(localNodeId string) error {
 		var ok bool
 		serviceCfg, ok = nodeService[s]
 		if ok == true {
-			cls.localServiceCfg[s] = serviceCfg
+			mapServiceCfg[s] = serviceCfg
 			continue
 		}
 
 		//如果找不到从PublicService中找
 		serviceCfg, ok = publicService[s]
 		if ok == true {
-			cls.localServiceCfg[s] = serviceCfg
+			mapServiceCfg[s] = serviceCfg
 		}
 	}
-	cls.globalCfg = globalCfg
 
-	return nil
+	return globalCfg, mapServiceCfg, nil
 }
 
 func (cls *Cluster) parseLocalCfg() error{
@@ -512,6 +527,9 @@ func (cls *Cluster) GetNodeIdByTemplateService(templateServiceName string, rpcCl
 	cls.locker.RLock()
 	defer cls.locker.RUnlock()
 
+	//过载的服务只在没有其他可选服务时参与路由
+	var overloadClientList []*rpc.Client
+	clientNum := len(rpcClientList)
 	mapServiceName := cls.mapTemplateServiceNode[templateServiceName]
 	for serviceName := range mapServiceName {
 		mapNodeId, ok := cls.mapServiceNode[serviceName]
@@ -527,17 +545,35 @@ func (cls *Cluster) GetNodeIdByTemplateService(templateServiceName string, rpcCl
 					continue
 				}
 
+				//未就绪的服务不参与路由
+				if cls.isServiceNotReady(nodeId, serviceName) == true {
+					continue
+				}
+
+				if cls.isServiceOverload(nodeId, serviceName) == true {
+					overloadClientList = append(overloadClientList, pClient)
+					continue
+				}
+
 				rpcClientList = append(rpcClientList, pClient)
 			}
 		}
 	}
 
+	if len(rpcClientList) == clientNum {
+		rpcClientList = append(rpcClientList, overloadClientList...)
+	}
+
 	return nil, rpcClientList
 }
 
 func (cls *Cluster) GetNodeIdByService(serviceName string, rpcClientList []*rpc.Client, filterRetire bool) (error, []*rpc.Client) {
 	cls.locker.RLock()
 	defer cls.locker.RUnlock()
+
+	//过载的服务只在没有其他可选服务时参与路由
+	var overloadClientList []*rpc.Client
+	clientNum := len(rpcClientList)
 	mapNodeId, ok := cls.mapServiceNode[serviceName]
 	if ok == true {
 		for nodeId := range mapNodeId {
@@ -551,14 +587,31 @@ func (cls *Cluster) GetNodeIdByService(serviceName string, rpcClientList []*rpc.
 				continue
 			}
 
+			//未就绪的服务不参与路由
+			if cls.isServiceNotReady(nodeId, serviceName) == true {
+				continue
+			}
+
+			if cls.isServiceOverload(nodeId, serviceName) == true {
+				overloadClientList = append(overloadClientList, pClient)
+				continue
+			}
+
 			rpcClientList = append(rpcClientList, pClient)
 		}
 	}
 
+	if len(rpcClientList) == clientNum {
+		rpcClientList = append(rpcClientList, overloadClientList...)
+	}
+
 	return nil, rpcClientList
 }
 
 func (cls *Cluster) GetServiceCfg(serviceName string) interface{} {
+	cls.locker.RLock()
+	defer cls.locker.RUnlock()
+
 	serviceCfg, ok := cls.localServiceCfg[serviceName]
 	if ok == false {
 		return nil
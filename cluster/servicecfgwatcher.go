@@ -0,0 +1,86 @@
+package cluster
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/service"
+)
+
+// reloadServiceCfg 配置文件变化时重新读取本结点的服务配置，通知配置发生变化的服务
+// 服务接受新配置后才更新集群中保存的配置，拒绝时保持原配置
+func (cls *Cluster) reloadServiceCfg() {
+	cls.locker.RLock()
+	localNodeId := cls.localNodeInfo.NodeId
+	serviceList := append([]string{}, cls.localNodeInfo.ServiceList...)
+	cls.locker.RUnlock()
+
+	_, mapServiceCfg, err := cls.loadLocalServiceCfg(localNodeId, serviceList)
+	if err != nil {
+		log.Error("reload service config fail", log.ErrorField("err", err))
+		return
+	}
+
+	for _, serviceName := range cls.getChangedServiceCfg(mapServiceCfg) {
+		s := service.GetService(serviceName)
+		if s == nil {
+			continue
+		}
+
+		newCfg := mapServiceCfg[serviceName]
+		cls.setOfferedServiceCfg(serviceName, newCfg)
+		s.NotifyServiceCfgChanged(newCfg, func(err error) {
+			cls.onServiceCfgResult(serviceName, newCfg, err)
+		})
+	}
+}
+
+// getChangedServiceCfg 获取需要通知的服务，与生效配置相同或已经通知过的配置不再通知
+func (cls *Cluster) getChangedServiceCfg(mapServiceCfg map[string]interface{}) []string {
+	cls.locker.Lock()
+	defer cls.locker.Unlock()
+
+	var changedList []string
+	for serviceName, serviceCfg := range mapServiceCfg {
+		if reflect.DeepEqual(cls.localServiceCfg[serviceName], serviceCfg) == true {
+			//配置文件改回生效配置，之后再次修改为被拒绝的配置时需要重新通知
+			delete(cls.offeredCfg, serviceName)
+			continue
+		}
+
+		if offeredCfg, ok := cls.offeredCfg[serviceName]; ok == true && reflect.DeepEqual(offeredCfg, serviceCfg) == true {
+			continue
+		}
+
+		changedList = append(changedList, serviceName)
+	}
+
+	sort.Strings(changedList)
+	return changedList
+}
+
+func (cls *Cluster) setOfferedServiceCfg(serviceName string, serviceCfg interface{}) {
+	cls.locker.Lock()
+	defer cls.locker.Unlock()
+
+	if cls.offeredCfg == nil {
+		cls.offeredCfg = map[string]interface{}{}
+	}
+	cls.offeredCfg[serviceName] = serviceCfg
+}
+
+// onServiceCfgResult 服务处理新配置后回调，拒绝时保留已通知的配置，避免每次文件变化都重复通知
+func (cls *Cluster) onServiceCfgResult(serviceName string, newCfg interface{}, err error) {
+	if err != nil {
+		return
+	}
+
+	cls.locker.Lock()
+	defer cls.locker.Unlock()
+
+	cls.localServiceCfg[serviceName] = newCfg
+	if reflect.DeepEqual(cls.offeredCfg[serviceName], newCfg) == true {
+		delete(cls.offeredCfg, serviceName)
+	}
+}
@@ -0,0 +1,60 @@
+package cluster
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func checkChangedServiceCfg(t *testing.T, cls *Cluster, mapServiceCfg map[string]interface{}, expectList ...string) {
+	t.Helper()
+	changedList := cls.getChangedServiceCfg(mapServiceCfg)
+	if len(changedList) == 0 && len(expectList) == 0 {
+		return
+	}
+	if reflect.DeepEqual(changedList, expectList) == false {
+		t.Fatalf("changed service %v, want %v", changedList, expectList)
+	}
+}
+
+func TestServiceCfgChanged(t *testing.T) {
+	var cls Cluster
+	cls.localServiceCfg = map[string]interface{}{
+		"GameService": map[string]interface{}{"MaxPlayer": 100.0},
+		"GateService": map[string]interface{}{"Port": 9000.0},
+	}
+
+	//只通知配置发生变化的服务
+	cfgV2 := map[string]interface{}{"MaxPlayer": 200.0}
+	mapServiceCfg := map[string]interface{}{
+		"GameService": cfgV2,
+		"GateService": map[string]interface{}{"Port": 9000.0},
+	}
+	checkChangedServiceCfg(t, &cls, mapServiceCfg, "GameService")
+
+	//服务拒绝后，配置文件因其他原因变化时不再重复通知
+	cls.setOfferedServiceCfg("GameService", cfgV2)
+	cls.onServiceCfgResult("GameService", cfgV2, errors.New("invalid config"))
+	checkChangedServiceCfg(t, &cls, mapServiceCfg)
+	if reflect.DeepEqual(cls.localServiceCfg["GameService"], map[string]interface{}{"MaxPlayer": 100.0}) == false {
+		t.Fatalf("rejected config should not take effect %v", cls.localServiceCfg["GameService"])
+	}
+
+	//再次修改后重新通知，接受后更新生效配置
+	cfgV3 := map[string]interface{}{"MaxPlayer": 300.0}
+	mapServiceCfg["GameService"] = cfgV3
+	checkChangedServiceCfg(t, &cls, mapServiceCfg, "GameService")
+	cls.setOfferedServiceCfg("GameService", cfgV3)
+	cls.onServiceCfgResult("GameService", cfgV3, nil)
+	if reflect.DeepEqual(cls.localServiceCfg["GameService"], cfgV3) == false {
+		t.Fatalf("accepted config should take effect %v", cls.localServiceCfg["GameService"])
+	}
+	if _, ok := cls.offeredCfg["GameService"]; ok == true {
+		t.Fatal("offered config should be removed after accepted")
+	}
+	checkChangedServiceCfg(t, &cls, mapServiceCfg)
+
+	//改回之前被拒绝的配置时重新通知
+	mapServiceCfg["GameService"] = cfgV2
+	checkChangedServiceCfg(t, &cls, mapServiceCfg, "GameService")
+}
@@ -10,3 +10,8 @@ type IServiceDiscovery interface {
 	InitDiscovery(localNodeId string,funDelNode FunDelNode,funSetNodeInfo FunSetNode) error
 }
 
+
+// INodeInfoRefresher 本结点信息(如服务列表)发生变化时，服务发现可实现该接口重新发布本结点信息
+type INodeInfoRefresher interface {
+	RefreshLocalNodeInfo()
+}
@@ -0,0 +1,234 @@
+package cluster
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/rpc"
+	"github.com/duanhf2012/origin/v2/service"
+)
+
+// DefaultHealthCheckInterval 默认服务健康检查间隔
+const DefaultHealthCheckInterval = 3 * time.Second
+
+var healthCheckInterval = DefaultHealthCheckInterval
+
+// SetHealthCheckInterval 设置本结点服务健康检查间隔，设置为0时关闭健康检查，需要在结点启动前设置
+func SetHealthCheckInterval(interval time.Duration) {
+	healthCheckInterval = interval
+}
+
+// healthChecker 定时检查本结点所有服务的健康状态，状态变化时重新发布结点信息
+type healthChecker struct {
+	locker   sync.Mutex
+	closeSig chan struct{}
+	notify   chan struct{} //服务过载状态变化时立即检查
+}
+
+func (hc *healthChecker) start() {
+	hc.locker.Lock()
+	defer hc.locker.Unlock()
+
+	if healthCheckInterval <= 0 || hc.closeSig != nil {
+		return
+	}
+
+	hc.closeSig = make(chan struct{})
+	hc.notify = make(chan struct{}, 1)
+	go hc.run(hc.closeSig, hc.notify)
+	service.OverloadNotifyFun = hc.onServiceOverload
+}
+
+func (hc *healthChecker) stop() {
+	hc.locker.Lock()
+	defer hc.locker.Unlock()
+
+	if hc.closeSig == nil {
+		return
+	}
+
+	close(hc.closeSig)
+	hc.closeSig = nil
+	hc.notify = nil
+}
+
+// onServiceOverload 服务过载状态变化，通知检查协程立即重新发布
+func (hc *healthChecker) onServiceOverload(serviceName string, overloaded bool) {
+	hc.locker.Lock()
+	defer hc.locker.Unlock()
+
+	if hc.notify == nil {
+		return
+	}
+
+	select {
+	case hc.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (hc *healthChecker) run(closeSig chan struct{}, notify chan struct{}) {
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closeSig:
+			return
+		case <-ticker.C:
+			hc.check()
+		case <-notify:
+			hc.check()
+		}
+	}
+}
+
+func (hc *healthChecker) check() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.StackError(fmt.Sprint(r))
+		}
+	}()
+
+	cls := GetCluster()
+	serviceList := cls.GetLocalNodeInfo().ServiceList
+
+	mapServiceState := make(map[string]*rpc.ServiceState, len(serviceList))
+	for _, serviceName := range serviceList {
+		//模板服务实例为"服务名:模板名"，按服务名查找与发布
+		serviceName, _, _ = strings.Cut(serviceName, ":")
+		s := service.GetService(serviceName)
+		if s == nil {
+			continue
+		}
+
+		status, reason := s.GetHealth()
+		overloaded := s.IsOverloaded()
+		if status == service.Healthy && overloaded == false {
+			continue
+		}
+		mapServiceState[serviceName] = &rpc.ServiceState{ServiceName: serviceName, Health: int32(status), Reason: reason, Overload: overloaded}
+	}
+
+	if cls.setLocalServiceState(mapServiceState) == false {
+		return
+	}
+
+	cls.RefreshLocalNodeInfo()
+}
+
+// setLocalServiceState 更新本结点服务的健康状态，只有状态变化时返回true，原因的变化不重新发布
+func (cls *Cluster) setLocalServiceState(mapServiceState map[string]*rpc.ServiceState) bool {
+	cls.locker.Lock()
+	defer cls.locker.Unlock()
+
+	bChanged := len(mapServiceState) != len(cls.localNodeInfo.mapServiceState)
+	for serviceName, state := range mapServiceState {
+		lastState, ok := cls.localNodeInfo.mapServiceState[serviceName]
+		if ok == false || lastState.Health != state.Health || lastState.Overload != state.Overload {
+			bChanged = true
+			log.Info("service health has changed", log.String("serviceName", serviceName), log.String("health", service.HealthStatus(state.Health).String()), log.Bool("overload", state.Overload), log.String("reason", state.Reason))
+		}
+	}
+
+	for serviceName := range cls.localNodeInfo.mapServiceState {
+		if _, ok := mapServiceState[serviceName]; ok == false {
+			log.Info("service health has changed", log.String("serviceName", serviceName), log.String("health", service.Healthy.String()))
+		}
+	}
+
+	if bChanged == false {
+		return false
+	}
+
+	cls.localNodeInfo.mapServiceState = mapServiceState
+	if localRpc, ok := cls.mapRpc[cls.localNodeInfo.NodeId]; ok == true {
+		localRpc.nodeInfo.mapServiceState = mapServiceState
+	}
+
+	return true
+}
+
+// getLocalServiceStateList 本结点需要发布的服务状态，需要在加锁后调用
+func (cls *Cluster) getLocalServiceStateList() []*rpc.ServiceState {
+	if len(cls.localNodeInfo.mapServiceState) == 0 {
+		return nil
+	}
+
+	serviceStateList := make([]*rpc.ServiceState, 0, len(cls.localNodeInfo.mapServiceState))
+	for _, state := range cls.localNodeInfo.mapServiceState {
+		serviceStateList = append(serviceStateList, state)
+	}
+
+	return serviceStateList
+}
+
+func newServiceStateMap(serviceStateList []*rpc.ServiceState) map[string]*rpc.ServiceState {
+	if len(serviceStateList) == 0 {
+		return nil
+	}
+
+	mapServiceState := make(map[string]*rpc.ServiceState, len(serviceStateList))
+	for _, state := range serviceStateList {
+		mapServiceState[state.ServiceName] = state
+	}
+
+	return mapServiceState
+}
+
+// isServiceNotReady 结点上的服务是否未就绪，需要在加锁后调用
+func (cls *Cluster) isServiceNotReady(nodeId string, serviceName string) bool {
+	rpcInfo, ok := cls.mapRpc[nodeId]
+	if ok == false {
+		return false
+	}
+
+	state, ok := rpcInfo.nodeInfo.mapServiceState[serviceName]
+	return ok == true && service.HealthStatus(state.Health) == service.NotReady
+}
+
+// isServiceOverload 结点上的服务是否过载，需要在加锁后调用
+func (cls *Cluster) isServiceOverload(nodeId string, serviceName string) bool {
+	rpcInfo, ok := cls.mapRpc[nodeId]
+	if ok == false {
+		return false
+	}
+
+	state, ok := rpcInfo.nodeInfo.mapServiceState[serviceName]
+	return ok == true && state.Overload == true
+}
+
+// IsServiceOverload 结点上的服务是否过载
+func (cls *Cluster) IsServiceOverload(nodeId string, serviceName string) bool {
+	cls.locker.RLock()
+	defer cls.locker.RUnlock()
+
+	return cls.isServiceOverload(nodeId, serviceName)
+}
+
+// IsServiceReachable 集群中是否存在已连接且未处于NotReady状态的服务
+func (cls *Cluster) IsServiceReachable(serviceName string) bool {
+	_, rpcClientList := cls.GetNodeIdByService(serviceName, nil, true)
+	return len(rpcClientList) > 0
+}
+
+// GetServiceHealth 获取结点上服务的健康状态，未同步状态的服务视为Healthy
+func (cls *Cluster) GetServiceHealth(nodeId string, serviceName string) (service.HealthStatus, string) {
+	cls.locker.RLock()
+	defer cls.locker.RUnlock()
+
+	rpcInfo, ok := cls.mapRpc[nodeId]
+	if ok == false {
+		return service.NotReady, "node is not found"
+	}
+
+	state, ok := rpcInfo.nodeInfo.mapServiceState[serviceName]
+	if ok == false {
+		return service.Healthy, ""
+	}
+
+	return service.HealthStatus(state.Health), state.Reason
+}
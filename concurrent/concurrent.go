@@ -1,8 +1,9 @@
 package concurrent
 
 import (
-	"errors"
+	"context"
 	"runtime"
+	"time"
 
 	"github.com/duanhf2012/origin/v2/log"
 	"sync/atomic"
@@ -15,6 +16,8 @@ type IConcurrent interface {
 	OpenConcurrent(minGoroutineNum int32, maxGoroutineNum int32, maxTaskChannelNum int)
 	AsyncDoByQueue(queueId int64, fn func() bool, cb func(err error))
 	AsyncDo(f func() bool, cb func(err error))
+	AsyncDoContext(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) bool, cb func(err error)) *TaskHandle
+	AsyncDoByQueueContext(ctx context.Context, queueId int64, timeout time.Duration, fn func(ctx context.Context) bool, cb func(err error)) *TaskHandle
 }
 
 type Concurrent struct {
@@ -23,6 +26,9 @@ type Concurrent struct {
 	tasks     chan task
 	cbChannel chan func(error)
 	open      int32
+
+	closeContext context.Context //Close时取消，用于取消未完成的任务
+	closeCancel  context.CancelFunc
 }
 
 /*
@@ -43,6 +49,7 @@ func (c *Concurrent) OpenConcurrent(minGoroutineNum int32, maxGoroutineNum int32
 
 	c.tasks = make(chan task, maxTaskChannelNum)
 	c.cbChannel = make(chan func(error), maxTaskChannelNum)
+	c.closeContext, c.closeCancel = context.WithCancel(context.Background())
 
 	//打开dispach
 	c.dispatch.open(minGoroutineNum, maxGoroutineNum, c.tasks, c.cbChannel)
@@ -67,23 +74,35 @@ func (c *Concurrent) AsyncDoByQueue(queueId int64, fn func() bool, cb func(err e
 		return
 	}
 
+	c.pushTask(queueId, fn, cb)
+}
+
+// pushTask 投递任务，任务队列已满时回调错误并返回false
+func (c *Concurrent) pushTask(queueId int64, fn func() bool, cb func(err error)) bool {
 	if queueId != 0 {
 		queueId = queueId%maxTaskQueueSessionId + 1
 	}
 
+	atomic.AddInt32(&c.taskNum, 1)
 	select {
 	case c.tasks <- task{queueId, fn, cb}:
+		return true
 	default:
+		atomic.AddInt32(&c.taskNum, -1)
 		log.Error("tasks channel is full")
 		if cb != nil {
 			c.pushAsyncDoCallbackEvent(func(err error) {
-				cb(errors.New("tasks channel is full"))
+				cb(ErrTaskDropped)
 			})
 		}
-		return
+		return false
 	}
 }
 
+func (c *Concurrent) isClosing() bool {
+	return c.closeContext.Err() != nil
+}
+
 func (c *Concurrent) Close() {
 	if cap(c.tasks) == 0 {
 		return
@@ -91,11 +110,22 @@ func (c *Concurrent) Close() {
 
 	log.Info("wait close concurrent")
 
+	//取消所有未完成的任务，不再投递回调
+	c.closeCancel()
 	c.dispatch.close()
 
 	log.Info("concurrent has successfully exited")
 }
 
+// GetPendingTaskNum 获取未执行完成的任务数与未处理的回调数
+func (c *Concurrent) GetPendingTaskNum() int {
+	if cap(c.tasks) == 0 {
+		return 0
+	}
+
+	return int(atomic.LoadInt32(&c.taskNum)) + len(c.cbChannel)
+}
+
 func (c *Concurrent) GetCallBackChannel() chan func(error) {
 	return c.cbChannel
 }
@@ -27,6 +27,7 @@ type dispatch struct {
 	idle           bool
 	workerNum      int32
 	cbChannel      chan func(error)
+	taskNum        int32 //已投递未执行完成的任务数
 
 	mapTaskQueueSession map[int64]*queue.Deque[task]
 
@@ -0,0 +1,177 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+var ErrTaskCancelled = errors.New("task is cancelled")
+var ErrTaskTimeout = errors.New("task is timeout")
+var ErrTaskDropped = errors.New("tasks channel is full")
+
+// TaskStatus 任务状态
+type TaskStatus int32
+
+const (
+	TaskPending   TaskStatus = 0 //排队中
+	TaskRunning   TaskStatus = 1 //执行中
+	TaskFinished  TaskStatus = 2 //执行完成
+	TaskCancelled TaskStatus = 3 //已取消，排队中取消的任务不会再执行
+	TaskTimeout   TaskStatus = 4 //执行超时
+	TaskDropped   TaskStatus = 5 //任务队列已满被丢弃
+)
+
+func (ts TaskStatus) String() string {
+	switch ts {
+	case TaskPending:
+		return "Pending"
+	case TaskRunning:
+		return "Running"
+	case TaskFinished:
+		return "Finished"
+	case TaskCancelled:
+		return "Cancelled"
+	case TaskTimeout:
+		return "Timeout"
+	case TaskDropped:
+		return "Dropped"
+	}
+
+	return "Unknown"
+}
+
+// TaskHandle 异步任务句柄，用于取消任务与查询状态，可以在任意协程中使用
+type TaskHandle struct {
+	status     int32
+	ctx        context.Context
+	cancel     context.CancelFunc
+	stopCancel func() bool
+}
+
+// Cancel 取消任务，排队中的任务保证不再执行，执行中的任务通过ctx感知
+func (h *TaskHandle) Cancel() {
+	h.cancel()
+}
+
+// Status 获取任务当前状态
+func (h *TaskHandle) Status() TaskStatus {
+	return TaskStatus(atomic.LoadInt32(&h.status))
+}
+
+// Err 任务结束的原因，正常完成或未结束时返回nil
+func (h *TaskHandle) Err() error {
+	switch h.Status() {
+	case TaskCancelled:
+		return ErrTaskCancelled
+	case TaskTimeout:
+		return ErrTaskTimeout
+	case TaskDropped:
+		return ErrTaskDropped
+	}
+
+	return nil
+}
+
+func (h *TaskHandle) casStatus(old TaskStatus, new TaskStatus) bool {
+	return atomic.CompareAndSwapInt32(&h.status, int32(old), int32(new))
+}
+
+// release 任务结束，释放ctx相关资源
+func (h *TaskHandle) release() {
+	if h.stopCancel != nil {
+		h.stopCancel()
+	}
+	h.cancel()
+}
+
+// AsyncDoContext 带ctx与超时的异步任务，timeout从任务开始执行时计时，为0表示不限制
+// fn需要通过ctx感知取消与超时，被取消或超时的任务cb收到ErrTaskCancelled或ErrTaskTimeout
+func (c *Concurrent) AsyncDoContext(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) bool, cb func(err error)) *TaskHandle {
+	return c.AsyncDoByQueueContext(ctx, 0, timeout, fn, cb)
+}
+
+// AsyncDoByQueueContext 同AsyncDoContext，相同queueId的任务按顺序执行
+func (c *Concurrent) AsyncDoByQueueContext(ctx context.Context, queueId int64, timeout time.Duration, fn func(ctx context.Context) bool, cb func(err error)) *TaskHandle {
+	if cap(c.tasks) == 0 {
+		panic("not open concurrent")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	h := &TaskHandle{}
+	h.ctx, h.cancel = context.WithCancel(ctx)
+
+	//服务释放时取消所有未完成的任务
+	stopClose := context.AfterFunc(c.closeContext, h.cancel)
+
+	//排队中被取消，立即回调，执行时直接略过
+	stopCancel := context.AfterFunc(h.ctx, func() {
+		if h.casStatus(TaskPending, TaskCancelled) == false {
+			return
+		}
+		stopClose()
+
+		if cb != nil && c.isClosing() == false {
+			c.pushAsyncDoCallbackEvent(func(err error) {
+				cb(ErrTaskCancelled)
+			})
+		}
+	})
+	h.stopCancel = func() bool {
+		stopClose()
+		return stopCancel()
+	}
+
+	taskFn := func() bool {
+		if h.casStatus(TaskPending, TaskRunning) == false {
+			return false
+		}
+		defer h.release()
+
+		runCtx := h.ctx
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			runCtx, cancel = context.WithTimeout(h.ctx, timeout)
+			defer cancel()
+		}
+
+		bCallback := fn(runCtx)
+		switch {
+		case h.ctx.Err() != nil:
+			atomic.StoreInt32(&h.status, int32(TaskCancelled))
+		case runCtx.Err() != nil:
+			atomic.StoreInt32(&h.status, int32(TaskTimeout))
+		default:
+			atomic.StoreInt32(&h.status, int32(TaskFinished))
+		}
+
+		//服务已经释放，不再投递回调
+		if c.isClosing() == true {
+			return false
+		}
+
+		return bCallback || h.Err() != nil
+	}
+
+	var taskCb func(err error)
+	if cb != nil {
+		taskCb = func(err error) {
+			if err == nil {
+				err = h.Err()
+			}
+			cb(err)
+		}
+	}
+
+	if c.pushTask(queueId, taskFn, taskCb) == false {
+		if h.casStatus(TaskPending, TaskDropped) == true {
+			h.release()
+		}
+	}
+
+	return h
+}
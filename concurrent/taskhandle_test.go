@@ -0,0 +1,76 @@
+package concurrent
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func waitCallback(t *testing.T, c *Concurrent) {
+	select {
+	case cb := <-c.GetCallBackChannel():
+		c.DoCallback(cb)
+	case <-time.After(3 * time.Second):
+		t.Fatal("wait callback timeout")
+	}
+}
+
+func TestCancelQueueTask(t *testing.T) {
+	var c Concurrent
+	c.OpenConcurrent(1, 2, 100)
+	defer c.Close()
+
+	block := make(chan struct{})
+	var runCount int32
+	var cancelErr error
+
+	first := c.AsyncDoByQueueContext(context.Background(), 1, 0, func(ctx context.Context) bool {
+		<-block
+		atomic.AddInt32(&runCount, 1)
+		return true
+	}, func(err error) {})
+
+	second := c.AsyncDoByQueueContext(context.Background(), 1, 0, func(ctx context.Context) bool {
+		atomic.AddInt32(&runCount, 1)
+		return true
+	}, func(err error) {
+		cancelErr = err
+	})
+
+	second.Cancel()
+	waitCallback(t, &c)
+	if cancelErr != ErrTaskCancelled || second.Status() != TaskCancelled {
+		t.Fatalf("cancel queue task fail, err %v status %s", cancelErr, second.Status())
+	}
+
+	close(block)
+	waitCallback(t, &c)
+	if first.Status() != TaskFinished {
+		t.Fatalf("first task status is %s", first.Status())
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if atomic.LoadInt32(&runCount) != 1 {
+		t.Fatalf("cancelled task is executed")
+	}
+}
+
+func TestTaskTimeout(t *testing.T) {
+	var c Concurrent
+	c.OpenConcurrent(1, 1, 100)
+	defer c.Close()
+
+	var taskErr error
+	h := c.AsyncDoContext(context.Background(), 20*time.Millisecond, func(ctx context.Context) bool {
+		<-ctx.Done()
+		return false
+	}, func(err error) {
+		taskErr = err
+	})
+
+	waitCallback(t, &c)
+	if taskErr != ErrTaskTimeout || h.Status() != TaskTimeout {
+		t.Fatalf("task timeout fail, err %v status %s", taskErr, h.Status())
+	}
+}
@@ -2,6 +2,7 @@ package concurrent
 
 import (
 	"sync"
+	"sync/atomic"
 
 	"errors"
 	"fmt"
@@ -49,6 +50,7 @@ func (w *worker) run(waitGroup *sync.WaitGroup, t task) {
 }
 
 func (w *worker) exec(t *task) {
+	defer atomic.AddInt32(&w.taskNum, -1)
 	defer func() {
 		if r := recover(); r != nil {
 			errString := fmt.Sprint(r)
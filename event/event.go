@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/duanhf2012/origin/v2/log"
 	"sync"
+	"sync/atomic"
 )
 
 // EventCallBack 事件接受器
@@ -20,6 +21,9 @@ type Event struct {
 	StringExt [2]string
 	AnyExt [2]any
 	ref  bool
+
+	pooledCast bool  //广播的内存池事件，所有监听者处理完成后回收
+	castNum    int32 //尚未处理完成的监听者数量
 }
 
 var emptyEvent Event
@@ -67,6 +71,7 @@ type IEventProcessor interface {
 	UnRegEventReceiverFun(eventType EventType, receiver IEventHandler)
 
 	castEvent(event IEvent) //广播事件
+	castPooledEvent(ev *Event) //广播内存池中的事件
 	addBindEvent(eventType EventType, receiver IEventHandler, callback EventCallBack)
 	addListen(eventType EventType, receiver IEventHandler)
 	removeBindEvent(eventType EventType, receiver IEventHandler)
@@ -218,6 +223,8 @@ func (processor *EventProcessor) EventHandler(ev IEvent) {
 		}
 	}()
 
+	defer releasePooledEvent(ev)
+
 	mapCallBack, ok := processor.mapBindHandlerEvent[ev.GetEventType()]
 	if ok == false {
 		return
@@ -242,3 +249,37 @@ func (processor *EventProcessor) castEvent(event IEvent) {
 		proc.PushEvent(event)
 	}
 }
+
+// castPooledEvent 广播内存池中的事件，所有监听者处理完成后回收，没有监听者时直接回收
+func (processor *EventProcessor) castPooledEvent(ev *Event) {
+	processor.locker.RLock()
+	listenerList := make([]IEventProcessor, 0, len(processor.mapListenerEvent[ev.GetEventType()]))
+	for proc := range processor.mapListenerEvent[ev.GetEventType()] {
+		listenerList = append(listenerList, proc)
+	}
+	processor.locker.RUnlock()
+
+	if len(listenerList) == 0 {
+		DeleteEvent(ev)
+		return
+	}
+
+	ev.pooledCast = true
+	ev.castNum = int32(len(listenerList))
+	for _, proc := range listenerList {
+		if proc.PushEvent(ev) != nil {
+			releasePooledEvent(ev)
+		}
+	}
+}
+
+func releasePooledEvent(ev IEvent) {
+	e, ok := ev.(*Event)
+	if ok == false || e.pooledCast == false {
+		return
+	}
+
+	if atomic.AddInt32(&e.castNum, -1) == 0 {
+		DeleteEvent(e)
+	}
+}
@@ -7,17 +7,22 @@ const (
 	ServiceRpcRequestEvent  EventType = -1
 	ServiceRpcResponseEvent EventType = -2
 
-	Sys_Event_Tcp             EventType = -3
-	Sys_Event_Http_Event      EventType = -4
-	Sys_Event_WebSocket       EventType = -5
-	Sys_Event_Kcp             EventType = -6
-	Sys_Event_Node_Conn_Event EventType = -7
-	Sys_Event_Nats_Conn_Event EventType = -8
-	Sys_Event_DiscoverService EventType = -9
-	Sys_Event_Retire          EventType = -10
-	Sys_Event_EtcdDiscovery   EventType = -11
-	Sys_Event_Gin_Event       EventType = -12
-	Sys_Event_FrameTick       EventType = -13
-	Sys_Event_ReloadBlueprint EventType = -14
-	Sys_Event_User_Define     EventType = 1
+	Sys_Event_Tcp                  EventType = -3
+	Sys_Event_Http_Event           EventType = -4
+	Sys_Event_WebSocket            EventType = -5
+	Sys_Event_Kcp                  EventType = -6
+	Sys_Event_Node_Conn_Event      EventType = -7
+	Sys_Event_Nats_Conn_Event      EventType = -8
+	Sys_Event_DiscoverService      EventType = -9
+	Sys_Event_Retire               EventType = -10
+	Sys_Event_EtcdDiscovery        EventType = -11
+	Sys_Event_Gin_Event            EventType = -12
+	Sys_Event_FrameTick            EventType = -13
+	Sys_Event_ReloadBlueprint      EventType = -14
+	Sys_Event_RefreshNodeInfo      EventType = -15
+	Sys_Event_GlobalConfigChanged  EventType = -16
+	Sys_Event_Drain                EventType = -17
+	Sys_Event_ServiceConfigChanged EventType = -18
+	Sys_Event_EventBus             EventType = -19
+	Sys_Event_User_Define          EventType = 1
 )
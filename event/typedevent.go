@@ -0,0 +1,91 @@
+package event
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/duanhf2012/origin/v2/log"
+)
+
+// typedEventTypeBegin 类型事件的起始EventType，按数据类型自动分配，自定义的EventType需要小于该值
+const typedEventTypeBegin EventType = 1 << 30
+
+var typedEventLocker sync.Mutex
+var mapTypedEvent sync.Map //reflect.Type->EventType
+var nextTypedEventType = typedEventTypeBegin
+
+// IEventModule 事件的发布与订阅者，service.Module满足
+type IEventModule interface {
+	GetEventHandler() IEventHandler
+}
+
+// EventTypeOf 获取数据类型T对应的EventType，同一进程中不同类型的EventType不会重复
+func EventTypeOf[T any]() EventType {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if eventType, ok := mapTypedEvent.Load(t); ok == true {
+		return eventType.(EventType)
+	}
+
+	typedEventLocker.Lock()
+	defer typedEventLocker.Unlock()
+
+	if eventType, ok := mapTypedEvent.Load(t); ok == true {
+		return eventType.(EventType)
+	}
+
+	eventType := nextTypedEventType
+	nextTypedEventType++
+	mapTypedEvent.Store(t, eventType)
+	return eventType
+}
+
+// IsTypedEvent 是否为按数据类型分配的事件
+func IsTypedEvent(eventType EventType) bool {
+	return eventType >= typedEventTypeBegin
+}
+
+// Subscribe 订阅本模块所属服务发布的T类型事件，回调在订阅者所属服务协程中执行
+func Subscribe[T any](receiver IEventModule, cb func(T)) {
+	SubscribeFrom[T](receiver, receiver, cb)
+}
+
+// SubscribeFrom 订阅source所属服务发布的T类型事件，回调在订阅者所属服务协程中执行
+// 同一订阅者重复订阅同一类型时替换回调
+func SubscribeFrom[T any](source IEventModule, receiver IEventModule, cb func(T)) {
+	source.GetEventHandler().GetEventProcessor().RegEventReceiverFunc(EventTypeOf[T](), receiver.GetEventHandler(), func(ev IEvent) {
+		e, ok := ev.(*Event)
+		if ok == false {
+			log.Error("typed event conversion error", log.Int("eventType", int(ev.GetEventType())))
+			return
+		}
+
+		data, ok := e.Data.(T)
+		if ok == false {
+			log.Error("typed event data conversion error", log.Int("eventType", int(ev.GetEventType())), log.String("dataType", fmt.Sprintf("%T", e.Data)))
+			return
+		}
+
+		cb(data)
+	})
+}
+
+// Unsubscribe 取消订阅本模块所属服务发布的T类型事件
+func Unsubscribe[T any](receiver IEventModule) {
+	UnsubscribeFrom[T](receiver, receiver)
+}
+
+// UnsubscribeFrom 取消订阅source所属服务发布的T类型事件
+func UnsubscribeFrom[T any](source IEventModule, receiver IEventModule) {
+	source.GetEventHandler().GetEventProcessor().UnRegEventReceiverFun(EventTypeOf[T](), receiver.GetEventHandler())
+}
+
+// Publish 向订阅了publisher所属服务的T类型事件的订阅者广播，事件从内存池分配，所有订阅者处理完成后回收
+// data会被多个订阅者共享，引用类型的数据发布后不要再修改
+func Publish[T any](publisher IEventModule, data T) {
+	ev := NewEvent()
+	ev.Type = EventTypeOf[T]()
+	ev.Data = data
+
+	publisher.GetEventHandler().GetEventProcessor().castPooledEvent(ev)
+}
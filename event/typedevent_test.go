@@ -0,0 +1,85 @@
+package event
+
+import (
+	"testing"
+)
+
+type syncChannel struct {
+	processor IEventProcessor
+}
+
+func (sc *syncChannel) PushEvent(ev IEvent) error {
+	sc.processor.EventHandler(ev)
+	return nil
+}
+
+type testModule struct {
+	handler IEventHandler
+}
+
+func (tm *testModule) GetEventHandler() IEventHandler {
+	return tm.handler
+}
+
+func newTestModule() *testModule {
+	processor := NewEventProcessor()
+	processor.Init(&syncChannel{processor: processor})
+	handler := NewEventHandler()
+	handler.Init(processor)
+
+	return &testModule{handler: handler}
+}
+
+type loginEvent struct {
+	UserId uint64
+}
+
+type logoutEvent struct {
+	UserId uint64
+}
+
+func TestEventTypeOf(t *testing.T) {
+	loginType := EventTypeOf[loginEvent]()
+	if loginType != EventTypeOf[loginEvent]() {
+		t.Fatalf("event type of the same type changed")
+	}
+
+	if loginType == EventTypeOf[logoutEvent]() || loginType == EventTypeOf[*loginEvent]() {
+		t.Fatalf("event type of different types collide")
+	}
+
+	if IsTypedEvent(loginType) == false || IsTypedEvent(Sys_Event_User_Define) == true {
+		t.Fatalf("typed event range is wrong")
+	}
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	source := newTestModule()
+	receiver := newTestModule()
+
+	var loginList []uint64
+	var logoutNum int
+	SubscribeFrom[loginEvent](source, receiver, func(ev loginEvent) {
+		loginList = append(loginList, ev.UserId)
+	})
+	SubscribeFrom[logoutEvent](source, receiver, func(ev logoutEvent) {
+		logoutNum++
+	})
+
+	Publish(source, loginEvent{UserId: 1})
+	Publish(source, loginEvent{UserId: 2})
+	Publish(source, &loginEvent{UserId: 3})
+	if len(loginList) != 2 || loginList[0] != 1 || loginList[1] != 2 {
+		t.Fatalf("unexpected login events %v", loginList)
+	}
+	if logoutNum != 0 {
+		t.Fatalf("logout event should not be received")
+	}
+
+	UnsubscribeFrom[loginEvent](source, receiver)
+	Publish(source, loginEvent{UserId: 4})
+	Publish(source, logoutEvent{UserId: 4})
+	if len(loginList) != 2 || logoutNum != 1 {
+		t.Fatalf("unexpected events after unsubscribe %v %d", loginList, logoutNum)
+	}
+}
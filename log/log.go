@@ -110,8 +110,14 @@ func (logger *Logger) AppendSyncerFun(syncerFun func() zapcore.WriteSyncer) {
 	logger.WriteSyncerFun = append(logger.WriteSyncerFun, syncerFun)
 }
 
+// SetLogLevel 设置全局级别，可以在运行时调用
 func SetLogLevel(level zapcore.Level) {
 	LogLevel = level
+	globalLevel.Store(int32(level))
+
+	levelLocker.Lock()
+	updateMinLevel()
+	levelLocker.Unlock()
 }
 
 func (logger *Logger) Enabled(zapcore.Level) bool {
@@ -169,8 +175,10 @@ func (logger *Logger) Init() {
 		syncerList = append(syncerList, zapcore.AddSync(os.Stdout))
 	}
 
+	//写入核心按最低级别过滤，全局日志与命名日志再按各自的级别过滤
+	SetLogLevel(LogLevel)
 	for _, writer := range syncerList {
-		core := zapcore.NewCore(logger.Encoder, writer, LogLevel)
+		core := zapcore.NewCore(logger.Encoder, writer, zap.LevelEnablerFunc(isMinLevelEnabled))
 		coreList = append(coreList, core)
 	}
 
@@ -178,7 +186,7 @@ func (logger *Logger) Init() {
 		coreList = append(coreList, logger.CoreList...)
 	}
 
-	core := zapcore.NewTee(coreList...)
+	core := &levelCore{Core: zapcore.NewTee(coreList...), enabler: zap.LevelEnablerFunc(isGlobalLevelEnabled)}
 	logger.Logger = zap.New(core, zap.AddCaller(), zap.AddStacktrace(logger), zap.AddCallerSkip(1+logger.Skip))
 	logger.SugaredLogger = logger.Logger.Sugar()
 }
@@ -0,0 +1,454 @@
+package log
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// 命名日志的级别默认继承全局级别，可以在运行时覆盖。名称以.分级，如GameService.BagModule未覆盖时继承GameService的级别
+
+var globalLevel atomic.Int32      //运行时的全局级别，零值为InfoLevel
+var minLevel atomic.Int32         //全局与所有覆盖级别中的最低级别，写入核心按该级别过滤
+var levelGeneration atomic.Uint64 //全局或覆盖级别每次变化时加1，命名日志缓存的级别过期
+
+var levelLocker sync.RWMutex
+var mapNamedLevel = map[string]*namedLevel{}
+
+type namedLevel struct {
+	level       zapcore.Level
+	expireTime  time.Time //为零时不自动恢复
+	revertTimer *time.Timer
+}
+
+// NamedLogLevel 命名日志的覆盖级别
+type NamedLogLevel struct {
+	Name       string
+	Level      string
+	ExpireTime time.Time //自动恢复的时间，为零时不自动恢复
+}
+
+// levelCore 在写入核心之前按命名日志的级别过滤
+type levelCore struct {
+	zapcore.Core
+	enabler zapcore.LevelEnabler
+}
+
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return c.enabler.Enabled(lvl)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), enabler: c.enabler}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.enabler.Enabled(ent.Level) == false {
+		return ce
+	}
+
+	return c.Core.Check(ent, ce)
+}
+
+// ParseLevel 解析日志级别，stackerror等同于error
+func ParseLevel(strLevel string) (zapcore.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(strLevel)) {
+	case "debug":
+		return zapcore.DebugLevel, nil
+	case "info":
+		return zapcore.InfoLevel, nil
+	case "warn":
+		return zapcore.WarnLevel, nil
+	case "error", "stackerror":
+		return zapcore.ErrorLevel, nil
+	case "fatal":
+		return zapcore.FatalLevel, nil
+	}
+
+	return zapcore.InfoLevel, errors.New("unknown level: " + strLevel)
+}
+
+// GetLogLevel 获取运行时的全局级别
+func GetLogLevel() zapcore.Level {
+	return zapcore.Level(globalLevel.Load())
+}
+
+// updateMinLevel 需要在levelLocker中调用，级别变化后调用
+func updateMinLevel() {
+	level := zapcore.Level(globalLevel.Load())
+	for _, nl := range mapNamedLevel {
+		level = min(level, nl.level)
+	}
+
+	minLevel.Store(int32(level))
+	levelGeneration.Add(1)
+}
+
+func isMinLevelEnabled(lvl zapcore.Level) bool {
+	return lvl >= zapcore.Level(minLevel.Load())
+}
+
+func isGlobalLevelEnabled(lvl zapcore.Level) bool {
+	return lvl >= zapcore.Level(globalLevel.Load())
+}
+
+// getNamedLevel 按名称逐级向上查找覆盖级别，都未覆盖时使用全局级别
+func getNamedLevel(name string) zapcore.Level {
+	levelLocker.RLock()
+	defer levelLocker.RUnlock()
+
+	for {
+		if nl, ok := mapNamedLevel[name]; ok == true {
+			return nl.level
+		}
+
+		idx := strings.LastIndexByte(name, '.')
+		if idx < 0 {
+			return zapcore.Level(globalLevel.Load())
+		}
+		name = name[:idx]
+	}
+}
+
+// SetNamedLogLevel 覆盖命名日志的级别，revertAfter大于0时到期后自动恢复为继承
+func SetNamedLogLevel(name string, level zapcore.Level, revertAfter time.Duration) error {
+	if name == "" {
+		return errors.New("logger name is empty")
+	}
+
+	levelLocker.Lock()
+	defer levelLocker.Unlock()
+
+	if old, ok := mapNamedLevel[name]; ok == true && old.revertTimer != nil {
+		old.revertTimer.Stop()
+	}
+
+	nl := &namedLevel{level: level}
+	if revertAfter > 0 {
+		nl.expireTime = time.Now().Add(revertAfter)
+		nl.revertTimer = time.AfterFunc(revertAfter, func() {
+			levelLocker.Lock()
+			defer levelLocker.Unlock()
+
+			//期间可能被重新设置
+			if mapNamedLevel[name] != nl {
+				return
+			}
+			delete(mapNamedLevel, name)
+			updateMinLevel()
+			Info("named log level is reverted", String("name", name))
+		})
+	}
+
+	mapNamedLevel[name] = nl
+	updateMinLevel()
+	return nil
+}
+
+// ResetNamedLogLevel 取消命名日志的覆盖级别
+func ResetNamedLogLevel(name string) {
+	levelLocker.Lock()
+	defer levelLocker.Unlock()
+
+	nl, ok := mapNamedLevel[name]
+	if ok == false {
+		return
+	}
+
+	if nl.revertTimer != nil {
+		nl.revertTimer.Stop()
+	}
+	delete(mapNamedLevel, name)
+	updateMinLevel()
+}
+
+// GetNamedLogLevelList 获取所有覆盖的级别，按名称排序
+func GetNamedLogLevelList() []NamedLogLevel {
+	levelLocker.RLock()
+	defer levelLocker.RUnlock()
+
+	levelList := make([]NamedLogLevel, 0, len(mapNamedLevel))
+	for name, nl := range mapNamedLevel {
+		levelList = append(levelList, NamedLogLevel{Name: name, Level: nl.level.String(), ExpireTime: nl.expireTime})
+	}
+
+	slices.SortFunc(levelList, func(a, b NamedLogLevel) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return levelList
+}
+
+var nodeId atomic.Pointer[string]
+
+// SetNodeId 设置结点Id，命名日志的每一行都会附加
+func SetNodeId(id string) {
+	nodeId.Store(&id)
+}
+
+func getNodeId() string {
+	if id := nodeId.Load(); id != nil {
+		return *id
+	}
+
+	return ""
+}
+
+// ILogContextSource 日志上下文的来源，如正在处理的Rpc请求，写日志时才生成字段
+type ILogContextSource interface {
+	AppendLogFields(fields []zap.Field) []zap.Field
+}
+
+// LogContext 日志上下文，服务处理Rpc、事件或定时器时设置，使用该上下文的命名日志在写入时附加
+// 只在服务协程中设置与写日志，其他协程中写日志使用不带上下文的命名日志
+type LogContext struct {
+	field  zap.Field
+	source ILogContextSource
+}
+
+// Set 设置上下文字段，覆盖之前设置的字段与来源
+func (lc *LogContext) Set(field zap.Field) {
+	lc.field = field
+	lc.source = nil
+}
+
+// SetSource 设置上下文的来源，写日志时才从来源生成字段，覆盖之前设置的字段
+func (lc *LogContext) SetSource(source ILogContextSource) {
+	lc.field = zap.Field{}
+	lc.source = source
+}
+
+// Clear 清空上下文
+func (lc *LogContext) Clear() {
+	lc.field = zap.Field{}
+	lc.source = nil
+}
+
+// Get 获取当前的上下文字段
+func (lc *LogContext) Get() []zap.Field {
+	return lc.appendFields(nil)
+}
+
+func (lc *LogContext) appendFields(fields []zap.Field) []zap.Field {
+	if lc == nil {
+		return fields
+	}
+
+	if lc.source != nil {
+		return lc.source.AppendLogFields(fields)
+	}
+	if lc.field.Key != "" {
+		return append(fields, lc.field)
+	}
+
+	return fields
+}
+
+type cachedLevel struct {
+	generation uint64
+	level      zapcore.Level
+}
+
+type namedZapLogger struct {
+	base   *zap.Logger
+	nodeId string
+	logger *zap.Logger
+	sugar  *zap.SugaredLogger
+}
+
+// NamedLogger 命名日志，按名称的级别过滤后写入全局日志，附加结点Id、固定字段与上下文字段
+type NamedLogger struct {
+	name   string
+	fields []zap.Field
+	ctx    *LogContext
+	cache  atomic.Pointer[namedZapLogger]
+	level  atomic.Pointer[cachedLevel] //级别未变化时不重新查找
+}
+
+func NewNamedLogger(name string, fields ...zap.Field) *NamedLogger {
+	return &NamedLogger{name: name, fields: fields}
+}
+
+// NewContextLogger 创建使用上下文的命名日志，ctx通常由服务持有，服务下所有模块的日志共用
+func NewContextLogger(name string, ctx *LogContext, fields ...zap.Field) *NamedLogger {
+	return &NamedLogger{name: name, fields: fields, ctx: ctx}
+}
+
+func (nl *NamedLogger) GetName() string {
+	return nl.name
+}
+
+// With 创建附加固定字段的子日志，名称与上下文不变
+func (nl *NamedLogger) With(fields ...zap.Field) *NamedLogger {
+	return &NamedLogger{name: nl.name, fields: append(slices.Clip(nl.fields), fields...), ctx: nl.ctx}
+}
+
+// Enabled 判断级别是否输出，可以在构造开销较大的日志前判断
+func (nl *NamedLogger) Enabled(lvl zapcore.Level) bool {
+	return isMinLevelEnabled(lvl) == true && lvl >= nl.getLevel()
+}
+
+// getLevel 获取缓存的级别，级别变化后重新查找
+func (nl *NamedLogger) getLevel() zapcore.Level {
+	//先读取版本，查找期间级别再次变化时下次调用会重新查找
+	generation := levelGeneration.Load()
+	if cache := nl.level.Load(); cache != nil && cache.generation == generation {
+		return cache.level
+	}
+
+	level := getNamedLevel(nl.name)
+	nl.level.Store(&cachedLevel{generation: generation, level: level})
+	return level
+}
+
+// getZapLogger 全局日志重新初始化或结点Id变化后重新创建
+func (nl *NamedLogger) getZapLogger() *namedZapLogger {
+	base := gLogger.Logger
+	id := getNodeId()
+	if cache := nl.cache.Load(); cache != nil && cache.base == base && cache.nodeId == id {
+		return cache
+	}
+
+	enabler := zap.LevelEnablerFunc(nl.Enabled)
+	logger := base.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if lc, ok := core.(*levelCore); ok == true {
+			core = lc.Core
+		}
+		return &levelCore{Core: core, enabler: enabler}
+	}), zap.AddCallerSkip(1)).Named(nl.name)
+
+	if id != "" {
+		logger = logger.With(zap.String("nodeId", id))
+	}
+	if len(nl.fields) > 0 {
+		logger = logger.With(nl.fields...)
+	}
+
+	cache := &namedZapLogger{base: base, nodeId: id, logger: logger, sugar: logger.Sugar()}
+	nl.cache.Store(cache)
+	return cache
+}
+
+func (nl *NamedLogger) log(lvl zapcore.Level, msg string, fields []zap.Field) {
+	ce := nl.getZapLogger().logger.Check(lvl, msg)
+	if ce == nil {
+		return
+	}
+
+	ce.Write(nl.ctx.appendFields(slices.Clip(fields))...)
+}
+
+// getSugar 已判断级别，只在需要输出时附加上下文
+func (nl *NamedLogger) getSugar(lvl zapcore.Level) *zap.SugaredLogger {
+	zl := nl.getZapLogger()
+	if zl.logger.Core().Enabled(lvl) == false {
+		return nil
+	}
+
+	if ctxFields := nl.ctx.Get(); len(ctxFields) > 0 {
+		return zl.logger.With(ctxFields...).Sugar()
+	}
+
+	return zl.sugar
+}
+
+func (nl *NamedLogger) logf(lvl zapcore.Level, template string, args []any) {
+	if sugar := nl.getSugar(lvl); sugar != nil {
+		sugar.Logf(lvl, template, args...)
+	}
+}
+
+func (nl *NamedLogger) logln(lvl zapcore.Level, args []any) {
+	if sugar := nl.getSugar(lvl); sugar != nil {
+		sugar.Logln(lvl, args...)
+	}
+}
+
+func (nl *NamedLogger) Debug(msg string, fields ...zap.Field) {
+	nl.log(zapcore.DebugLevel, msg, fields)
+}
+
+func (nl *NamedLogger) Info(msg string, fields ...zap.Field) {
+	nl.log(zapcore.InfoLevel, msg, fields)
+}
+
+func (nl *NamedLogger) Warn(msg string, fields ...zap.Field) {
+	nl.log(zapcore.WarnLevel, msg, fields)
+}
+
+func (nl *NamedLogger) Error(msg string, fields ...zap.Field) {
+	nl.log(zapcore.ErrorLevel, msg, fields)
+}
+
+func (nl *NamedLogger) StackError(msg string, fields ...zap.Field) {
+	gLogger.stack = true
+	nl.log(zapcore.ErrorLevel, msg, fields)
+	gLogger.stack = false
+}
+
+func (nl *NamedLogger) Fatal(msg string, fields ...zap.Field) {
+	gLogger.stack = true
+	nl.log(zapcore.FatalLevel, msg, fields)
+	gLogger.stack = false
+}
+
+func (nl *NamedLogger) Debugf(template string, args ...any) {
+	nl.logf(zapcore.DebugLevel, template, args)
+}
+
+func (nl *NamedLogger) Infof(template string, args ...any) {
+	nl.logf(zapcore.InfoLevel, template, args)
+}
+
+func (nl *NamedLogger) Warnf(template string, args ...any) {
+	nl.logf(zapcore.WarnLevel, template, args)
+}
+
+func (nl *NamedLogger) Errorf(template string, args ...any) {
+	nl.logf(zapcore.ErrorLevel, template, args)
+}
+
+func (nl *NamedLogger) StackErrorf(template string, args ...any) {
+	gLogger.stack = true
+	nl.logf(zapcore.ErrorLevel, template, args)
+	gLogger.stack = false
+}
+
+func (nl *NamedLogger) Fatalf(template string, args ...any) {
+	gLogger.stack = true
+	nl.logf(zapcore.FatalLevel, template, args)
+	gLogger.stack = false
+}
+
+func (nl *NamedLogger) SDebug(args ...interface{}) {
+	nl.logln(zapcore.DebugLevel, args)
+}
+
+func (nl *NamedLogger) SInfo(args ...interface{}) {
+	nl.logln(zapcore.InfoLevel, args)
+}
+
+func (nl *NamedLogger) SWarn(args ...interface{}) {
+	nl.logln(zapcore.WarnLevel, args)
+}
+
+func (nl *NamedLogger) SError(args ...interface{}) {
+	nl.logln(zapcore.ErrorLevel, args)
+}
+
+func (nl *NamedLogger) SStackError(args ...interface{}) {
+	gLogger.stack = true
+	nl.logln(zapcore.ErrorLevel, args)
+	gLogger.stack = false
+}
+
+func (nl *NamedLogger) SFatal(args ...interface{}) {
+	gLogger.stack = true
+	nl.logln(zapcore.FatalLevel, args)
+	gLogger.stack = false
+}
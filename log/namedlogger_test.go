@@ -0,0 +1,184 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func initTestLogger(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	bOpenConsole := false
+	OpenConsole = &bOpenConsole
+	LogLevel = zapcore.InfoLevel
+
+	logger := &Logger{Encoder: GetTxtEncoder()}
+	logger.SetSyncers(func() zapcore.WriteSyncer { return zapcore.AddSync(&buf) })
+	logger.Init()
+
+	oldLogger := gLogger
+	gLogger = logger
+	t.Cleanup(func() {
+		gLogger = oldLogger
+		OpenConsole = nil
+		for _, namedLevel := range GetNamedLogLevelList() {
+			ResetNamedLogLevel(namedLevel.Name)
+		}
+		SetLogLevel(zapcore.InfoLevel)
+		nodeId.Store(nil)
+	})
+
+	return &buf
+}
+
+func TestNamedLoggerLevel(t *testing.T) {
+	buf := initTestLogger(t)
+	serviceLogger := NewNamedLogger("GameService")
+	moduleLogger := NewNamedLogger("GameService.BagModule")
+	otherLogger := NewNamedLogger("ChatService")
+
+	serviceLogger.Debug("service debug 1")
+	if err := SetNamedLogLevel("GameService", zapcore.DebugLevel, 0); err != nil {
+		t.Fatal(err)
+	}
+	serviceLogger.Debug("service debug 2")
+	moduleLogger.Debug("module debug")
+	otherLogger.Debug("other debug")
+	Debug("global debug")
+
+	if err := SetNamedLogLevel("GameService.BagModule", zapcore.WarnLevel, 0); err != nil {
+		t.Fatal(err)
+	}
+	moduleLogger.Info("module info")
+	moduleLogger.Warn("module warn")
+
+	output := buf.String()
+	for _, msg := range []string{"service debug 2", "module debug", "module warn"} {
+		if strings.Contains(output, msg) == false {
+			t.Fatalf("%q is not in output:\n%s", msg, output)
+		}
+	}
+	for _, msg := range []string{"service debug 1", "other debug", "global debug", "module info"} {
+		if strings.Contains(output, msg) == true {
+			t.Fatalf("%q should not be in output:\n%s", msg, output)
+		}
+	}
+	if strings.Contains(output, "GameService.BagModule") == false {
+		t.Fatalf("logger name is not in output:\n%s", output)
+	}
+
+	ResetNamedLogLevel("GameService.BagModule")
+	ResetNamedLogLevel("GameService")
+	if moduleLogger.Enabled(zapcore.DebugLevel) == true || moduleLogger.Enabled(zapcore.InfoLevel) == false {
+		t.Fatal("module logger should inherit global level after reset")
+	}
+}
+
+func TestNamedLoggerGlobalLevel(t *testing.T) {
+	initTestLogger(t)
+	logger := NewNamedLogger("GlobalService.BagModule")
+
+	if logger.Enabled(zapcore.DebugLevel) == true {
+		t.Fatal("debug should be disabled")
+	}
+
+	//缓存的级别在全局级别变化后失效
+	SetLogLevel(zapcore.DebugLevel)
+	if logger.Enabled(zapcore.DebugLevel) == false {
+		t.Fatal("debug should be enabled after global level changed")
+	}
+
+	if err := SetNamedLogLevel("GlobalService", zapcore.ErrorLevel, 0); err != nil {
+		t.Fatal(err)
+	}
+	if logger.Enabled(zapcore.WarnLevel) == true {
+		t.Fatal("warn should be disabled after parent level changed")
+	}
+}
+
+func TestNamedLoggerRevert(t *testing.T) {
+	initTestLogger(t)
+	logger := NewNamedLogger("RevertService")
+
+	if err := SetNamedLogLevel("RevertService", zapcore.DebugLevel, 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if logger.Enabled(zapcore.DebugLevel) == false {
+		t.Fatal("debug should be enabled")
+	}
+	if levelList := GetNamedLogLevelList(); len(levelList) != 1 || levelList[0].ExpireTime.IsZero() == true {
+		t.Fatalf("named level list %+v", levelList)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if logger.Enabled(zapcore.DebugLevel) == true {
+		t.Fatal("debug should be reverted")
+	}
+	if levelList := GetNamedLogLevelList(); len(levelList) != 0 {
+		t.Fatalf("named level list %+v", levelList)
+	}
+}
+
+type testLogSource []zap.Field
+
+func (s testLogSource) AppendLogFields(fields []zap.Field) []zap.Field {
+	return append(fields, s...)
+}
+
+func TestNamedLoggerContext(t *testing.T) {
+	buf := initTestLogger(t)
+	SetNodeId("node-1")
+
+	var ctx LogContext
+	logger := NewContextLogger("GameService", &ctx, String("service", "GameService"))
+	moduleLogger := NewContextLogger("GameService.BagModule", &ctx, String("service", "GameService")).With(Int("bagId", 7))
+
+	ctx.SetSource(testLogSource{String("rpcMethod", "GameService.RPC_Login"), String("traceId", "t-100")})
+	logger.Info("login", String("userId", "u1"))
+	moduleLogger.Infof("bag %d", 3)
+	logger.SWarn("warn", 1)
+	ctx.Clear()
+	logger.Info("after clear")
+
+	lineList := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lineList) != 4 {
+		t.Fatalf("output:\n%s", buf.String())
+	}
+
+	for i, line := range lineList {
+		if strings.Contains(line, `"nodeId": "node-1"`) == false || strings.Contains(line, `"service": "GameService"`) == false {
+			t.Fatalf("line %d has no node or service: %s", i, line)
+		}
+		if strings.Contains(line, "namedlogger_test.go") == false {
+			t.Fatalf("line %d caller is wrong: %s", i, line)
+		}
+
+		bCtx := strings.Contains(line, `"rpcMethod": "GameService.RPC_Login"`) && strings.Contains(line, `"traceId": "t-100"`)
+		if bCtx != (i < 3) {
+			t.Fatalf("line %d context is wrong: %s", i, line)
+		}
+	}
+
+	if strings.Contains(lineList[0], `"userId": "u1"`) == false {
+		t.Fatalf("line has no field: %s", lineList[0])
+	}
+	if strings.Contains(lineList[1], "bag 3") == false || strings.Contains(lineList[1], `"bagId": 7`) == false {
+		t.Fatalf("sugared line is wrong: %s", lineList[1])
+	}
+	if strings.Contains(lineList[2], "warn 1") == false {
+		t.Fatalf("sugared line is wrong: %s", lineList[2])
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	if level, err := ParseLevel(" StackError "); err != nil || level != zapcore.ErrorLevel {
+		t.Fatalf("level %v err %v", level, err)
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Fatal("unknown level should fail")
+	}
+}
@@ -30,6 +30,16 @@ var preSetupTemplateService []func() service.IService
 var profilerInterval time.Duration
 var configDir = "./config/"
 var NodeIsRun = false
+var maxDrainTime = 30 * time.Second
+var bRetire = false
+var timerBackend = timer.HeapBackend
+var watchdogCfg *profiler.WatchdogCfg
+
+// 排空进度检查间隔
+const drainCheckInterval = time.Second
+
+// 等待进程退出时在排空时长外额外等待的时间，用于服务停止与日志落盘
+const processExitMargin = 10 * time.Second
 
 const (
 	SingleStop   syscall.Signal = 10
@@ -56,16 +66,64 @@ func init() {
 	console.RegisterCommandString("config", "", "<-config path> Configuration file path.", setConfigPath)
 	console.RegisterCommandString("console", "", "<-console true|false> Turn on or off screen log output.", openConsole)
 	console.RegisterCommandString("loglevel", "debug", "<-loglevel debug|info|warn|error|stackerror|fatal> Set loglevel.", setLevel)
+	console.RegisterCommandString("loglevels", "", "<-loglevels name=level,name=level> Set loglevel of service or module at startup, e.g. GameService=debug,GameService.BagModule=info. Use AdminService.RPC_SetLogLevel at runtime.", setNamedLevels)
 	console.RegisterCommandString("logpath", "", "<-logpath path> Set log file path.", setLogPath)
 	console.RegisterCommandInt("logsize", 0, "<-logsize size> Set log size(MB).", setLogSize)
 	console.RegisterCommandInt("logchanlen", 0, "<-logchanlen len> Set log channel len.", setLogChanLen)
 	console.RegisterCommandString("pprof", "", "<-pprof ip:port> Open performance analysis.", setPprof)
+	console.RegisterCommandInt("draintime", 30, "<-draintime seconds> Max drain time before stop,0 means stop immediately.", setDrainTime)
 }
 
 func notifyAllServiceRetire() {
+	bRetire = true
 	service.NotifyAllServiceRetire()
 }
 
+// drainNode 退休并排空结点，等待所有服务的事件、并发任务、连接以及未完成的Rpc调用处理完成，超时或再次收到停止信号时返回
+func drainNode() {
+	log.Info("start draining node", log.String("maxDrainTime", maxDrainTime.String()))
+	if bRetire == false {
+		notifyAllServiceRetire()
+	}
+	service.NotifyAllServiceDrain()
+
+	ticker := time.NewTicker(drainCheckInterval)
+	defer ticker.Stop()
+	deadline := time.NewTimer(maxDrainTime)
+	defer deadline.Stop()
+
+	beginTime := time.Now()
+	for {
+		pendingList := service.GetAllServiceDrainPending()
+		pendingCallNum := cluster.GetCluster().GetPendingCallNum()
+		if len(pendingList) == 0 && pendingCallNum == 0 {
+			log.Info("node has been drained", log.String("drainTime", time.Since(beginTime).String()))
+			return
+		}
+
+		var pending strings.Builder
+		for _, p := range pendingList {
+			if pending.Len() > 0 {
+				pending.WriteString(",")
+			}
+			pending.WriteString(fmt.Sprintf("%s.%s:%d", p.ServiceName, p.Name, p.Num))
+		}
+		log.Info("node is draining", log.String("waitTime", time.Since(beginTime).String()), log.Int("pendingCall", pendingCallNum), log.String("pending", pending.String()))
+
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			log.Warn("drain node timeout", log.Int("pendingCall", pendingCallNum), log.String("pending", pending.String()))
+			return
+		case s := <-sig:
+			if s.(syscall.Signal) != SignalRetire {
+				log.Warn("receipt stop signal while draining, stop immediately.")
+				return
+			}
+		}
+	}
+}
+
 func usage(val interface{}) error {
 	ret := val.(bool)
 	if ret == false {
@@ -160,6 +218,7 @@ func initNode(id string) {
 		log.Error("Init cluster fail", log.ErrorField("error", err))
 		os.Exit(1)
 	}
+	service.SetCheckpointKeyPrefix(GetNodeId())
 
 	err = initLog()
 	if err != nil {
@@ -178,17 +237,13 @@ func initNode(id string) {
 		if len(splitServiceName) == 2 {
 			serviceName = splitServiceName[0]
 			templateServiceName := splitServiceName[1]
-			for _, newSer := range preSetupTemplateService {
-				ser := newSer()
-				ser.OnSetup(ser)
-				if ser.GetName() == templateServiceName {
-					ser.SetName(serviceName)
-					ser.Init(ser, cluster.GetRpcClient, cluster.GetRpcServer, cluster.GetCluster().GetServiceCfg(ser.GetName()))
-					service.Setup(ser)
-
-					bSetup = true
-					break
-				}
+			ser := newTemplateService(templateServiceName)
+			if ser != nil {
+				ser.SetName(serviceName)
+				ser.Init(ser, cluster.GetRpcClient, cluster.GetRpcServer, cluster.GetCluster().GetServiceCfg(ser.GetName()))
+				service.Setup(ser)
+
+				bSetup = true
 			}
 
 			if bSetup == false {
@@ -231,6 +286,7 @@ func initLog() error {
 	fileName := fmt.Sprintf("%s.log", localNodeInfo.NodeId)
 	logger.FileName = fileName
 
+	log.SetNodeId(localNodeInfo.NodeId)
 	logger.Init()
 	return nil
 }
@@ -296,9 +352,33 @@ func stopNode(args interface{}) error {
 	}
 
 	KillProcess(processId)
+	if waitProcessExit(processId, maxDrainTime+processExitMargin) == false {
+		os.Exit(1)
+	}
 	return nil
 }
 
+// waitProcessExit 等待进程排空后退出，并输出等待进度，超过timeout未退出时返回false
+// timeout按本命令的-draintime计算，需要与被停止结点的排空时长一致
+func waitProcessExit(processId int, timeout time.Duration) bool {
+	beginTime := time.Now()
+	for {
+		_, err := sysprocess.GetProcessNameByPID(int32(processId))
+		if err != nil {
+			fmt.Printf("processid %d has exited after %s.\n", processId, time.Since(beginTime).Round(time.Second))
+			return true
+		}
+
+		if time.Since(beginTime) >= timeout {
+			fmt.Printf("processid %d has not exited after %s, timeout.\n", processId, timeout)
+			return false
+		}
+
+		fmt.Printf("waiting for processid %d to drain and exit(%s)...\n", processId, time.Since(beginTime).Round(time.Second))
+		time.Sleep(drainCheckInterval)
+	}
+}
+
 func startNode(args interface{}) error {
 	//1.解析参数
 	param := args.(string)
@@ -342,7 +422,7 @@ func startNode(args interface{}) error {
 
 	//2.记录进程id号
 	writeProcessPid(strNodeId)
-	timer.StartTimer(10*time.Millisecond, 1000000)
+	timer.StartTimerWithBackend(timerBackend, 10*time.Millisecond, 1000000)
 
 	//3.初始化node
 	defer log.GetLogger().Logger.Sync()
@@ -359,6 +439,13 @@ func startNode(args interface{}) error {
 	}
 
 	//6.监听程序退出信号&性能报告
+	if watchdogCfg != nil {
+		watchdogCfg.NodeId = nodeId
+		if err = profiler.StartWatchdog(*watchdogCfg); err != nil {
+			log.Error("start watchdog fail", log.ErrorField("err", err))
+		}
+	}
+
 	var pProfilerTicker *time.Ticker = &time.Ticker{}
 	if profilerInterval > 0 {
 		pProfilerTicker = time.NewTicker(profilerInterval)
@@ -372,6 +459,11 @@ func startNode(args interface{}) error {
 			if signal == SignalRetire {
 				log.Info("receipt retire signal.")
 				notifyAllServiceRetire()
+			} else if signal == SingleStop && maxDrainTime > 0 {
+				//-stop命令先排空再停止
+				log.Info("receipt stop signal, drain before stop.")
+				drainNode()
+				NodeIsRun = false
 			} else {
 				NodeIsRun = false
 				log.Info("receipt stop signal.")
@@ -382,6 +474,7 @@ func startNode(args interface{}) error {
 	}
 
 	//7.退出
+	profiler.StopWatchdog()
 	service.StopAllService()
 	cluster.GetCluster().Stop()
 
@@ -432,6 +525,16 @@ func OpenProfilerReport(interval time.Duration) {
 	profilerInterval = interval
 }
 
+// OpenWatchdog 开启看门狗，开启了性能分析的服务卡在某个事件中时转储堆栈与profile，需要在Start前调用
+func OpenWatchdog(cfg profiler.WatchdogCfg) {
+	watchdogCfg = &cfg
+}
+
+// SetTimerBackend 设置定时器的调度实现，需要在Start前调用
+func SetTimerBackend(backend timer.TimerBackend) {
+	timerBackend = backend
+}
+
 func openConsole(args interface{}) error {
 	if args == "" {
 		return nil
@@ -474,6 +577,36 @@ func setLevel(args interface{}) error {
 	return nil
 }
 
+// setNamedLevels 只在启动时设置，运行时通过AdminService.RPC_SetLogLevel修改
+func setNamedLevels(args interface{}) error {
+	if args == "" {
+		return nil
+	}
+
+	for _, strNamedLevel := range strings.Split(args.(string), ",") {
+		strNamedLevel = strings.TrimSpace(strNamedLevel)
+		if strNamedLevel == "" {
+			continue
+		}
+
+		name, strLevel, ok := strings.Cut(strNamedLevel, "=")
+		if ok == false {
+			return errors.New("invalid loglevels: " + strNamedLevel)
+		}
+
+		level, err := log.ParseLevel(strLevel)
+		if err != nil {
+			return err
+		}
+
+		if err = log.SetNamedLogLevel(strings.TrimSpace(name), level, 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func setLogPath(args interface{}) error {
 	if args == "" {
 		return nil
@@ -505,6 +638,19 @@ func setLogSize(args interface{}) error {
 	return nil
 }
 
+func setDrainTime(args interface{}) error {
+	drainTime, ok := args.(int)
+	if ok == false {
+		return errors.New("param draintime is error")
+	}
+	if drainTime < 0 {
+		return errors.New("param draintime is error")
+	}
+
+	maxDrainTime = time.Duration(drainTime) * time.Second
+	return nil
+}
+
 func setLogChanLen(args interface{}) error {
 	logChanLen, ok := args.(int)
 	if ok == false {
@@ -0,0 +1,100 @@
+package node
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/duanhf2012/origin/v2/cluster"
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/service"
+)
+
+var templateServiceLocker sync.Mutex
+
+// newTemplateService 通过模板服务名创建服务实例
+func newTemplateService(templateServiceName string) service.IService {
+	for _, newSer := range preSetupTemplateService {
+		ser := newSer()
+		ser.OnSetup(ser)
+		if ser.GetName() == templateServiceName {
+			return ser
+		}
+	}
+
+	return nil
+}
+
+// SpawnTemplateService 运行时通过模板服务创建服务实例，完成OnInit与Start后通过服务发现发布
+// serviceCfg为nil时使用配置文件中serviceName对应的配置
+func SpawnTemplateService(serviceName string, templateServiceName string, bPublic bool, serviceCfg interface{}) error {
+	templateServiceLocker.Lock()
+	defer templateServiceLocker.Unlock()
+
+	if NodeIsRun == false {
+		return fmt.Errorf("node is not running,cannot spawn service %s", serviceName)
+	}
+
+	if service.GetService(serviceName) != nil {
+		return fmt.Errorf("service %s already exists", serviceName)
+	}
+
+	ser := newTemplateService(templateServiceName)
+	if ser == nil {
+		return fmt.Errorf("template service %s not found", templateServiceName)
+	}
+
+	if serviceCfg == nil {
+		serviceCfg = cluster.GetCluster().GetServiceCfg(serviceName)
+	}
+
+	ser.SetName(serviceName)
+	ser.Init(ser, cluster.GetRpcClient, cluster.GetRpcServer, serviceCfg)
+	if service.Setup(ser) == false {
+		return fmt.Errorf("service %s already exists", serviceName)
+	}
+
+	err := ser.OnInit()
+	if err != nil {
+		service.Remove(serviceName)
+		return fmt.Errorf("failed to initialize service %s: %w", serviceName, err)
+	}
+	ser.Start()
+
+	err = cluster.GetCluster().AddLocalService(serviceName, templateServiceName, bPublic, serviceCfg)
+	if err != nil {
+		ser.Stop()
+		service.Remove(serviceName)
+		return err
+	}
+
+	log.Info("spawn template service", log.String("serviceName", serviceName), log.String("templateServiceName", templateServiceName), log.Bool("public", bPublic))
+	return nil
+}
+
+// ReleaseTemplateService 退休并释放运行时创建的模板服务实例，不允许在该服务自身协程中调用
+func ReleaseTemplateService(serviceName string) error {
+	templateServiceLocker.Lock()
+	defer templateServiceLocker.Unlock()
+
+	ser := service.GetService(serviceName)
+	if ser == nil {
+		return fmt.Errorf("service %s not found", serviceName)
+	}
+
+	if cluster.GetCluster().IsLocalTemplateService(serviceName) == false {
+		return fmt.Errorf("service %s is not a template service", serviceName)
+	}
+
+	//先从服务发现中下线，调用方不再路由到该服务
+	ser.SetRetire()
+	err := cluster.GetCluster().RemoveLocalService(serviceName)
+	if err != nil {
+		return err
+	}
+
+	ser.Stop()
+	service.Remove(serviceName)
+
+	log.Info("release template service", log.String("serviceName", serviceName))
+	return nil
+}
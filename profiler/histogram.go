@@ -0,0 +1,172 @@
+package profiler
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// 对数线性分桶，与HDR Histogram相同，每个2的幂区间分为16个桶，以微秒计，相对误差约6%
+const (
+	histSubBits    = 4
+	histSubNum     = 1 << histSubBits
+	histMaxMsb     = 40 //最大约12天
+	histBucketNum  = histSubNum + (histMaxMsb-histSubBits+1)*histSubNum
+	defaultSlotNum = 6
+)
+
+// DefaultLatencyWindow 耗时统计的滑动窗口时长
+var DefaultLatencyWindow = time.Minute
+
+// histogram 耗时直方图，桶在首次记录时分配
+type histogram struct {
+	counts []uint32
+	count  uint64
+	sum    time.Duration
+	max    time.Duration
+}
+
+func getBucketIndex(d time.Duration) int {
+	v := uint64(max(d/time.Microsecond, 0))
+	if v < histSubNum {
+		return int(v)
+	}
+
+	msb := bits.Len64(v) - 1
+	if msb > histMaxMsb {
+		return histBucketNum - 1
+	}
+
+	shift := msb - histSubBits
+	return histSubNum + shift*histSubNum + int((v>>uint(shift))&(histSubNum-1))
+}
+
+// getBucketValue 桶的中间值
+func getBucketValue(idx int) time.Duration {
+	if idx < histSubNum {
+		return time.Duration(idx) * time.Microsecond
+	}
+
+	shift := (idx - histSubNum) / histSubNum
+	sub := (idx - histSubNum) % histSubNum
+	lower := uint64(histSubNum+sub) << uint(shift)
+	return time.Duration(lower+(uint64(1)<<uint(shift))/2) * time.Microsecond
+}
+
+func (h *histogram) record(d time.Duration) {
+	if h.counts == nil {
+		h.counts = make([]uint32, histBucketNum)
+	}
+
+	h.counts[getBucketIndex(d)]++
+	h.count++
+	h.sum += d
+	h.max = max(h.max, d)
+}
+
+func (h *histogram) reset() {
+	if h.counts != nil {
+		clear(h.counts)
+	}
+	h.count = 0
+	h.sum = 0
+	h.max = 0
+}
+
+func (h *histogram) merge(o *histogram) {
+	if o.count == 0 {
+		return
+	}
+
+	if h.counts == nil {
+		h.counts = make([]uint32, histBucketNum)
+	}
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	h.count += o.count
+	h.sum += o.sum
+	h.max = max(h.max, o.max)
+}
+
+// quantile 获取分位数，q范围为0-1
+func (h *histogram) quantile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(q * float64(h.count)))
+	rank = max(rank, 1)
+
+	var cum uint64
+	for i, c := range h.counts {
+		cum += uint64(c)
+		if cum >= rank {
+			if i == histBucketNum-1 {
+				//超出范围的桶
+				return h.max
+			}
+			return min(getBucketValue(i), h.max)
+		}
+	}
+
+	return h.max
+}
+
+// LatencyStat 耗时统计
+type LatencyStat struct {
+	Count uint64
+	Avg   time.Duration
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+func (h *histogram) stat() LatencyStat {
+	stat := LatencyStat{Count: h.count, Max: h.max}
+	if h.count == 0 {
+		return stat
+	}
+
+	stat.Avg = h.sum / time.Duration(h.count)
+	stat.P50 = h.quantile(0.5)
+	stat.P90 = h.quantile(0.9)
+	stat.P99 = h.quantile(0.99)
+	return stat
+}
+
+// rollingHistogram 滑动窗口直方图，窗口分为多个时间片，过期的时间片在写入时重置
+type rollingHistogram struct {
+	slotDur   time.Duration
+	slots     [defaultSlotNum]histogram
+	slotEpoch [defaultSlotNum]int64 //时间片序号
+}
+
+func newRollingHistogram(window time.Duration) *rollingHistogram {
+	return &rollingHistogram{slotDur: max(window/defaultSlotNum, time.Millisecond)}
+}
+
+func (rh *rollingHistogram) record(now time.Time, d time.Duration) {
+	epoch := now.UnixNano() / int64(rh.slotDur)
+	slot := epoch % defaultSlotNum
+	if rh.slotEpoch[slot] != epoch {
+		rh.slots[slot].reset()
+		rh.slotEpoch[slot] = epoch
+	}
+
+	rh.slots[slot].record(d)
+}
+
+// snapshot 合并窗口内的时间片
+func (rh *rollingHistogram) snapshot(now time.Time) *histogram {
+	var h histogram
+	epoch := now.UnixNano() / int64(rh.slotDur)
+	for slot := range rh.slots {
+		if epoch-rh.slotEpoch[slot] < defaultSlotNum {
+			h.merge(&rh.slots[slot])
+		}
+	}
+
+	return &h
+}
@@ -0,0 +1,102 @@
+package profiler
+
+import (
+	"testing"
+	"time"
+)
+
+func checkApprox(t *testing.T, name string, got time.Duration, want time.Duration) {
+	t.Helper()
+	diff := got - want
+	if diff < 0 {
+		diff = -diff
+	}
+
+	if diff > want/16+time.Microsecond {
+		t.Fatalf("%s = %v, want about %v", name, got, want)
+	}
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	var h histogram
+	for i := 1; i <= 1000; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+
+	stat := h.stat()
+	if stat.Count != 1000 || stat.Max != time.Second {
+		t.Fatalf("count %d max %v", stat.Count, stat.Max)
+	}
+
+	checkApprox(t, "avg", stat.Avg, 500500*time.Microsecond)
+	checkApprox(t, "p50", stat.P50, 500*time.Millisecond)
+	checkApprox(t, "p90", stat.P90, 900*time.Millisecond)
+	checkApprox(t, "p99", stat.P99, 990*time.Millisecond)
+}
+
+func TestHistogramSmallAndLarge(t *testing.T) {
+	var h histogram
+	h.record(0)
+	h.record(-time.Second)
+	h.record(3 * time.Microsecond)
+	h.record(1000 * time.Hour)
+
+	if q := h.quantile(0.5); q != 0 {
+		t.Fatalf("p50 = %v, want 0", q)
+	}
+	if q := h.quantile(0.75); q != 3*time.Microsecond {
+		t.Fatalf("p75 = %v, want 3us", q)
+	}
+	if q := h.quantile(1); q != 1000*time.Hour {
+		t.Fatalf("p100 = %v, want max", q)
+	}
+
+	var empty histogram
+	if stat := empty.stat(); stat != (LatencyStat{}) {
+		t.Fatalf("empty stat %+v", stat)
+	}
+}
+
+func TestRollingHistogramExpire(t *testing.T) {
+	rh := newRollingHistogram(6 * time.Second)
+	start := time.Unix(1000, 0)
+
+	rh.record(start, time.Millisecond)
+	rh.record(start.Add(3*time.Second), 2*time.Millisecond)
+
+	if h := rh.snapshot(start.Add(5 * time.Second)); h.count != 2 || h.max != 2*time.Millisecond {
+		t.Fatalf("count %d max %v", h.count, h.max)
+	}
+
+	//第一条过期
+	if h := rh.snapshot(start.Add(6 * time.Second)); h.count != 1 {
+		t.Fatalf("count %d, want 1", h.count)
+	}
+
+	//写入复用的时间片时清空旧数据
+	rh.record(start.Add(12*time.Second), 5*time.Millisecond)
+	if h := rh.snapshot(start.Add(12 * time.Second)); h.count != 1 || h.max != 5*time.Millisecond {
+		t.Fatalf("count %d max %v", h.count, h.max)
+	}
+}
+
+func TestProfilerLatency(t *testing.T) {
+	prof := RegProfiler("TestProfilerLatency")
+	defer UnRegProfiler("TestProfilerLatency")
+
+	prof.PushWait("b", 2*time.Millisecond).Pop()
+	prof.Push("a").Pop()
+
+	latencyList := GetLatency("TestProfilerLatency")
+	if len(latencyList) != 2 || latencyList[0].Tag != "a" || latencyList[1].Tag != "b" {
+		t.Fatalf("latency list %+v", latencyList)
+	}
+	if latencyList[0].Wait.Count != 0 || latencyList[1].Wait.Count != 1 {
+		t.Fatalf("wait count %d %d", latencyList[0].Wait.Count, latencyList[1].Wait.Count)
+	}
+	checkApprox(t, "wait", latencyList[1].Wait.Max, 2*time.Millisecond)
+
+	if GetLatency("NotExist") != nil {
+		t.Fatal("latency of unknown profiler should be nil")
+	}
+}
@@ -2,7 +2,10 @@ package profiler
 
 import (
 	"container/list"
+	"fmt"
 	"github.com/duanhf2012/origin/v2/log"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 )
@@ -14,14 +17,49 @@ var DefaultMaxOvertime = 5 * time.Second
 var DefaultOvertime = 10 * time.Millisecond
 var DefaultMaxRecordNum = 100 //最大记录条数
 var mapProfiler map[string]*Profiler
+var profilerLocker sync.RWMutex
 
 type ReportFunType func(name string, callNum int, costTime time.Duration, record *list.List)
 
 var reportFunc ReportFunType = DefaultReportFunction
 
+// LatencyReportFunType 耗时分布报告函数，latencyList按Tag排序
+type LatencyReportFunType func(name string, latencyList []TagLatency)
+
+var latencyReportFunc LatencyReportFunType = DefaultLatencyReportFunction
+
 type Element struct {
 	tagName  string
 	pushTime time.Time
+	wait     time.Duration //在队列中等待的时间
+	hasWait  bool
+	goid     uint64 //开启看门狗时记录处理事件的协程Id
+	stuck    bool   //已被看门狗报告
+}
+
+func newElement(tag string, goid uint64) *Element {
+	return &Element{tagName: tag, pushTime: time.Now(), goid: goid}
+}
+
+// currentGoroutineId 未传入协程Id时，开启看门狗才获取当前协程Id
+func currentGoroutineId() uint64 {
+	if watchdogOpen.Load() == false {
+		return 0
+	}
+
+	return GetGoroutineId()
+}
+
+// TagLatency 按Push的Tag统计的滑动窗口内耗时分布
+type TagLatency struct {
+	Tag    string
+	Handle LatencyStat //处理耗时
+	Wait   LatencyStat //在队列中等待的时间，只统计使用PushWait的调用
+}
+
+type tagHistogram struct {
+	handle *rollingHistogram
+	wait   *rollingHistogram
 }
 
 type RecordType int
@@ -54,6 +92,12 @@ type Profiler struct {
 	maxOverTime  time.Duration
 	overTime     time.Duration
 	maxRecordNum int
+
+	depthNameList []string              //队列名，按注册顺序输出
+	mapDepthFunc  map[string]func() int //队列深度获取函数
+
+	latencyWindow time.Duration
+	mapHistogram  map[string]*tagHistogram //Tag->耗时直方图
 }
 
 func init() {
@@ -61,15 +105,26 @@ func init() {
 }
 
 func RegProfiler(profilerName string) *Profiler {
+	profilerLocker.Lock()
+	defer profilerLocker.Unlock()
+
 	if _, ok := mapProfiler[profilerName]; ok == true {
 		return nil
 	}
 
-	pProfiler := &Profiler{stack: list.New(), record: list.New(), maxOverTime: DefaultMaxOvertime, overTime: DefaultOvertime}
+	pProfiler := &Profiler{stack: list.New(), record: list.New(), maxOverTime: DefaultMaxOvertime, overTime: DefaultOvertime, latencyWindow: DefaultLatencyWindow, mapHistogram: map[string]*tagHistogram{}}
 	mapProfiler[profilerName] = pProfiler
 	return pProfiler
 }
 
+// UnRegProfiler 注销性能分析器，服务释放后可以重新注册同名分析器
+func UnRegProfiler(profilerName string) {
+	profilerLocker.Lock()
+	defer profilerLocker.Unlock()
+
+	delete(mapProfiler, profilerName)
+}
+
 func (slf *Profiler) SetMaxOverTime(tm time.Duration) {
 	slf.maxOverTime = tm
 }
@@ -82,15 +137,152 @@ func (slf *Profiler) SetMaxRecordNum(num int) {
 	slf.maxRecordNum = num
 }
 
+// SetLatencyWindow 设置耗时统计的滑动窗口时长，已有的统计会被清空
+func (slf *Profiler) SetLatencyWindow(window time.Duration) {
+	slf.stackLocker.Lock()
+	defer slf.stackLocker.Unlock()
+
+	slf.latencyWindow = window
+	slf.mapHistogram = map[string]*tagHistogram{}
+}
+
+// RegQueueDepth 注册队列深度，报告时一并输出，fun可能在其他协程中调用
+func (slf *Profiler) RegQueueDepth(name string, fun func() int) {
+	slf.stackLocker.Lock()
+	defer slf.stackLocker.Unlock()
+
+	if slf.mapDepthFunc == nil {
+		slf.mapDepthFunc = map[string]func() int{}
+	}
+
+	if _, ok := slf.mapDepthFunc[name]; ok == false {
+		slf.depthNameList = append(slf.depthNameList, name)
+	}
+	slf.mapDepthFunc[name] = fun
+}
+
+// GetQueueDepth 获取所有注册的队列当前深度
+func (slf *Profiler) GetQueueDepth() map[string]int {
+	slf.stackLocker.RLock()
+	defer slf.stackLocker.RUnlock()
+
+	mapDepth := make(map[string]int, len(slf.mapDepthFunc))
+	for name, fun := range slf.mapDepthFunc {
+		mapDepth[name] = fun()
+	}
+
+	return mapDepth
+}
+
+// reportQueueDepth 输出队列深度，所有队列为空时不输出
+func (slf *Profiler) reportQueueDepth(name string) {
+	slf.stackLocker.RLock()
+	defer slf.stackLocker.RUnlock()
+
+	bEmpty := true
+	var strDepth strings.Builder
+	for _, depthName := range slf.depthNameList {
+		depth := slf.mapDepthFunc[depthName]()
+		if depth > 0 {
+			bEmpty = false
+		}
+		fmt.Fprintf(&strDepth, "%s:%d ", depthName, depth)
+	}
+
+	if bEmpty == true {
+		return
+	}
+
+	log.Info("Profiler report queue depth "+name, log.String("depth", strings.TrimSpace(strDepth.String())))
+}
+
 func (slf *Profiler) Push(tag string) *Analyzer {
 	slf.stackLocker.Lock()
 	defer slf.stackLocker.Unlock()
 
-	pElem := slf.stack.PushBack(&Element{tagName: tag, pushTime: time.Now()})
+	pElem := slf.stack.PushBack(newElement(tag, currentGoroutineId()))
 
 	return &Analyzer{elem: pElem, profiler: slf}
 }
 
+// PushWait 与Push相同，同时统计在队列中等待的时间
+func (slf *Profiler) PushWait(tag string, wait time.Duration) *Analyzer {
+	return slf.PushWaitGoroutine(tag, wait, currentGoroutineId())
+}
+
+// PushWaitGoroutine 与PushWait相同，goid为调用者在协程开始时通过GetGoroutineId获取的协程Id，供看门狗输出堆栈
+func (slf *Profiler) PushWaitGoroutine(tag string, wait time.Duration, goid uint64) *Analyzer {
+	slf.stackLocker.Lock()
+	defer slf.stackLocker.Unlock()
+
+	pElement := newElement(tag, goid)
+	pElement.wait = wait
+	pElement.hasWait = true
+	pElem := slf.stack.PushBack(pElement)
+
+	return &Analyzer{elem: pElem, profiler: slf}
+}
+
+// recordLatency 需要在锁中调用
+func (slf *Profiler) recordLatency(pElem *Element, now time.Time, costTime time.Duration) {
+	th, ok := slf.mapHistogram[pElem.tagName]
+	if ok == false {
+		th = &tagHistogram{handle: newRollingHistogram(slf.latencyWindow), wait: newRollingHistogram(slf.latencyWindow)}
+		slf.mapHistogram[pElem.tagName] = th
+	}
+
+	th.handle.record(now, costTime)
+	if pElem.hasWait == true {
+		th.wait.record(now, pElem.wait)
+	}
+}
+
+// GetLatency 获取滑动窗口内各Tag的耗时分布，按Tag排序
+func (slf *Profiler) GetLatency() []TagLatency {
+	slf.stackLocker.RLock()
+	defer slf.stackLocker.RUnlock()
+
+	now := time.Now()
+	latencyList := make([]TagLatency, 0, len(slf.mapHistogram))
+	for tag, th := range slf.mapHistogram {
+		tagLatency := TagLatency{Tag: tag, Handle: th.handle.snapshot(now).stat(), Wait: th.wait.snapshot(now).stat()}
+		if tagLatency.Handle.Count == 0 {
+			continue
+		}
+		latencyList = append(latencyList, tagLatency)
+	}
+
+	slices.SortFunc(latencyList, func(a, b TagLatency) int {
+		return strings.Compare(a.Tag, b.Tag)
+	})
+	return latencyList
+}
+
+// GetLatency 获取指定分析器的耗时分布，分析器不存在时返回nil
+func GetLatency(profilerName string) []TagLatency {
+	profilerLocker.RLock()
+	prof, ok := mapProfiler[profilerName]
+	profilerLocker.RUnlock()
+	if ok == false {
+		return nil
+	}
+
+	return prof.GetLatency()
+}
+
+// GetAllLatency 获取所有分析器的耗时分布
+func GetAllLatency() map[string][]TagLatency {
+	profilerLocker.RLock()
+	defer profilerLocker.RUnlock()
+
+	mapLatency := make(map[string][]TagLatency, len(mapProfiler))
+	for name, prof := range mapProfiler {
+		mapLatency[name] = prof.GetLatency()
+	}
+
+	return mapLatency
+}
+
 func (slf *Profiler) check(pElem *Element) (*Record, time.Duration) {
 	if pElem == nil {
 		return nil, 0
@@ -122,6 +314,7 @@ func (slf *Analyzer) Pop() {
 	pElem, subTm := slf.profiler.check(pElement)
 	slf.profiler.callNum += 1
 	slf.profiler.totalCostTime += subTm
+	slf.profiler.recordLatency(pElement, time.Now(), subTm)
 	if pElem != nil {
 		slf.profiler.pushRecordLog(pElem)
 	}
@@ -143,6 +336,24 @@ func SetReportFunction(reportFun ReportFunType) {
 	reportFunc = reportFun
 }
 
+// SetLatencyReportFunction 设置耗时分布报告函数，在Report时调用
+func SetLatencyReportFunction(reportFun LatencyReportFunType) {
+	latencyReportFunc = reportFun
+}
+
+// DefaultLatencyReportFunction 输出P99超过超时时间的Tag
+func DefaultLatencyReportFunction(name string, latencyList []TagLatency) {
+	for _, tagLatency := range latencyList {
+		if tagLatency.Handle.P99 < DefaultOvertime && tagLatency.Wait.P99 < DefaultOvertime {
+			continue
+		}
+
+		log.Info("Profiler report latency "+name, log.String("tag", tagLatency.Tag), log.Uint64("count", tagLatency.Handle.Count),
+			log.Duration("p50", tagLatency.Handle.P50), log.Duration("p90", tagLatency.Handle.P90), log.Duration("p99", tagLatency.Handle.P99), log.Duration("max", tagLatency.Handle.Max),
+			log.Duration("waitP50", tagLatency.Wait.P50), log.Duration("waitP99", tagLatency.Wait.P99), log.Duration("waitMax", tagLatency.Wait.Max))
+	}
+}
+
 func DefaultReportFunction(name string, callNum int, costTime time.Duration, record *list.List) {
 	if record.Len() <= 0 {
 		return
@@ -171,7 +382,12 @@ func DefaultReportFunction(name string, callNum int, costTime time.Duration, rec
 
 func Report() {
 	var record *list.List
+	profilerLocker.RLock()
+	defer profilerLocker.RUnlock()
+
 	for name, prof := range mapProfiler {
+		prof.reportQueueDepth(name)
+		latencyReportFunc(name, prof.GetLatency())
 		prof.stackLocker.RLock()
 
 		//取栈顶，是否存在异常MaxOverTime数据
@@ -196,6 +412,6 @@ func Report() {
 		totalCostTime := prof.totalCostTime
 		prof.stackLocker.RUnlock()
 
-		DefaultReportFunction(name, callNum, totalCostTime, record)
+		reportFunc(name, callNum, totalCostTime, record)
 	}
 }
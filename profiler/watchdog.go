@@ -0,0 +1,334 @@
+package profiler
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/log"
+)
+
+const (
+	defaultWatchdogCheckInterval = time.Second
+	defaultWatchdogDumpDir       = "./dump"
+	defaultWatchdogDumpInterval  = time.Minute
+)
+
+// WatchdogCfg 看门狗配置，只监控开启了性能分析的服务
+type WatchdogCfg struct {
+	NodeId         string        //写入转储文件名
+	StuckTime      time.Duration //处理一个事件超过该时间视为卡住，默认DefaultMaxOvertime
+	CheckInterval  time.Duration //检查间隔，默认1秒
+	DumpDir        string        //转储目录，默认./dump
+	DumpInterval   time.Duration //两次转储的最小间隔，默认1分钟
+	CPUProfileTime time.Duration //卡住时采集CPU profile的时长，为0时不采集
+	HeapRSS        uint64        //进程常驻内存超过该字节数时转储heap profile，为0时不检查
+}
+
+type watchdog struct {
+	cfg          WatchdogCfg
+	closeSig     chan struct{}
+	wg           sync.WaitGroup
+	lastDumpTime time.Time
+	lastHeapTime time.Time
+}
+
+// stuckElement 卡住的事件
+type stuckElement struct {
+	profilerName string
+	tagName      string
+	goid         uint64
+	costTime     time.Duration
+}
+
+var watchdogOpen atomic.Bool
+var watchdogLocker sync.Mutex
+var curWatchdog *watchdog
+
+// StartWatchdog 启动看门狗，服务卡在某个事件中时转储该协程的堆栈与CPU profile
+func StartWatchdog(cfg WatchdogCfg) error {
+	if cfg.StuckTime <= 0 {
+		cfg.StuckTime = DefaultMaxOvertime
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = defaultWatchdogCheckInterval
+	}
+	if cfg.DumpDir == "" {
+		cfg.DumpDir = defaultWatchdogDumpDir
+	}
+	if cfg.DumpInterval <= 0 {
+		cfg.DumpInterval = defaultWatchdogDumpInterval
+	}
+
+	if err := os.MkdirAll(cfg.DumpDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	watchdogLocker.Lock()
+	defer watchdogLocker.Unlock()
+	if curWatchdog != nil {
+		return fmt.Errorf("watchdog is already started")
+	}
+
+	curWatchdog = &watchdog{cfg: cfg, closeSig: make(chan struct{})}
+	watchdogOpen.Store(true)
+	curWatchdog.wg.Add(1)
+	go curWatchdog.run()
+
+	log.Info("watchdog is started", log.String("stuckTime", cfg.StuckTime.String()), log.String("dumpDir", cfg.DumpDir))
+	return nil
+}
+
+// StopWatchdog 停止看门狗
+func StopWatchdog() {
+	watchdogLocker.Lock()
+	defer watchdogLocker.Unlock()
+	if curWatchdog == nil {
+		return
+	}
+
+	watchdogOpen.Store(false)
+	close(curWatchdog.closeSig)
+	curWatchdog.wg.Wait()
+	curWatchdog = nil
+}
+
+// GetGoroutineId 从当前协程堆栈的第一行"goroutine N ["解析协程Id，开销较大，在协程开始时获取一次
+func GetGoroutineId() uint64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	return parseGoroutineId(buf[:n])
+}
+
+func parseGoroutineId(stack []byte) uint64 {
+	stack = bytes.TrimPrefix(stack, []byte("goroutine "))
+	idx := bytes.IndexByte(stack, ' ')
+	if idx < 0 {
+		return 0
+	}
+
+	goid, err := strconv.ParseUint(string(stack[:idx]), 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return goid
+}
+
+// findGoroutineStack 从所有协程的堆栈中找出指定协程
+func findGoroutineStack(allStack []byte, goid uint64) []byte {
+	prefix := []byte("goroutine " + strconv.FormatUint(goid, 10) + " [")
+	for _, stack := range bytes.Split(allStack, []byte("\n\n")) {
+		if bytes.HasPrefix(stack, prefix) {
+			return stack
+		}
+	}
+
+	return nil
+}
+
+// getDumpFileName 文件名中只保留字母数字与.-，其他字符替换为_
+func getDumpFileName(now time.Time, labelList ...string) string {
+	var fileName strings.Builder
+	for _, label := range labelList {
+		for _, c := range label {
+			if (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '.' || c == '-' {
+				fileName.WriteRune(c)
+			} else {
+				fileName.WriteByte('_')
+			}
+		}
+		fileName.WriteByte('_')
+	}
+
+	fileName.WriteString(now.Format("20060102-150405"))
+	return fileName.String()
+}
+
+// getRSS 获取进程常驻内存，非linux系统使用运行时从系统申请的内存
+func getRSS() uint64 {
+	byteData, err := os.ReadFile("/proc/self/statm")
+	if err == nil {
+		fields := strings.Fields(string(byteData))
+		if len(fields) > 1 {
+			pages, err := strconv.ParseUint(fields[1], 10, 64)
+			if err == nil {
+				return pages * uint64(os.Getpagesize())
+			}
+		}
+	}
+
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	return memStats.Sys
+}
+
+func (wd *watchdog) run() {
+	defer wd.wg.Done()
+
+	ticker := time.NewTicker(wd.cfg.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-wd.closeSig:
+			return
+		case <-ticker.C:
+			wd.check()
+		}
+	}
+}
+
+func (wd *watchdog) check() {
+	now := time.Now()
+	for _, stuck := range collectStuck(now, wd.cfg.StuckTime) {
+		log.Warn("service is stuck in event", log.String("service", stuck.profilerName), log.String("tag", stuck.tagName), log.Int64("costTime", stuck.costTime.Milliseconds()))
+		if now.Sub(wd.lastDumpTime) < wd.cfg.DumpInterval {
+			continue
+		}
+
+		wd.lastDumpTime = now
+		wd.dumpStuck(now, stuck)
+	}
+
+	if wd.cfg.HeapRSS > 0 && now.Sub(wd.lastHeapTime) >= wd.cfg.DumpInterval {
+		if rss := getRSS(); rss >= wd.cfg.HeapRSS {
+			wd.lastHeapTime = now
+			wd.dumpHeap(now, rss)
+		}
+	}
+}
+
+// collectStuck 找出超过stuckTime未返回的事件，每个事件只返回一次
+func collectStuck(now time.Time, stuckTime time.Duration) []stuckElement {
+	profilerLocker.RLock()
+	defer profilerLocker.RUnlock()
+
+	var stuckList []stuckElement
+	for name, prof := range mapProfiler {
+		prof.stackLocker.Lock()
+		for pElem := prof.stack.Front(); pElem != nil; pElem = pElem.Next() {
+			pElement := pElem.Value.(*Element)
+			costTime := now.Sub(pElement.pushTime)
+			if pElement.stuck == true || costTime < stuckTime {
+				continue
+			}
+
+			pElement.stuck = true
+			stuckList = append(stuckList, stuckElement{profilerName: name, tagName: pElement.tagName, goid: pElement.goid, costTime: costTime})
+		}
+		prof.stackLocker.Unlock()
+	}
+
+	return stuckList
+}
+
+func (wd *watchdog) dumpStuck(now time.Time, stuck stuckElement) {
+	fileName := filepath.Join(wd.cfg.DumpDir, getDumpFileName(now, wd.cfg.NodeId, stuck.profilerName, stuck.tagName))
+
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+
+	stack := findGoroutineStack(buf, stuck.goid)
+	if stack == nil {
+		//协程已返回或未记录协程Id时保留所有协程的堆栈
+		stack = buf
+	}
+
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "node: %s\nservice: %s\ntag: %s\ncost: %s\ntime: %s\n\n", wd.cfg.NodeId, stuck.profilerName, stuck.tagName, stuck.costTime, now.Format(time.RFC3339))
+	content.Write(stack)
+	content.WriteByte('\n')
+	//先写临时文件再改名，避免读取到不完整的文件
+	if err := os.WriteFile(fileName+".stack.tmp", content.Bytes(), 0644); err != nil {
+		log.Error("write stuck stack fail", log.String("fileName", fileName), log.ErrorField("err", err))
+		return
+	}
+	if err := os.Rename(fileName+".stack.tmp", fileName+".stack"); err != nil {
+		log.Error("rename stuck stack fail", log.String("fileName", fileName), log.ErrorField("err", err))
+		return
+	}
+	log.Warn("dump stuck service stack", log.String("fileName", fileName+".stack"))
+
+	if wd.cfg.CPUProfileTime > 0 {
+		wd.dumpCPUProfile(fileName + ".cpu.pprof")
+	}
+}
+
+// dumpCPUProfile 采集期间阻塞检查，避免同时采集多个CPU profile
+func (wd *watchdog) dumpCPUProfile(fileName string) {
+	f, err := os.Create(fileName + ".tmp")
+	if err != nil {
+		log.Error("create cpu profile fail", log.String("fileName", fileName), log.ErrorField("err", err))
+		return
+	}
+
+	if err = pprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		os.Remove(fileName + ".tmp")
+		log.Error("start cpu profile fail", log.String("fileName", fileName), log.ErrorField("err", err))
+		return
+	}
+
+	select {
+	case <-wd.closeSig:
+	case <-time.After(wd.cfg.CPUProfileTime):
+	}
+	pprof.StopCPUProfile()
+	if err = commitDumpFile(f, fileName); err != nil {
+		log.Error("write cpu profile fail", log.String("fileName", fileName), log.ErrorField("err", err))
+		return
+	}
+	log.Warn("dump stuck service cpu profile", log.String("fileName", fileName))
+}
+
+func (wd *watchdog) dumpHeap(now time.Time, rss uint64) {
+	fileName := filepath.Join(wd.cfg.DumpDir, getDumpFileName(now, wd.cfg.NodeId, "heap", strconv.FormatUint(rss, 10))+".heap.pprof")
+	f, err := os.Create(fileName + ".tmp")
+	if err != nil {
+		log.Error("create heap profile fail", log.String("fileName", fileName), log.ErrorField("err", err))
+		return
+	}
+
+	if err = pprof.Lookup("heap").WriteTo(f, 0); err != nil {
+		f.Close()
+		os.Remove(fileName + ".tmp")
+		log.Error("write heap profile fail", log.String("fileName", fileName), log.ErrorField("err", err))
+		return
+	}
+
+	if err = commitDumpFile(f, fileName); err != nil {
+		log.Error("write heap profile fail", log.String("fileName", fileName), log.ErrorField("err", err))
+		return
+	}
+	log.Warn("rss is over threshold, dump heap profile", log.Uint64("rss", rss), log.Uint64("threshold", wd.cfg.HeapRSS), log.String("fileName", fileName))
+}
+
+// commitDumpFile 关闭临时文件后改名为fileName，避免读取到不完整的文件
+func commitDumpFile(f *os.File, fileName string) error {
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := os.Rename(f.Name(), fileName); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package profiler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGoroutineId(t *testing.T) {
+	if goid := parseGoroutineId([]byte("goroutine 123 [running]:\nmain.main()")); goid != 123 {
+		t.Fatalf("goid = %d, want 123", goid)
+	}
+	if goid := parseGoroutineId([]byte("bad")); goid != 0 {
+		t.Fatalf("goid = %d, want 0", goid)
+	}
+	if GetGoroutineId() == 0 {
+		t.Fatal("current goroutine id is 0")
+	}
+}
+
+func TestFindGoroutineStack(t *testing.T) {
+	allStack := []byte("goroutine 1 [running]:\nmain.a()\n\ngoroutine 12 [chan receive]:\nmain.b()\n\ngoroutine 123 [select]:\nmain.c()")
+
+	if stack := string(findGoroutineStack(allStack, 12)); stack != "goroutine 12 [chan receive]:\nmain.b()" {
+		t.Fatalf("stack = %q", stack)
+	}
+	if stack := findGoroutineStack(allStack, 2); stack != nil {
+		t.Fatalf("stack = %q, want nil", stack)
+	}
+}
+
+func TestGetDumpFileName(t *testing.T) {
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	fileName := getDumpFileName(now, "node-1", "GameService", "[RpcReq]GameService.RPC_Login.1")
+	if fileName != "node-1_GameService__RpcReq_GameService.RPC_Login.1_20240506-070809" {
+		t.Fatalf("file name = %s", fileName)
+	}
+}
+
+func stuckHandler(prof *Profiler, release chan struct{}) {
+	analyzer := prof.Push("[SEvent]stuck")
+	<-release
+	analyzer.Pop()
+}
+
+func TestWatchdogDumpStuck(t *testing.T) {
+	dumpDir := t.TempDir()
+	err := StartWatchdog(WatchdogCfg{NodeId: "node1", StuckTime: 50 * time.Millisecond, CheckInterval: 10 * time.Millisecond, DumpDir: dumpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer StopWatchdog()
+
+	prof := RegProfiler("TestWatchdog")
+	defer UnRegProfiler("TestWatchdog")
+
+	release := make(chan struct{})
+	go stuckHandler(prof, release)
+	defer close(release)
+
+	var fileList []string
+	for i := 0; i < 200 && len(fileList) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		fileList, _ = filepath.Glob(filepath.Join(dumpDir, "*.stack"))
+	}
+	if len(fileList) != 1 {
+		t.Fatalf("stack file list %v", fileList)
+	}
+
+	if strings.HasPrefix(filepath.Base(fileList[0]), "node1_TestWatchdog__SEvent_stuck_") == false {
+		t.Fatalf("stack file name %s", fileList[0])
+	}
+
+	byteData, err := os.ReadFile(fileList[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(byteData)
+	if strings.Contains(content, "tag: [SEvent]stuck") == false || strings.Contains(content, "stuckHandler") == false {
+		t.Fatalf("stack file content:\n%s", content)
+	}
+	if strings.Contains(content, "testing.tRunner") == true {
+		t.Fatalf("stack file should only contain the stuck goroutine:\n%s", content)
+	}
+}
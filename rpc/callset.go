@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/util/timer"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -18,6 +19,7 @@ type CallSet struct {
 	maxCheckCallRpcCount int
 
 	callTimerHeap CallTimerHeap
+	clock         *callSetClock //虚拟时钟下注册的监听者
 }
 
 func (cs *CallSet) Init() {
@@ -28,13 +30,59 @@ func (cs *CallSet) Init() {
 	cs.maxCheckCallRpcCount = DefaultMaxCheckCallRpcCount
 	cs.callRpcTimeout = DefaultRpcTimeout
 
-	go cs.checkRpcCallTimeout()
+	if timer.IsVirtualClock() == true {
+		//虚拟时钟下由Advance检查超时
+		if cs.clock != nil {
+			timer.UnRegVirtualClockListener(cs.clock)
+		}
+		cs.clock = &callSetClock{cs: cs}
+		timer.RegVirtualClockListener(cs.clock)
+	} else {
+		go cs.checkRpcCallTimeout()
+	}
 	cs.pendingLock.Unlock()
 }
 
-func (cs *CallSet) makeCallFail(call *Call) {
+// UnRegVirtualClock 取消注册虚拟时钟的监听者，结点停止时调用
+func (cs *CallSet) UnRegVirtualClock() {
+	cs.pendingLock.Lock()
+	clock := cs.clock
+	cs.clock = nil
+	cs.pendingLock.Unlock()
+
+	if clock != nil {
+		timer.UnRegVirtualClockListener(clock)
+	}
+}
+
+// callSetClock 虚拟时钟下检查Rpc调用超时
+type callSetClock struct {
+	cs *CallSet
+}
+
+func (cc *callSetClock) NextFireTime() (time.Time, bool) {
+	cc.cs.pendingLock.Lock()
+	defer cc.cs.pendingLock.Unlock()
+
+	return cc.cs.callTimerHeap.NextFireTime()
+}
+
+// OnClock 超时的异步调用在回调执行完成后才结束本次推进
+func (cc *callSetClock) OnClock(now time.Time) {
+	for cc.cs.checkTimeout(true) {
+	}
+}
+
+// makeCallFail bVirtualTask为true时异步回调作为虚拟时钟的任务，HandlerRpcResponseCB执行完成后结束
+func (cs *CallSet) makeCallFail(call *Call, bVirtualTask bool) {
 	if call.callback != nil && call.callback.IsValid() {
-		call.rpcHandler.PushRpcResponse(call)
+		if bVirtualTask == true {
+			call.virtualTask = true
+			timer.AddVirtualTask()
+		}
+		if err := call.rpcHandler.PushRpcResponse(call); err != nil && bVirtualTask == true {
+			timer.DoneVirtualTask()
+		}
 	} else {
 		call.done <- call
 	}
@@ -44,30 +92,35 @@ func (cs *CallSet) checkRpcCallTimeout() {
 	for {
 		time.Sleep(DefaultCheckRpcCallTimeoutInterval)
 		for i := 0; i < cs.maxCheckCallRpcCount; i++ {
-			cs.pendingLock.Lock()
-
-			callSeq := cs.callTimerHeap.PopTimeout()
-			if callSeq == 0 {
-				cs.pendingLock.Unlock()
+			if cs.checkTimeout(false) == false {
 				break
 			}
+		}
+	}
+}
 
-			pCall := cs.pending[callSeq]
-			if pCall == nil {
-				cs.pendingLock.Unlock()
-				log.Error("call seq is not find", log.Uint64("seq", callSeq))
-				continue
-			}
+// checkTimeout 处理一个超时的调用，没有超时的调用时返回false
+func (cs *CallSet) checkTimeout(bVirtualTask bool) bool {
+	cs.pendingLock.Lock()
+	defer cs.pendingLock.Unlock()
 
-			delete(cs.pending, callSeq)
-			strTimeout := strconv.FormatInt(int64(pCall.TimeOut.Seconds()), 10)
-			pCall.Err = errors.New("RPC call takes more than " + strTimeout + " seconds,method is " + pCall.ServiceMethod)
-			log.Error("call timeout", log.String("error", pCall.Err.Error()))
-			cs.makeCallFail(pCall)
-			cs.pendingLock.Unlock()
-			continue
-		}
+	callSeq := cs.callTimerHeap.PopTimeout()
+	if callSeq == 0 {
+		return false
+	}
+
+	pCall := cs.pending[callSeq]
+	if pCall == nil {
+		log.Error("call seq is not find", log.Uint64("seq", callSeq))
+		return true
 	}
+
+	delete(cs.pending, callSeq)
+	strTimeout := strconv.FormatInt(int64(pCall.TimeOut.Seconds()), 10)
+	pCall.Err = errors.New("RPC call takes more than " + strTimeout + " seconds,method is " + pCall.ServiceMethod)
+	log.Error("call timeout", log.String("error", pCall.Err.Error()))
+	cs.makeCallFail(pCall, bVirtualTask)
+	return true
 }
 
 func (cs *CallSet) AddPending(call *Call) {
@@ -106,6 +159,14 @@ func (cs *CallSet) removePending(seq uint64) *Call {
 	return v
 }
 
+// GetPendingNum 获取未完成的Rpc调用数
+func (cs *CallSet) GetPendingNum() int {
+	cs.pendingLock.RLock()
+	defer cs.pendingLock.RUnlock()
+
+	return len(cs.pending)
+}
+
 func (cs *CallSet) FindPending(seq uint64) (pCall *Call) {
 	if seq == 0 {
 		return nil
@@ -133,7 +194,7 @@ func (cs *CallSet) cleanPending() {
 
 		delete(cs.pending, callSeq)
 		pCall.Err = errors.New("node is disconnect ")
-		cs.makeCallFail(pCall)
+		cs.makeCallFail(pCall, false)
 	}
 
 	cs.pendingLock.Unlock()
@@ -0,0 +1,97 @@
+package rpc
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/util/timer"
+)
+
+// testResponseHandler 在独立协程中处理Rpc返回，模拟服务协程
+type testResponseHandler struct {
+	RpcHandler
+	chanCall chan *Call
+}
+
+func (h *testResponseHandler) PushRpcResponse(call *Call) error {
+	select {
+	case h.chanCall <- call:
+		return nil
+	default:
+		return errors.New("response channel is full")
+	}
+}
+
+func (h *testResponseHandler) run(chanClose chan struct{}) {
+	for {
+		select {
+		case call := <-h.chanCall:
+			h.HandlerRpcResponseCB(call)
+		case <-chanClose:
+			return
+		}
+	}
+}
+
+func TestCallSetVirtualClockTimeout(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	timer.EnableVirtualClock(start)
+	timer.StartTimer(time.Millisecond, 100)
+	defer timer.DisableVirtualClock()
+
+	var cs CallSet
+	cs.Init()
+	defer cs.UnRegVirtualClock()
+
+	h := &testResponseHandler{chanCall: make(chan *Call, 10)}
+	chanClose := make(chan struct{})
+	defer close(chanClose)
+	go h.run(chanClose)
+
+	var callErr error
+	var callTime time.Time
+	addCall := func(timeout time.Duration) uint64 {
+		callback := reflect.ValueOf(func(reply *struct{}, err error) {
+			//回调较慢时Advance也需要等待回调执行完成
+			time.Sleep(20 * time.Millisecond)
+			callErr = err
+			callTime = timer.Now()
+		})
+
+		call := MakeCall()
+		call.Seq = cs.generateSeq()
+		call.ServiceMethod = "TestService.RPC_Test"
+		call.TimeOut = timeout
+		call.Reply = &struct{}{}
+		call.callback = &callback
+		call.rpcHandler = h
+		cs.AddPending(call)
+		return call.Seq
+	}
+
+	addCall(5 * time.Second)
+	timer.Advance(4 * time.Second)
+	if callErr != nil || cs.GetPendingNum() != 1 {
+		t.Fatalf("call should not time out, err %v pending %d", callErr, cs.GetPendingNum())
+	}
+
+	timer.Advance(time.Second)
+	if callErr == nil || callTime.Equal(start.Add(5*time.Second)) == false {
+		t.Fatalf("call should time out at 5s, err %v time %v", callErr, callTime)
+	}
+	if cs.GetPendingNum() != 0 {
+		t.Fatalf("unexpected pending num %d", cs.GetPendingNum())
+	}
+
+	//取消注册后不再由虚拟时钟检查超时
+	cs.UnRegVirtualClock()
+	callErr = nil
+	seq := addCall(time.Second)
+	timer.Advance(time.Minute)
+	if callErr != nil || cs.GetPendingNum() != 1 {
+		t.Fatalf("call should not time out after unregister, err %v pending %d", callErr, cs.GetPendingNum())
+	}
+	cs.RemovePending(seq)
+}
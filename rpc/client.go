@@ -158,6 +158,7 @@ func (client *Client) rawGo(nodeId string, w IWriter, timeout time.Duration, rpc
 	call.TimeOut = timeout
 
 	request := MakeRpcRequest(processor, call.Seq, rpcMethodId, serviceMethod, noReply, rawArgs)
+	request.setTraceMeta(rpcHandler)
 	bytes, err := processor.Marshal(request.RpcRequestData)
 	ReleaseRpcRequest(request)
 
@@ -220,6 +221,7 @@ func (client *Client) asyncCall(nodeId string, w IWriter, timeout time.Duration,
 
 	seq := client.generateSeq()
 	request := MakeRpcRequest(processor, seq, 0, serviceMethod, false, InParam)
+	request.setTraceMeta(rpcHandler)
 	bytes, err := processor.Marshal(request.RpcRequestData)
 	ReleaseRpcRequest(request)
 	if err != nil {
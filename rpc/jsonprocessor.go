@@ -19,6 +19,8 @@ type JsonRpcRequestData struct {
 	NoReply       bool           //是否需要返回
 	//packbody
 	InParam      []byte
+	TraceId      string //调用链Id，沿用调用方正在处理的请求
+	CallerNodeId string //调用方结点Id
 }
 
 type JsonRpcResponseData struct {
@@ -53,6 +55,8 @@ func (jsonProcessor *JsonProcessor) MakeRpcRequest(seq uint64,rpcMethodId uint32
 	jsonRpcRequestData.ServiceMethod = serviceMethod
 	jsonRpcRequestData.NoReply = noReply
 	jsonRpcRequestData.InParam = inParam
+	jsonRpcRequestData.TraceId = ""
+	jsonRpcRequestData.CallerNodeId = ""
 	return jsonRpcRequestData
 }
 
@@ -102,6 +106,20 @@ func (jsonRpcRequestData *JsonRpcRequestData) GetInParam() []byte{
 	return jsonRpcRequestData.InParam
 }
 
+func (jsonRpcRequestData *JsonRpcRequestData) GetTraceId() string {
+	return jsonRpcRequestData.TraceId
+}
+
+func (jsonRpcRequestData *JsonRpcRequestData) GetCallerNodeId() string {
+	return jsonRpcRequestData.CallerNodeId
+}
+
+// SetTraceMeta 发送请求时填写调用链信息
+func (jsonRpcRequestData *JsonRpcRequestData) SetTraceMeta(traceId string, callerNodeId string) {
+	jsonRpcRequestData.TraceId = traceId
+	jsonRpcRequestData.CallerNodeId = callerNodeId
+}
+
 func (jsonRpcResponseData *JsonRpcResponseData)	GetSeq() uint64 {
 	return jsonRpcResponseData.Seq
 }
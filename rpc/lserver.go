@@ -33,7 +33,7 @@ func (server *BaseServer) myselfRpcHandlerGo(client *Client, handlerName string,
 	return rpcHandler.CallMethod(client, serviceMethod, args, callBack, reply)
 }
 
-func (server *BaseServer) selfNodeRpcHandlerGo(timeout time.Duration, processor IRpcProcessor, client *Client, noReply bool, handlerName string, rpcMethodId uint32, serviceMethod string, args interface{}, reply interface{}, rawArgs []byte) *Call {
+func (server *BaseServer) selfNodeRpcHandlerGo(timeout time.Duration, processor IRpcProcessor, client *Client, callerRpcHandler IRpcHandler, noReply bool, handlerName string, rpcMethodId uint32, serviceMethod string, args interface{}, reply interface{}, rawArgs []byte) *Call {
 	pCall := MakeCall()
 	pCall.Seq = client.generateSeq()
 	pCall.TimeOut = timeout
@@ -68,6 +68,7 @@ func (server *BaseServer) selfNodeRpcHandlerGo(timeout time.Duration, processor
 	}
 
 	req := MakeRpcRequest(processor, 0, rpcMethodId, serviceMethod, noReply, nil)
+	req.setTraceMeta(callerRpcHandler)
 	req.inParam = iParam
 	req.localReply = reply
 	if rawArgs != nil {
@@ -120,6 +121,9 @@ func (server *BaseServer) selfNodeRpcHandlerGo(timeout time.Duration, processor
 	err := rpcHandler.PushRpcRequest(req)
 	if err != nil {
 		log.Error(err.Error())
+		if noReply == false {
+			client.RemovePending(pCall.Seq)
+		}
 		pCall.DoError(err)
 		ReleaseRpcRequest(req)
 	}
@@ -144,6 +148,7 @@ func (server *BaseServer) selfNodeRpcHandlerAsyncGo(timeout time.Duration, clien
 	}
 
 	req := MakeRpcRequest(processor, 0, 0, serviceMethod, noReply, nil)
+	req.setTraceMeta(callerRpcHandler)
 	req.inParam = iParam
 	req.localReply = reply
 
@@ -276,15 +281,14 @@ func (server *BaseServer) processRpcRequest(data []byte, connTag string, wrRespo
 		return nil
 	}
 
+	//服务过载等投递失败时立即返回错误，调用方不需要等待超时
 	err = rpcHandler.PushRpcRequest(req)
 	if err != nil {
-		rpcError := RpcError(err.Error())
-
-		if req.RpcRequestData.IsNoReply() {
-			wrResponse(processor, connTag, req.RpcRequestData.GetServiceMethod(), req.RpcRequestData.GetSeq(), nil, rpcError)
+		if req.requestHandle != nil {
+			req.requestHandle(nil, RpcError(err.Error()))
+		} else {
+			ReleaseRpcRequest(req)
 		}
-
-		ReleaseRpcRequest(req)
 	}
 
 	return nil
@@ -0,0 +1,43 @@
+package rpc
+
+import (
+	"errors"
+	"testing"
+)
+
+// overloadedHandler 模拟过载的服务，投递请求失败
+type overloadedHandler struct {
+	testRequestHandler
+}
+
+func (h *overloadedHandler) PushRpcRequest(_ *RpcRequest) error {
+	return errors.New("service is overloaded")
+}
+
+func TestProcessRpcRequestPushFail(t *testing.T) {
+	var server BaseServer
+	server.initBaseServer(0, &testHandleFinder{handler: &overloadedHandler{}})
+
+	for _, processor := range []IRpcProcessor{&PBProcessor{}, &JsonProcessor{}} {
+		request := MakeRpcRequest(processor, 7, 1, "TestService.RPC_Test", false, []byte{1})
+		data, err := processor.Marshal(request.RpcRequestData)
+		ReleaseRpcRequest(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		//投递失败时立即回复远程调用方
+		var replySeq uint64
+		var replyErr RpcError
+		wrResponse := func(_ IRpcProcessor, _ string, _ string, seq uint64, _ interface{}, rpcError RpcError) {
+			replySeq = seq
+			replyErr = rpcError
+		}
+		if err = server.processRpcRequest(append([]byte{uint8(processor.GetProcessorType())}, data...), "", wrResponse); err != nil {
+			t.Fatal(err)
+		}
+		if replySeq != 7 || replyErr != "service is overloaded" {
+			t.Fatalf("reply seq %d err %q", replySeq, replyErr)
+		}
+	}
+}
@@ -20,23 +20,96 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// 服务健康状态，只同步非Healthy的服务
+type ServiceState struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ServiceName string `protobuf:"bytes,1,opt,name=ServiceName,proto3" json:"ServiceName,omitempty"`
+	Health      int32  `protobuf:"varint,2,opt,name=Health,proto3" json:"Health,omitempty"`
+	Reason      string `protobuf:"bytes,3,opt,name=Reason,proto3" json:"Reason,omitempty"`
+	Overload    bool   `protobuf:"varint,4,opt,name=Overload,proto3" json:"Overload,omitempty"`
+}
+
+func (x *ServiceState) Reset() {
+	*x = ServiceState{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpcproto_origindiscover_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ServiceState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServiceState) ProtoMessage() {}
+
+func (x *ServiceState) ProtoReflect() protoreflect.Message {
+	mi := &file_rpcproto_origindiscover_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServiceState.ProtoReflect.Descriptor instead.
+func (*ServiceState) Descriptor() ([]byte, []int) {
+	return file_rpcproto_origindiscover_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ServiceState) GetServiceName() string {
+	if x != nil {
+		return x.ServiceName
+	}
+	return ""
+}
+
+func (x *ServiceState) GetHealth() int32 {
+	if x != nil {
+		return x.Health
+	}
+	return 0
+}
+
+func (x *ServiceState) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *ServiceState) GetOverload() bool {
+	if x != nil {
+		return x.Overload
+	}
+	return false
+}
+
 type NodeInfo struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	NodeId            string   `protobuf:"bytes,1,opt,name=NodeId,proto3" json:"NodeId,omitempty"`
-	ListenAddr        string   `protobuf:"bytes,2,opt,name=ListenAddr,proto3" json:"ListenAddr,omitempty"`
-	MaxRpcParamLen    uint32   `protobuf:"varint,3,opt,name=MaxRpcParamLen,proto3" json:"MaxRpcParamLen,omitempty"`
-	Private           bool     `protobuf:"varint,4,opt,name=Private,proto3" json:"Private,omitempty"`
-	Retire            bool     `protobuf:"varint,5,opt,name=Retire,proto3" json:"Retire,omitempty"`
-	PublicServiceList []string `protobuf:"bytes,6,rep,name=PublicServiceList,proto3" json:"PublicServiceList,omitempty"`
+	NodeId            string          `protobuf:"bytes,1,opt,name=NodeId,proto3" json:"NodeId,omitempty"`
+	ListenAddr        string          `protobuf:"bytes,2,opt,name=ListenAddr,proto3" json:"ListenAddr,omitempty"`
+	MaxRpcParamLen    uint32          `protobuf:"varint,3,opt,name=MaxRpcParamLen,proto3" json:"MaxRpcParamLen,omitempty"`
+	Private           bool            `protobuf:"varint,4,opt,name=Private,proto3" json:"Private,omitempty"`
+	Retire            bool            `protobuf:"varint,5,opt,name=Retire,proto3" json:"Retire,omitempty"`
+	PublicServiceList []string        `protobuf:"bytes,6,rep,name=PublicServiceList,proto3" json:"PublicServiceList,omitempty"`
+	ServiceStateList  []*ServiceState `protobuf:"bytes,7,rep,name=ServiceStateList,proto3" json:"ServiceStateList,omitempty"`
 }
 
 func (x *NodeInfo) Reset() {
 	*x = NodeInfo{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_rpcproto_origindiscover_proto_msgTypes[0]
+		mi := &file_rpcproto_origindiscover_proto_msgTypes[1]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -49,7 +122,7 @@ func (x *NodeInfo) String() string {
 func (*NodeInfo) ProtoMessage() {}
 
 func (x *NodeInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_rpcproto_origindiscover_proto_msgTypes[0]
+	mi := &file_rpcproto_origindiscover_proto_msgTypes[1]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -62,7 +135,7 @@ func (x *NodeInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use NodeInfo.ProtoReflect.Descriptor instead.
 func (*NodeInfo) Descriptor() ([]byte, []int) {
-	return file_rpcproto_origindiscover_proto_rawDescGZIP(), []int{0}
+	return file_rpcproto_origindiscover_proto_rawDescGZIP(), []int{1}
 }
 
 func (x *NodeInfo) GetNodeId() string {
@@ -107,6 +180,13 @@ func (x *NodeInfo) GetPublicServiceList() []string {
 	return nil
 }
 
+func (x *NodeInfo) GetServiceStateList() []*ServiceState {
+	if x != nil {
+		return x.ServiceStateList
+	}
+	return nil
+}
+
 // Client->Master
 type RegServiceDiscoverReq struct {
 	state         protoimpl.MessageState
@@ -119,7 +199,7 @@ type RegServiceDiscoverReq struct {
 func (x *RegServiceDiscoverReq) Reset() {
 	*x = RegServiceDiscoverReq{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_rpcproto_origindiscover_proto_msgTypes[1]
+		mi := &file_rpcproto_origindiscover_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -132,7 +212,7 @@ func (x *RegServiceDiscoverReq) String() string {
 func (*RegServiceDiscoverReq) ProtoMessage() {}
 
 func (x *RegServiceDiscoverReq) ProtoReflect() protoreflect.Message {
-	mi := &file_rpcproto_origindiscover_proto_msgTypes[1]
+	mi := &file_rpcproto_origindiscover_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -145,7 +225,7 @@ func (x *RegServiceDiscoverReq) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RegServiceDiscoverReq.ProtoReflect.Descriptor instead.
 func (*RegServiceDiscoverReq) Descriptor() ([]byte, []int) {
-	return file_rpcproto_origindiscover_proto_rawDescGZIP(), []int{1}
+	return file_rpcproto_origindiscover_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *RegServiceDiscoverReq) GetNodeInfo() *NodeInfo {
@@ -170,7 +250,7 @@ type SubscribeDiscoverNotify struct {
 func (x *SubscribeDiscoverNotify) Reset() {
 	*x = SubscribeDiscoverNotify{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_rpcproto_origindiscover_proto_msgTypes[2]
+		mi := &file_rpcproto_origindiscover_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -183,7 +263,7 @@ func (x *SubscribeDiscoverNotify) String() string {
 func (*SubscribeDiscoverNotify) ProtoMessage() {}
 
 func (x *SubscribeDiscoverNotify) ProtoReflect() protoreflect.Message {
-	mi := &file_rpcproto_origindiscover_proto_msgTypes[2]
+	mi := &file_rpcproto_origindiscover_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -196,7 +276,7 @@ func (x *SubscribeDiscoverNotify) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SubscribeDiscoverNotify.ProtoReflect.Descriptor instead.
 func (*SubscribeDiscoverNotify) Descriptor() ([]byte, []int) {
-	return file_rpcproto_origindiscover_proto_rawDescGZIP(), []int{2}
+	return file_rpcproto_origindiscover_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *SubscribeDiscoverNotify) GetMasterNodeId() string {
@@ -239,7 +319,7 @@ type NodeRetireReq struct {
 func (x *NodeRetireReq) Reset() {
 	*x = NodeRetireReq{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_rpcproto_origindiscover_proto_msgTypes[3]
+		mi := &file_rpcproto_origindiscover_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -252,7 +332,7 @@ func (x *NodeRetireReq) String() string {
 func (*NodeRetireReq) ProtoMessage() {}
 
 func (x *NodeRetireReq) ProtoReflect() protoreflect.Message {
-	mi := &file_rpcproto_origindiscover_proto_msgTypes[3]
+	mi := &file_rpcproto_origindiscover_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -265,7 +345,7 @@ func (x *NodeRetireReq) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use NodeRetireReq.ProtoReflect.Descriptor instead.
 func (*NodeRetireReq) Descriptor() ([]byte, []int) {
-	return file_rpcproto_origindiscover_proto_rawDescGZIP(), []int{3}
+	return file_rpcproto_origindiscover_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *NodeRetireReq) GetNodeInfo() *NodeInfo {
@@ -275,6 +355,54 @@ func (x *NodeRetireReq) GetNodeInfo() *NodeInfo {
 	return nil
 }
 
+// Client->Master
+type UpdateNodeInfoReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	NodeInfo *NodeInfo `protobuf:"bytes,1,opt,name=nodeInfo,proto3" json:"nodeInfo,omitempty"`
+}
+
+func (x *UpdateNodeInfoReq) Reset() {
+	*x = UpdateNodeInfoReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpcproto_origindiscover_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UpdateNodeInfoReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateNodeInfoReq) ProtoMessage() {}
+
+func (x *UpdateNodeInfoReq) ProtoReflect() protoreflect.Message {
+	mi := &file_rpcproto_origindiscover_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateNodeInfoReq.ProtoReflect.Descriptor instead.
+func (*UpdateNodeInfoReq) Descriptor() ([]byte, []int) {
+	return file_rpcproto_origindiscover_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UpdateNodeInfoReq) GetNodeInfo() *NodeInfo {
+	if x != nil {
+		return x.NodeInfo
+	}
+	return nil
+}
+
 // Master->Client
 type Empty struct {
 	state         protoimpl.MessageState
@@ -285,7 +413,7 @@ type Empty struct {
 func (x *Empty) Reset() {
 	*x = Empty{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_rpcproto_origindiscover_proto_msgTypes[4]
+		mi := &file_rpcproto_origindiscover_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -298,7 +426,7 @@ func (x *Empty) String() string {
 func (*Empty) ProtoMessage() {}
 
 func (x *Empty) ProtoReflect() protoreflect.Message {
-	mi := &file_rpcproto_origindiscover_proto_msgTypes[4]
+	mi := &file_rpcproto_origindiscover_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -311,7 +439,7 @@ func (x *Empty) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Empty.ProtoReflect.Descriptor instead.
 func (*Empty) Descriptor() ([]byte, []int) {
-	return file_rpcproto_origindiscover_proto_rawDescGZIP(), []int{4}
+	return file_rpcproto_origindiscover_proto_rawDescGZIP(), []int{6}
 }
 
 // Client->Master
@@ -326,7 +454,7 @@ type Ping struct {
 func (x *Ping) Reset() {
 	*x = Ping{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_rpcproto_origindiscover_proto_msgTypes[5]
+		mi := &file_rpcproto_origindiscover_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -339,7 +467,7 @@ func (x *Ping) String() string {
 func (*Ping) ProtoMessage() {}
 
 func (x *Ping) ProtoReflect() protoreflect.Message {
-	mi := &file_rpcproto_origindiscover_proto_msgTypes[5]
+	mi := &file_rpcproto_origindiscover_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -352,7 +480,7 @@ func (x *Ping) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Ping.ProtoReflect.Descriptor instead.
 func (*Ping) Descriptor() ([]byte, []int) {
-	return file_rpcproto_origindiscover_proto_rawDescGZIP(), []int{5}
+	return file_rpcproto_origindiscover_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *Ping) GetNodeId() string {
@@ -374,7 +502,7 @@ type Pong struct {
 func (x *Pong) Reset() {
 	*x = Pong{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_rpcproto_origindiscover_proto_msgTypes[6]
+		mi := &file_rpcproto_origindiscover_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -387,7 +515,7 @@ func (x *Pong) String() string {
 func (*Pong) ProtoMessage() {}
 
 func (x *Pong) ProtoReflect() protoreflect.Message {
-	mi := &file_rpcproto_origindiscover_proto_msgTypes[6]
+	mi := &file_rpcproto_origindiscover_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -400,7 +528,7 @@ func (x *Pong) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Pong.ProtoReflect.Descriptor instead.
 func (*Pong) Descriptor() ([]byte, []int) {
-	return file_rpcproto_origindiscover_proto_rawDescGZIP(), []int{6}
+	return file_rpcproto_origindiscover_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *Pong) GetOk() bool {
@@ -421,7 +549,7 @@ type UnRegServiceDiscoverReq struct {
 func (x *UnRegServiceDiscoverReq) Reset() {
 	*x = UnRegServiceDiscoverReq{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_rpcproto_origindiscover_proto_msgTypes[7]
+		mi := &file_rpcproto_origindiscover_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -434,7 +562,7 @@ func (x *UnRegServiceDiscoverReq) String() string {
 func (*UnRegServiceDiscoverReq) ProtoMessage() {}
 
 func (x *UnRegServiceDiscoverReq) ProtoReflect() protoreflect.Message {
-	mi := &file_rpcproto_origindiscover_proto_msgTypes[7]
+	mi := &file_rpcproto_origindiscover_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -447,7 +575,7 @@ func (x *UnRegServiceDiscoverReq) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UnRegServiceDiscoverReq.ProtoReflect.Descriptor instead.
 func (*UnRegServiceDiscoverReq) Descriptor() ([]byte, []int) {
-	return file_rpcproto_origindiscover_proto_rawDescGZIP(), []int{7}
+	return file_rpcproto_origindiscover_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *UnRegServiceDiscoverReq) GetNodeId() string {
@@ -462,46 +590,62 @@ var File_rpcproto_origindiscover_proto protoreflect.FileDescriptor
 var file_rpcproto_origindiscover_proto_rawDesc = []byte{
 	0x0a, 0x1d, 0x72, 0x70, 0x63, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x6f, 0x72, 0x69, 0x67, 0x69,
 	0x6e, 0x64, 0x69, 0x73, 0x63, 0x6f, 0x76, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
-	0x03, 0x72, 0x70, 0x63, 0x22, 0xca, 0x01, 0x0a, 0x08, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66,
-	0x6f, 0x12, 0x16, 0x0a, 0x06, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x06, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x12, 0x1e, 0x0a, 0x0a, 0x4c, 0x69, 0x73,
-	0x74, 0x65, 0x6e, 0x41, 0x64, 0x64, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x4c,
-	0x69, 0x73, 0x74, 0x65, 0x6e, 0x41, 0x64, 0x64, 0x72, 0x12, 0x26, 0x0a, 0x0e, 0x4d, 0x61, 0x78,
-	0x52, 0x70, 0x63, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x4c, 0x65, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28,
-	0x0d, 0x52, 0x0e, 0x4d, 0x61, 0x78, 0x52, 0x70, 0x63, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x4c, 0x65,
-	0x6e, 0x12, 0x18, 0x0a, 0x07, 0x50, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x18, 0x04, 0x20, 0x01,
-	0x28, 0x08, 0x52, 0x07, 0x50, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x52,
-	0x65, 0x74, 0x69, 0x72, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x52, 0x65, 0x74,
-	0x69, 0x72, 0x65, 0x12, 0x2c, 0x0a, 0x11, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x53, 0x65, 0x72,
-	0x76, 0x69, 0x63, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x18, 0x06, 0x20, 0x03, 0x28, 0x09, 0x52, 0x11,
-	0x50, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4c, 0x69, 0x73,
-	0x74, 0x22, 0x42, 0x0a, 0x15, 0x52, 0x65, 0x67, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x44,
-	0x69, 0x73, 0x63, 0x6f, 0x76, 0x65, 0x72, 0x52, 0x65, 0x71, 0x12, 0x29, 0x0a, 0x08, 0x6e, 0x6f,
-	0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x72,
-	0x70, 0x63, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x08, 0x6e, 0x6f, 0x64,
-	0x65, 0x49, 0x6e, 0x66, 0x6f, 0x22, 0x9e, 0x01, 0x0a, 0x17, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72,
-	0x69, 0x62, 0x65, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x76, 0x65, 0x72, 0x4e, 0x6f, 0x74, 0x69, 0x66,
-	0x79, 0x12, 0x22, 0x0a, 0x0c, 0x4d, 0x61, 0x73, 0x74, 0x65, 0x72, 0x4e, 0x6f, 0x64, 0x65, 0x49,
-	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x4d, 0x61, 0x73, 0x74, 0x65, 0x72, 0x4e,
-	0x6f, 0x64, 0x65, 0x49, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x49, 0x73, 0x46, 0x75, 0x6c, 0x6c, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x49, 0x73, 0x46, 0x75, 0x6c, 0x6c, 0x12, 0x1c, 0x0a,
-	0x09, 0x44, 0x65, 0x6c, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x09, 0x44, 0x65, 0x6c, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x12, 0x29, 0x0a, 0x08, 0x6e,
-	0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0d, 0x2e,
-	0x72, 0x70, 0x63, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x08, 0x6e, 0x6f,
-	0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x22, 0x3a, 0x0a, 0x0d, 0x4e, 0x6f, 0x64, 0x65, 0x52, 0x65,
-	0x74, 0x69, 0x72, 0x65, 0x52, 0x65, 0x71, 0x12, 0x29, 0x0a, 0x08, 0x6e, 0x6f, 0x64, 0x65, 0x49,
+	0x03, 0x72, 0x70, 0x63, 0x22, 0x7c, 0x0a, 0x0c, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x53,
+	0x74, 0x61, 0x74, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4e,
+	0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x53, 0x65, 0x72, 0x76, 0x69,
+	0x63, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x48, 0x65, 0x61, 0x6c, 0x74, 0x68,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x48, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x12, 0x16,
+	0x0a, 0x06, 0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x4f, 0x76, 0x65, 0x72, 0x6c, 0x6f,
+	0x61, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x4f, 0x76, 0x65, 0x72, 0x6c, 0x6f,
+	0x61, 0x64, 0x22, 0x89, 0x02, 0x0a, 0x08, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x12,
+	0x16, 0x0a, 0x06, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x06, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x12, 0x1e, 0x0a, 0x0a, 0x4c, 0x69, 0x73, 0x74, 0x65,
+	0x6e, 0x41, 0x64, 0x64, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x4c, 0x69, 0x73,
+	0x74, 0x65, 0x6e, 0x41, 0x64, 0x64, 0x72, 0x12, 0x26, 0x0a, 0x0e, 0x4d, 0x61, 0x78, 0x52, 0x70,
+	0x63, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x4c, 0x65, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x0e, 0x4d, 0x61, 0x78, 0x52, 0x70, 0x63, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x4c, 0x65, 0x6e, 0x12,
+	0x18, 0x0a, 0x07, 0x50, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x07, 0x50, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x52, 0x65, 0x74,
+	0x69, 0x72, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x52, 0x65, 0x74, 0x69, 0x72,
+	0x65, 0x12, 0x2c, 0x0a, 0x11, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x53, 0x65, 0x72, 0x76, 0x69,
+	0x63, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x18, 0x06, 0x20, 0x03, 0x28, 0x09, 0x52, 0x11, 0x50, 0x75,
+	0x62, 0x6c, 0x69, 0x63, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x12,
+	0x3d, 0x0a, 0x10, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x53, 0x74, 0x61, 0x74, 0x65, 0x4c,
+	0x69, 0x73, 0x74, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x72, 0x70, 0x63, 0x2e,
+	0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x10, 0x53, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x53, 0x74, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x22, 0x42,
+	0x0a, 0x15, 0x52, 0x65, 0x67, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x44, 0x69, 0x73, 0x63,
+	0x6f, 0x76, 0x65, 0x72, 0x52, 0x65, 0x71, 0x12, 0x29, 0x0a, 0x08, 0x6e, 0x6f, 0x64, 0x65, 0x49,
 	0x6e, 0x66, 0x6f, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x72, 0x70, 0x63, 0x2e,
 	0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x08, 0x6e, 0x6f, 0x64, 0x65, 0x49, 0x6e,
-	0x66, 0x6f, 0x22, 0x07, 0x0a, 0x05, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x22, 0x1e, 0x0a, 0x04, 0x50,
-	0x69, 0x6e, 0x67, 0x12, 0x16, 0x0a, 0x06, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x06, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x22, 0x16, 0x0a, 0x04, 0x50,
-	0x6f, 0x6e, 0x67, 0x12, 0x0e, 0x0a, 0x02, 0x6f, 0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52,
-	0x02, 0x6f, 0x6b, 0x22, 0x31, 0x0a, 0x17, 0x55, 0x6e, 0x52, 0x65, 0x67, 0x53, 0x65, 0x72, 0x76,
-	0x69, 0x63, 0x65, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x76, 0x65, 0x72, 0x52, 0x65, 0x71, 0x12, 0x16,
-	0x0a, 0x06, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
-	0x4e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x42, 0x07, 0x5a, 0x05, 0x2e, 0x3b, 0x72, 0x70, 0x63, 0x62,
-	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x66, 0x6f, 0x22, 0x9e, 0x01, 0x0a, 0x17, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65,
+	0x44, 0x69, 0x73, 0x63, 0x6f, 0x76, 0x65, 0x72, 0x4e, 0x6f, 0x74, 0x69, 0x66, 0x79, 0x12, 0x22,
+	0x0a, 0x0c, 0x4d, 0x61, 0x73, 0x74, 0x65, 0x72, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x4d, 0x61, 0x73, 0x74, 0x65, 0x72, 0x4e, 0x6f, 0x64, 0x65,
+	0x49, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x49, 0x73, 0x46, 0x75, 0x6c, 0x6c, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x06, 0x49, 0x73, 0x46, 0x75, 0x6c, 0x6c, 0x12, 0x1c, 0x0a, 0x09, 0x44, 0x65,
+	0x6c, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x44,
+	0x65, 0x6c, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x12, 0x29, 0x0a, 0x08, 0x6e, 0x6f, 0x64, 0x65,
+	0x49, 0x6e, 0x66, 0x6f, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x72, 0x70, 0x63,
+	0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x08, 0x6e, 0x6f, 0x64, 0x65, 0x49,
+	0x6e, 0x66, 0x6f, 0x22, 0x3a, 0x0a, 0x0d, 0x4e, 0x6f, 0x64, 0x65, 0x52, 0x65, 0x74, 0x69, 0x72,
+	0x65, 0x52, 0x65, 0x71, 0x12, 0x29, 0x0a, 0x08, 0x6e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x4e, 0x6f, 0x64,
+	0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x08, 0x6e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x22,
+	0x3e, 0x0a, 0x11, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66,
+	0x6f, 0x52, 0x65, 0x71, 0x12, 0x29, 0x0a, 0x08, 0x6e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x4e, 0x6f, 0x64,
+	0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x08, 0x6e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x22,
+	0x07, 0x0a, 0x05, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x22, 0x1e, 0x0a, 0x04, 0x50, 0x69, 0x6e, 0x67,
+	0x12, 0x16, 0x0a, 0x06, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x22, 0x16, 0x0a, 0x04, 0x50, 0x6f, 0x6e, 0x67,
+	0x12, 0x0e, 0x0a, 0x02, 0x6f, 0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x02, 0x6f, 0x6b,
+	0x22, 0x31, 0x0a, 0x17, 0x55, 0x6e, 0x52, 0x65, 0x67, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
+	0x44, 0x69, 0x73, 0x63, 0x6f, 0x76, 0x65, 0x72, 0x52, 0x65, 0x71, 0x12, 0x16, 0x0a, 0x06, 0x4e,
+	0x6f, 0x64, 0x65, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x4e, 0x6f, 0x64,
+	0x65, 0x49, 0x64, 0x42, 0x07, 0x5a, 0x05, 0x2e, 0x3b, 0x72, 0x70, 0x63, 0x62, 0x06, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -516,26 +660,30 @@ func file_rpcproto_origindiscover_proto_rawDescGZIP() []byte {
 	return file_rpcproto_origindiscover_proto_rawDescData
 }
 
-var file_rpcproto_origindiscover_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_rpcproto_origindiscover_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
 var file_rpcproto_origindiscover_proto_goTypes = []interface{}{
-	(*NodeInfo)(nil),                // 0: rpc.NodeInfo
-	(*RegServiceDiscoverReq)(nil),   // 1: rpc.RegServiceDiscoverReq
-	(*SubscribeDiscoverNotify)(nil), // 2: rpc.SubscribeDiscoverNotify
-	(*NodeRetireReq)(nil),           // 3: rpc.NodeRetireReq
-	(*Empty)(nil),                   // 4: rpc.Empty
-	(*Ping)(nil),                    // 5: rpc.Ping
-	(*Pong)(nil),                    // 6: rpc.Pong
-	(*UnRegServiceDiscoverReq)(nil), // 7: rpc.UnRegServiceDiscoverReq
+	(*ServiceState)(nil),            // 0: rpc.ServiceState
+	(*NodeInfo)(nil),                // 1: rpc.NodeInfo
+	(*RegServiceDiscoverReq)(nil),   // 2: rpc.RegServiceDiscoverReq
+	(*SubscribeDiscoverNotify)(nil), // 3: rpc.SubscribeDiscoverNotify
+	(*NodeRetireReq)(nil),           // 4: rpc.NodeRetireReq
+	(*UpdateNodeInfoReq)(nil),       // 5: rpc.UpdateNodeInfoReq
+	(*Empty)(nil),                   // 6: rpc.Empty
+	(*Ping)(nil),                    // 7: rpc.Ping
+	(*Pong)(nil),                    // 8: rpc.Pong
+	(*UnRegServiceDiscoverReq)(nil), // 9: rpc.UnRegServiceDiscoverReq
 }
 var file_rpcproto_origindiscover_proto_depIdxs = []int32{
-	0, // 0: rpc.RegServiceDiscoverReq.nodeInfo:type_name -> rpc.NodeInfo
-	0, // 1: rpc.SubscribeDiscoverNotify.nodeInfo:type_name -> rpc.NodeInfo
-	0, // 2: rpc.NodeRetireReq.nodeInfo:type_name -> rpc.NodeInfo
-	3, // [3:3] is the sub-list for method output_type
-	3, // [3:3] is the sub-list for method input_type
-	3, // [3:3] is the sub-list for extension type_name
-	3, // [3:3] is the sub-list for extension extendee
-	0, // [0:3] is the sub-list for field type_name
+	0, // 0: rpc.NodeInfo.ServiceStateList:type_name -> rpc.ServiceState
+	1, // 1: rpc.RegServiceDiscoverReq.nodeInfo:type_name -> rpc.NodeInfo
+	1, // 2: rpc.SubscribeDiscoverNotify.nodeInfo:type_name -> rpc.NodeInfo
+	1, // 3: rpc.NodeRetireReq.nodeInfo:type_name -> rpc.NodeInfo
+	1, // 4: rpc.UpdateNodeInfoReq.nodeInfo:type_name -> rpc.NodeInfo
+	5, // [5:5] is the sub-list for method output_type
+	5, // [5:5] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
 }
 
 func init() { file_rpcproto_origindiscover_proto_init() }
@@ -545,7 +693,7 @@ func file_rpcproto_origindiscover_proto_init() {
 	}
 	if !protoimpl.UnsafeEnabled {
 		file_rpcproto_origindiscover_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*NodeInfo); i {
+			switch v := v.(*ServiceState); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -557,7 +705,7 @@ func file_rpcproto_origindiscover_proto_init() {
 			}
 		}
 		file_rpcproto_origindiscover_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*RegServiceDiscoverReq); i {
+			switch v := v.(*NodeInfo); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -569,7 +717,7 @@ func file_rpcproto_origindiscover_proto_init() {
 			}
 		}
 		file_rpcproto_origindiscover_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*SubscribeDiscoverNotify); i {
+			switch v := v.(*RegServiceDiscoverReq); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -581,7 +729,7 @@ func file_rpcproto_origindiscover_proto_init() {
 			}
 		}
 		file_rpcproto_origindiscover_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*NodeRetireReq); i {
+			switch v := v.(*SubscribeDiscoverNotify); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -593,7 +741,7 @@ func file_rpcproto_origindiscover_proto_init() {
 			}
 		}
 		file_rpcproto_origindiscover_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Empty); i {
+			switch v := v.(*NodeRetireReq); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -605,7 +753,7 @@ func file_rpcproto_origindiscover_proto_init() {
 			}
 		}
 		file_rpcproto_origindiscover_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Ping); i {
+			switch v := v.(*UpdateNodeInfoReq); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -617,7 +765,7 @@ func file_rpcproto_origindiscover_proto_init() {
 			}
 		}
 		file_rpcproto_origindiscover_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Pong); i {
+			switch v := v.(*Empty); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -629,6 +777,30 @@ func file_rpcproto_origindiscover_proto_init() {
 			}
 		}
 		file_rpcproto_origindiscover_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Ping); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpcproto_origindiscover_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Pong); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpcproto_origindiscover_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*UnRegServiceDiscoverReq); i {
 			case 0:
 				return &v.state
@@ -647,7 +819,7 @@ func file_rpcproto_origindiscover_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_rpcproto_origindiscover_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   8,
+			NumMessages:   10,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -23,10 +23,18 @@ func (slf *PBRpcRequestData) MakeRequest(seq uint64, rpcMethodId uint32, service
 	slf.ServiceMethod = serviceMethod
 	slf.NoReply = noReply
 	slf.InParam = inParam
+	slf.TraceId = ""
+	slf.CallerNodeId = ""
 
 	return slf
 }
 
+// SetTraceMeta 发送请求时填写调用链信息
+func (slf *PBRpcRequestData) SetTraceMeta(traceId string, callerNodeId string) {
+	slf.TraceId = traceId
+	slf.CallerNodeId = callerNodeId
+}
+
 func (slf *PBRpcResponseData) MakeResponse(seq uint64, err RpcError, reply []byte) *PBRpcResponseData {
 	slf.Seq = seq
 	slf.Error = err.Error()
@@ -45,6 +45,24 @@ type IRpcRequestData interface {
 	GetRpcMethodId() uint32
 }
 
+// IRpcRequestMeta 携带调用链信息的请求数据，处理请求时服务日志会附加TraceId与调用方结点
+type IRpcRequestMeta interface {
+	GetTraceId() string
+	GetCallerNodeId() string
+}
+
+// iRpcRequestMetaSetter 发送请求时填写调用链信息
+type iRpcRequestMetaSetter interface {
+	SetTraceMeta(traceId string, callerNodeId string)
+}
+
+var localNodeId string //本结点Id，发送请求时作为调用方结点
+
+// SetLocalNodeId 设置本结点Id，集群初始化时调用
+func SetLocalNodeId(nodeId string) {
+	localNodeId = nodeId
+}
+
 type IRpcResponseData interface {
 	GetSeq() uint64
 	GetErr() *RpcError
@@ -69,6 +87,7 @@ type Call struct {
 	callback      *reflect.Value
 	rpcHandler    IRpcHandler
 	TimeOut       time.Duration
+	virtualTask   bool //虚拟时钟下超时的调用，回调执行完成后通知时钟
 }
 
 type RpcCancel struct {
@@ -106,6 +125,21 @@ func (slf *RpcRequest) UnRef(){
 	slf.ref = false
 }
 
+// ReplyError 请求未被处理时直接返回错误，没有返回回调时释放请求
+func (slf *RpcRequest) ReplyError(err RpcError) {
+	if slf.requestHandle == nil {
+		ReleaseRpcRequest(slf)
+		return
+	}
+
+	slf.requestHandle(nil, err)
+}
+
+// GetInParam 获取请求参数，本地调用时为参数对象，远程调用时为反序列化后的对象，原始Rpc为[]byte
+func (slf *RpcRequest) GetInParam() interface{}{
+	return slf.inParam
+}
+
 func (rpcResponse *RpcResponse) Clear() *RpcResponse{
 	rpcResponse.RpcResponseData = nil
 	return rpcResponse
@@ -134,6 +168,7 @@ func (call *Call) Clear() *Call{
 	call.callback = nil
 	call.rpcHandler = nil
 	call.TimeOut = 0
+	call.virtualTask = false
 
 	return call
 }
@@ -166,6 +201,20 @@ func MakeRpcRequest(rpcProcessor IRpcProcessor,seq uint64,rpcMethodId uint32,ser
 	return rpcRequest
 }
 
+// setTraceMeta 填写调用方正在处理的请求的TraceId与本结点Id
+func (slf *RpcRequest) setTraceMeta(callerRpcHandler IRpcHandler) {
+	setter, ok := slf.RpcRequestData.(iRpcRequestMetaSetter)
+	if ok == false {
+		return
+	}
+
+	var traceId string
+	if callerRpcHandler != nil {
+		traceId = callerRpcHandler.GetTraceId()
+	}
+	setter.SetTraceMeta(traceId, localNodeId)
+}
+
 func ReleaseRpcRequest(rpcRequest *RpcRequest){
 	rpcRequest.rpcProcessor.ReleaseRpcRequest(rpcRequest.RpcRequestData)
 	rpcRequestPool.Put(rpcRequest)
@@ -5,9 +5,13 @@ import (
 	"fmt"
 	"github.com/duanhf2012/origin/v2/event"
 	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/profiler"
+	"github.com/duanhf2012/origin/v2/util/timer"
 	"reflect"
 
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 	"unicode"
 	"unicode/utf8"
@@ -63,6 +67,8 @@ type RpcHandler struct {
 	mapRawFunctions map[uint32]RawRpcCallBack
 	funcRpcClient   FuncRpcClient
 	funcRpcServer   FuncRpcServer
+	mapTraceId      sync.Map     //协程Id->该协程正在处理的请求的TraceId，发送请求时沿用
+	traceNum        atomic.Int32 //设置了TraceId的协程数，为0时不需要获取协程Id
 
 	//pClientList []*Client
 }
@@ -114,6 +120,9 @@ type IRpcHandler interface {
 	CastGo(serviceMethod string, args interface{}) error
 	UnmarshalInParam(rpcProcessor IRpcProcessor, serviceMethod string, rawRpcMethodId uint32, inParam []byte) (interface{}, error)
 	GetRpcServer() FuncRpcServer
+
+	SetTraceId(traceId string)
+	GetTraceId() string
 }
 
 func reqHandlerNull(Returns interface{}, Err RpcError) {
@@ -223,6 +232,10 @@ func (handler *RpcHandler) RegisterRpc(rpcHandler IRpcHandler) error {
 }
 
 func (handler *RpcHandler) HandlerRpcResponseCB(call *Call) {
+	if call.virtualTask == true {
+		defer timer.DoneVirtualTask()
+	}
+
 	defer func() {
 		if r := recover(); r != nil {
 			log.StackError(fmt.Sprint(r))
@@ -640,3 +653,32 @@ func (handler *RpcHandler) UnmarshalInParam(rpcProcessor IRpcProcessor, serviceM
 func (handler *RpcHandler) GetRpcServer() FuncRpcServer {
 	return handler.funcRpcServer
 }
+
+// SetTraceId 设置当前协程正在处理的请求的TraceId，之后在该协程中发送的请求沿用，为空时清除
+// 按协程区分，分区协程各自设置，AsyncDo等其他协程中发送的请求不会沿用
+func (handler *RpcHandler) SetTraceId(traceId string) {
+	goid := profiler.GetGoroutineId()
+	if traceId == "" {
+		if _, loaded := handler.mapTraceId.LoadAndDelete(goid); loaded == true {
+			handler.traceNum.Add(-1)
+		}
+		return
+	}
+
+	if _, loaded := handler.mapTraceId.Swap(goid, traceId); loaded == false {
+		handler.traceNum.Add(1)
+	}
+}
+
+// GetTraceId 获取当前协程正在处理的请求的TraceId
+func (handler *RpcHandler) GetTraceId() string {
+	if handler.traceNum.Load() == 0 {
+		return ""
+	}
+
+	if traceId, ok := handler.mapTraceId.Load(profiler.GetGoroutineId()); ok == true {
+		return traceId.(string)
+	}
+
+	return ""
+}
@@ -3,6 +3,8 @@ package rpc
 import (
 	"container/heap"
 	"time"
+
+	"github.com/duanhf2012/origin/v2/util/timer"
 )
 
 type CallTimer struct {
@@ -62,7 +64,7 @@ func (h *CallTimerHeap) Cancel(seq uint64) bool {
 func (h *CallTimerHeap) AddTimer(seqId uint64,d time.Duration){
 	heap.Push(h, CallTimer{
 		SeqId:    seqId,
-		FireTime: time.Now().Add(d).UnixNano(),
+		FireTime: timer.Now().Add(d).UnixNano(),
 	})
 }
 
@@ -72,13 +74,22 @@ func (h *CallTimerHeap) PopTimeout() uint64 {
 	}
 
 	nextFireTime := h.callTimer[0].FireTime
-	if nextFireTime > time.Now().UnixNano() {
+	if nextFireTime > timer.Now().UnixNano() {
 		return 0
 	}
 
 	return heap.Pop(h).(uint64)
 }
 
+// NextFireTime 最早的超时时间
+func (h *CallTimerHeap) NextFireTime() (time.Time, bool) {
+	if h.Len() == 0 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, h.callTimer[0].FireTime), true
+}
+
 func (h *CallTimerHeap) PopFirst() uint64 {
 	if h.Len() == 0 {
 		return 0
@@ -0,0 +1,128 @@
+package rpc
+
+import (
+	"testing"
+	"time"
+)
+
+// captureWriter 记录发送的数据，模拟连接
+type captureWriter struct {
+	data []byte
+}
+
+func (w *captureWriter) WriteMsg(_ string, args ...[]byte) error {
+	w.data = w.data[:0]
+	for _, arg := range args {
+		w.data = append(w.data, arg...)
+	}
+	return nil
+}
+
+func (w *captureWriter) IsConnected() bool {
+	return true
+}
+
+// testRequestHandler 记录收到的请求，模拟服务
+type testRequestHandler struct {
+	RpcHandler
+	request *RpcRequest
+}
+
+func (h *testRequestHandler) PushRpcRequest(request *RpcRequest) error {
+	h.request = request
+	return nil
+}
+
+func (h *testRequestHandler) UnmarshalInParam(_ IRpcProcessor, _ string, _ uint32, inParam []byte) (interface{}, error) {
+	return inParam, nil
+}
+
+type testHandleFinder struct {
+	handler IRpcHandler
+}
+
+func (f *testHandleFinder) FindRpcHandler(_ string) IRpcHandler {
+	return f.handler
+}
+
+func checkTraceMeta(t *testing.T, request *RpcRequest, traceId string, callerNodeId string) {
+	t.Helper()
+	if request == nil {
+		t.Fatal("request is not received")
+	}
+
+	meta, ok := request.RpcRequestData.(IRpcRequestMeta)
+	if ok == false {
+		t.Fatalf("%T has no trace meta", request.RpcRequestData)
+	}
+	if meta.GetTraceId() != traceId || meta.GetCallerNodeId() != callerNodeId {
+		t.Fatalf("trace id %q caller node id %q, want %q %q", meta.GetTraceId(), meta.GetCallerNodeId(), traceId, callerNodeId)
+	}
+}
+
+func TestRpcRequestTraceMeta(t *testing.T) {
+	SetLocalNodeId("node-1")
+	defer SetLocalNodeId("")
+
+	caller := &RpcHandler{}
+	receiver := &testRequestHandler{}
+	var server BaseServer
+	server.initBaseServer(0, &testHandleFinder{handler: receiver})
+
+	for _, processor := range []IRpcProcessor{&PBProcessor{}, &JsonProcessor{}} {
+		client := &Client{CallSet: &CallSet{}}
+
+		//经过序列化发送到其他结点，请求数据回收后不残留上一次的TraceId
+		for _, traceId := range []string{"t-100", ""} {
+			caller.SetTraceId(traceId)
+			var w captureWriter
+			client.rawGo("node-2", &w, time.Second, caller, processor, true, 1, "TestService.RPC_Test", []byte{1}, nil)
+
+			receiver.request = nil
+			if err := server.processRpcRequest(w.data, "", nil); err != nil {
+				t.Fatal(err)
+			}
+			checkTraceMeta(t, receiver.request, traceId, "node-1")
+			ReleaseRpcRequest(receiver.request)
+		}
+
+		//同结点调用直接投递请求
+		caller.SetTraceId("t-200")
+		receiver.request = nil
+		server.selfNodeRpcHandlerGo(time.Second, processor, client, caller, true, "TestService", 1, "TestService.RPC_Test", nil, nil, []byte{1})
+		checkTraceMeta(t, receiver.request, "t-200", "node-1")
+		ReleaseRpcRequest(receiver.request)
+	}
+}
+
+func TestTraceIdByGoroutine(t *testing.T) {
+	var handler RpcHandler
+	handler.SetTraceId("t-100")
+	defer handler.SetTraceId("")
+
+	//其他协程如AsyncDo与分区协程不沿用，各自设置后互不影响
+	chanTraceId := make(chan string, 2)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		chanTraceId <- handler.GetTraceId()
+		handler.SetTraceId("t-200")
+		chanTraceId <- handler.GetTraceId()
+		handler.SetTraceId("")
+	}()
+	if traceId := <-chanTraceId; traceId != "" {
+		t.Fatalf("trace id %q in other goroutine", traceId)
+	}
+	if traceId := <-chanTraceId; traceId != "t-200" {
+		t.Fatalf("trace id %q, want t-200", traceId)
+	}
+	<-done
+	if handler.GetTraceId() != "t-100" {
+		t.Fatalf("trace id %q, want t-100", handler.GetTraceId())
+	}
+
+	handler.SetTraceId("")
+	if handler.GetTraceId() != "" || handler.traceNum.Load() != 0 {
+		t.Fatal("trace id should be cleared")
+	}
+}
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/util/timer"
+)
+
+// DefaultCheckpointInterval 默认定时快照间隔
+const DefaultCheckpointInterval = 60 * time.Second
+
+// DefaultCheckpointDir 默认快照文件目录
+const DefaultCheckpointDir = "checkpoint"
+
+// ICheckpoint 服务实现该接口后开启快照，框架定时以及停止时调用Snapshot保存，启动时在OnStart前调用Restore恢复
+// Snapshot与Restore均在服务协程中调用
+type ICheckpoint interface {
+	Snapshot() ([]byte, error)
+	Restore(data []byte) error
+}
+
+// ICheckpointStore 快照存储，实现需要保证协程安全
+type ICheckpointStore interface {
+	Save(key string, data []byte) error
+	Load(key string) ([]byte, error) //快照不存在时返回nil,nil
+}
+
+// CheckpointTimer 快照中的定时器
+type CheckpointTimer struct {
+	Id           uint64          //快照时的定时器Id
+	ModuleId     uint32          //创建定时器的模块Id
+	FuncName     string          //回调函数名
+	IsTicker     bool            //是否为Ticker
+	Interval     time.Duration   //定时间隔
+	Remain       time.Duration   //距离下次触发的时间
+	AdditionData json.RawMessage //附加数据
+}
+
+type checkpointData struct {
+	Time      int64
+	Data      []byte
+	TimerList []CheckpointTimer
+}
+
+type serviceCheckpoint struct {
+	interval        time.Duration
+	tickerId        uint64
+	mapTimerFunc    map[string]func(uint64, interface{})
+	mapRestoreTimer map[uint64]uint64 //快照时的定时器Id->恢复后的定时器Id
+}
+
+var checkpointLocker sync.RWMutex
+var checkpointStore ICheckpointStore = NewFileCheckpointStore(DefaultCheckpointDir)
+var checkpointKeyPrefix string
+
+// SetCheckpointStore 设置快照存储，需要在服务启动前设置
+func SetCheckpointStore(store ICheckpointStore) {
+	checkpointLocker.Lock()
+	defer checkpointLocker.Unlock()
+
+	checkpointStore = store
+}
+
+// SetCheckpointKeyPrefix 设置快照键前缀，由node设置为结点Id
+func SetCheckpointKeyPrefix(prefix string) {
+	checkpointLocker.Lock()
+	defer checkpointLocker.Unlock()
+
+	checkpointKeyPrefix = prefix
+}
+
+func getCheckpointStore() (ICheckpointStore, string) {
+	checkpointLocker.RLock()
+	defer checkpointLocker.RUnlock()
+
+	return checkpointStore, checkpointKeyPrefix
+}
+
+// FileCheckpointStore 本地文件快照存储
+type FileCheckpointStore struct {
+	dir string
+}
+
+func NewFileCheckpointStore(dir string) *FileCheckpointStore {
+	return &FileCheckpointStore{dir: dir}
+}
+
+func (fs *FileCheckpointStore) Save(key string, data []byte) error {
+	if err := os.MkdirAll(fs.dir, 0755); err != nil {
+		return err
+	}
+
+	//先写临时文件再替换，防止写入中途退出导致快照损坏
+	fileName := filepath.Join(fs.dir, key+".ckpt")
+	tmpFileName := fileName + ".tmp"
+	if err := os.WriteFile(tmpFileName, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFileName, fileName)
+}
+
+func (fs *FileCheckpointStore) Load(key string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(fs.dir, key+".ckpt"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	return data, err
+}
+
+// SetCheckpointInterval 设置定时快照间隔，小于等于0时只在停止时快照，需要在OnInit中调用
+func (s *Service) SetCheckpointInterval(interval time.Duration) {
+	s.checkpoint.interval = interval
+}
+
+// RegCheckpointTimerFunc 注册可以被快照的定时器回调，使用这些回调通过SafeAfterFunc或SafeNewTicker创建的定时器会保存到快照中
+// 子模块的定时器同样保存，恢复到相同Id的模块中，子模块需要通过GetService注册回调且模块Id在重启后保持不变
+// 恢复时附加数据为json.RawMessage，Cron定时器不会保存，需要在OnInit中调用
+func (s *Service) RegCheckpointTimerFunc(cbList ...func(uint64, interface{})) {
+	if s.checkpoint.mapTimerFunc == nil {
+		s.checkpoint.mapTimerFunc = map[string]func(uint64, interface{}){}
+	}
+
+	for _, cb := range cbList {
+		s.checkpoint.mapTimerFunc[timer.GetFuncName(cb)] = cb
+	}
+}
+
+// GetRestoredTimerId 获取快照中的定时器恢复后的Id，在Restore与OnStart中调用，不存在时返回0
+func (s *Service) GetRestoredTimerId(snapshotTimerId uint64) uint64 {
+	return s.checkpoint.mapRestoreTimer[snapshotTimerId]
+}
+
+func (s *Service) getCheckpointKey() string {
+	_, prefix := getCheckpointStore()
+	if prefix == "" {
+		return s.GetName()
+	}
+
+	return prefix + "_" + s.GetName()
+}
+
+// restoreCheckpoint 在OnStart前恢复快照，先恢复定时器再调用Restore
+func (s *Service) restoreCheckpoint() {
+	cp, ok := s.self.(ICheckpoint)
+	if ok == false {
+		return
+	}
+
+	store, _ := getCheckpointStore()
+	if store == nil {
+		return
+	}
+
+	byteData, err := store.Load(s.getCheckpointKey())
+	if err != nil {
+		log.Error("load checkpoint fail", log.String("serviceName", s.GetName()), log.ErrorField("err", err))
+		return
+	}
+	if byteData == nil {
+		return
+	}
+
+	var data checkpointData
+	if err = json.Unmarshal(byteData, &data); err != nil {
+		log.Error("unmarshal checkpoint fail", log.String("serviceName", s.GetName()), log.ErrorField("err", err))
+		return
+	}
+
+	s.restoreTimer(data.TimerList, timer.Now().Sub(time.Unix(0, data.Time)))
+	if err = cp.Restore(data.Data); err != nil {
+		log.Error("restore checkpoint fail", log.String("serviceName", s.GetName()), log.ErrorField("err", err))
+		return
+	}
+
+	log.Info("service checkpoint has been restored", log.String("serviceName", s.GetName()), log.String("checkpointTime", time.Unix(0, data.Time).String()), log.Int("timerNum", len(s.checkpoint.mapRestoreTimer)))
+}
+
+// restoreTimer 恢复定时器，剩余时间扣除快照后经过的时间，停止期间已经到期的定时器立即触发，Ticker重新开始计时
+func (s *Service) restoreTimer(timerList []CheckpointTimer, elapsed time.Duration) {
+	s.checkpoint.mapRestoreTimer = map[uint64]uint64{}
+	for _, t := range timerList {
+		cb, ok := s.checkpoint.mapTimerFunc[t.FuncName]
+		if ok == false {
+			log.Warn("checkpoint timer func is not registered", log.String("serviceName", s.GetName()), log.String("funcName", t.FuncName))
+			continue
+		}
+
+		m := s.getCheckpointModule(t.ModuleId)
+		if m == nil {
+			log.Warn("checkpoint timer module is not found", log.String("serviceName", s.GetName()), log.String("funcName", t.FuncName), log.Uint32("moduleId", t.ModuleId))
+			continue
+		}
+
+		var additionData interface{}
+		if len(t.AdditionData) > 0 && string(t.AdditionData) != "null" {
+			additionData = t.AdditionData
+		}
+
+		var timerId uint64
+		if t.IsTicker == true {
+			m.SafeNewTicker(&timerId, t.Interval, additionData, cb)
+		} else {
+			m.SafeAfterFunc(&timerId, max(t.Remain-elapsed, 0), additionData, cb)
+		}
+		s.checkpoint.mapRestoreTimer[t.Id] = timerId
+	}
+}
+
+// getCheckpointModule 获取服务或子模块
+func (s *Service) getCheckpointModule(moduleId uint32) *Module {
+	if moduleId == s.GetModuleId() {
+		return &s.Module
+	}
+
+	m := s.GetModule(moduleId)
+	if m == nil {
+		return nil
+	}
+
+	return m.getBaseModule().(*Module)
+}
+
+// startCheckpointTicker 在OnStart后开启定时快照
+func (s *Service) startCheckpointTicker() {
+	s.checkpoint.mapRestoreTimer = nil
+	if _, ok := s.self.(ICheckpoint); ok == false {
+		return
+	}
+
+	if s.checkpoint.interval > 0 {
+		s.SafeNewTicker(&s.checkpoint.tickerId, s.checkpoint.interval, nil, s.onCheckpointTicker)
+	}
+}
+
+func (s *Service) onCheckpointTicker(uint64, interface{}) {
+	s.saveCheckpoint()
+}
+
+// saveCheckpoint 在服务协程中保存快照
+func (s *Service) saveCheckpoint() {
+	cp, ok := s.self.(ICheckpoint)
+	if ok == false {
+		return
+	}
+
+	store, _ := getCheckpointStore()
+	if store == nil {
+		return
+	}
+
+	byteData, err := cp.Snapshot()
+	if err != nil {
+		log.Error("snapshot service fail", log.String("serviceName", s.GetName()), log.ErrorField("err", err))
+		return
+	}
+
+	data := checkpointData{Time: timer.Now().UnixNano(), Data: byteData, TimerList: s.snapshotTimer()}
+	byteCheckpoint, err := json.Marshal(&data)
+	if err != nil {
+		log.Error("marshal checkpoint fail", log.String("serviceName", s.GetName()), log.ErrorField("err", err))
+		return
+	}
+
+	if err = store.Save(s.getCheckpointKey(), byteCheckpoint); err != nil {
+		log.Error("save checkpoint fail", log.String("serviceName", s.GetName()), log.ErrorField("err", err))
+	}
+}
+
+// snapshotTimer 获取服务及所有子模块中使用已注册回调创建的定时器
+func (s *Service) snapshotTimer() []CheckpointTimer {
+	if len(s.checkpoint.mapTimerFunc) == 0 {
+		return nil
+	}
+
+	return s.snapshotModuleTimer(&s.Module, timer.Now(), nil)
+}
+
+func (s *Service) snapshotModuleTimer(m *Module, now time.Time, timerList []CheckpointTimer) []CheckpointTimer {
+	for id, t := range m.mapActiveIdTimer {
+		if t.IsActive() == false {
+			continue
+		}
+
+		funcName := t.GetName()
+		if _, ok := s.checkpoint.mapTimerFunc[funcName]; ok == false {
+			continue
+		}
+
+		var ct CheckpointTimer
+		switch tm := t.(type) {
+		case *timer.Ticker:
+			ct = CheckpointTimer{IsTicker: true, Interval: tm.GetInterval(), AdditionData: marshalAdditionData(tm.AdditionData)}
+		case *timer.Timer:
+			ct = CheckpointTimer{Interval: tm.GetInterval(), AdditionData: marshalAdditionData(tm.AdditionData)}
+		default:
+			continue
+		}
+
+		ct.Id = id
+		ct.ModuleId = m.GetModuleId()
+		ct.FuncName = funcName
+		ct.Remain = t.GetFireTime().Sub(now)
+		timerList = append(timerList, ct)
+	}
+
+	for _, child := range m.child {
+		timerList = s.snapshotModuleTimer(child.getBaseModule().(*Module), now, timerList)
+	}
+
+	return timerList
+}
+
+func marshalAdditionData(additionData interface{}) json.RawMessage {
+	if additionData == nil {
+		return nil
+	}
+
+	if rawData, ok := additionData.(json.RawMessage); ok == true {
+		return rawData
+	}
+
+	byteData, err := json.Marshal(additionData)
+	if err != nil {
+		log.Error("marshal timer addition data fail", log.ErrorField("err", err))
+		return nil
+	}
+
+	return byteData
+}
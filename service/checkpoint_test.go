@@ -0,0 +1,184 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/util/timer"
+)
+
+type checkpointTestService struct {
+	Service
+
+	Score   int
+	TimerId uint64
+}
+
+func (cs *checkpointTestService) Snapshot() ([]byte, error) {
+	return json.Marshal(cs)
+}
+
+func (cs *checkpointTestService) Restore(data []byte) error {
+	if err := json.Unmarshal(data, cs); err != nil {
+		return err
+	}
+
+	cs.TimerId = cs.GetRestoredTimerId(cs.TimerId)
+	return nil
+}
+
+func (cs *checkpointTestService) onTimer(uint64, interface{}) {
+}
+
+func newCheckpointTestService() *checkpointTestService {
+	cs := &checkpointTestService{}
+	cs.self = cs
+	cs.SetName("CheckpointTestService")
+	cs.dispatcher = timer.NewDispatcher(100)
+	cs.RegCheckpointTimerFunc(cs.onTimer)
+	return cs
+}
+
+func TestFileCheckpointStore(t *testing.T) {
+	store := NewFileCheckpointStore(t.TempDir())
+	data, err := store.Load("node_1_TestService")
+	if err != nil || data != nil {
+		t.Fatalf("missing checkpoint should return nil, %v", err)
+	}
+
+	if err = store.Save("node_1_TestService", []byte("snapshot")); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = store.Load("node_1_TestService")
+	if err != nil || string(data) != "snapshot" {
+		t.Fatalf("unexpected checkpoint %s %v", data, err)
+	}
+}
+
+func TestCheckpointRestore(t *testing.T) {
+	SetCheckpointStore(NewFileCheckpointStore(t.TempDir()))
+	defer SetCheckpointStore(NewFileCheckpointStore(DefaultCheckpointDir))
+
+	cs := newCheckpointTestService()
+	cs.Score = 100
+	cs.SafeAfterFunc(&cs.TimerId, time.Hour, map[string]int{"Level": 3}, cs.onTimer)
+	cs.saveCheckpoint()
+
+	restored := newCheckpointTestService()
+	restored.restoreCheckpoint()
+	if restored.Score != 100 {
+		t.Fatalf("unexpected score %d", restored.Score)
+	}
+
+	if restored.TimerId == 0 {
+		t.Fatal("timer is not restored")
+	}
+
+	tm, ok := restored.mapActiveIdTimer[restored.TimerId].(*timer.Timer)
+	if ok == false {
+		t.Fatal("restored timer not found")
+	}
+
+	if string(tm.AdditionData.(json.RawMessage)) != `{"Level":3}` {
+		t.Fatalf("unexpected addition data %s", tm.AdditionData)
+	}
+
+	if remain := time.Until(tm.GetFireTime()); remain < 59*time.Minute || remain > time.Hour {
+		t.Fatalf("unexpected remain time %s", remain)
+	}
+}
+
+type checkpointTestModule struct {
+	Module
+}
+
+type checkpointModuleService struct {
+	Service
+}
+
+func (cs *checkpointModuleService) Snapshot() ([]byte, error) {
+	return nil, nil
+}
+
+func (cs *checkpointModuleService) Restore([]byte) error {
+	return nil
+}
+
+func (cm *checkpointTestModule) onModuleTimer(uint64, interface{}) {
+}
+
+func TestCheckpointRestoreElapsed(t *testing.T) {
+	store := NewFileCheckpointStore(t.TempDir())
+	SetCheckpointStore(store)
+	defer SetCheckpointStore(NewFileCheckpointStore(DefaultCheckpointDir))
+
+	//快照保存于2小时前，1小时后到期的定时器在恢复时立即到期，2小时后到期的剩余1小时
+	data := checkpointData{
+		Time: timer.Now().Add(-2 * time.Hour).UnixNano(),
+		Data: []byte(`{"TimerId":1}`),
+		TimerList: []CheckpointTimer{
+			{Id: 1, FuncName: timer.GetFuncName((&checkpointTestService{}).onTimer), Remain: time.Hour},
+			{Id: 2, FuncName: timer.GetFuncName((&checkpointTestService{}).onTimer), Remain: 3 * time.Hour},
+		},
+	}
+	byteData, err := json.Marshal(&data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := newCheckpointTestService()
+	if err = store.Save(restored.getCheckpointKey(), byteData); err != nil {
+		t.Fatal(err)
+	}
+	restored.restoreCheckpoint()
+
+	overdue := restored.mapActiveIdTimer[restored.GetRestoredTimerId(1)]
+	if overdue == nil {
+		t.Fatal("overdue timer is not restored")
+	}
+	if remain := time.Until(overdue.GetFireTime()); remain > time.Second {
+		t.Fatalf("overdue timer should fire now, remain %s", remain)
+	}
+
+	pending := restored.mapActiveIdTimer[restored.GetRestoredTimerId(2)]
+	if pending == nil {
+		t.Fatal("pending timer is not restored")
+	}
+	if remain := time.Until(pending.GetFireTime()); remain < 59*time.Minute || remain > time.Hour {
+		t.Fatalf("unexpected remain time %s", remain)
+	}
+}
+
+func TestCheckpointModuleTimer(t *testing.T) {
+	SetCheckpointStore(NewFileCheckpointStore(t.TempDir()))
+	defer SetCheckpointStore(NewFileCheckpointStore(DefaultCheckpointDir))
+
+	newService := func() (*checkpointModuleService, *checkpointTestModule) {
+		cs := &checkpointModuleService{}
+		cs.SetName("CheckpointModuleService")
+		cs.Init(cs, nil, nil, nil)
+		cm := &checkpointTestModule{}
+		if _, err := cs.AddModule(cm); err != nil {
+			t.Fatal(err)
+		}
+		cs.RegCheckpointTimerFunc(cm.onModuleTimer)
+		return cs, cm
+	}
+
+	cs, cm := newService()
+	var timerId uint64
+	cm.SafeAfterFunc(&timerId, time.Hour, nil, cm.onModuleTimer)
+	cs.saveCheckpoint()
+
+	restored, restoredModule := newService()
+	restored.restoreCheckpoint()
+	restoredId := restored.GetRestoredTimerId(timerId)
+	if restoredId == 0 || restoredModule.mapActiveIdTimer[restoredId] == nil {
+		t.Fatalf("module timer is not restored, %d", restoredId)
+	}
+	if len(restored.mapActiveIdTimer) != 0 {
+		t.Fatal("module timer should not be restored to service")
+	}
+}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/log"
+)
+
+// DefaultDependTimeout 默认等待依赖就绪的超时时间，超时后服务仍会启动
+const DefaultDependTimeout = 60 * time.Second
+
+// 依赖检查间隔
+const dependCheckInterval = 100 * time.Millisecond
+
+// RemoteServiceReadyFun 检查集群中是否存在可路由的服务，由cluster设置
+var RemoteServiceReadyFun func(serviceName string) bool
+
+// ServiceDepend 服务依赖声明
+type ServiceDepend struct {
+	LocalList  []string      //本结点依赖的服务，需要已启动且不处于NotReady状态
+	RemoteList []string      //集群中依赖的服务，通过服务发现检查是否可路由
+	Timeout    time.Duration //等待超时时间
+}
+
+// dependReport 服务启动报告
+type dependReport struct {
+	serviceName string
+	waitTime    time.Duration
+	timeout     bool
+	missingList []string
+}
+
+// activator 等待依赖就绪后按依赖顺序启动服务
+type activator struct {
+	locker         sync.Mutex
+	mapWaitService map[string]string //等待中的服务->等待原因
+	closeSig       chan struct{}
+	wg             sync.WaitGroup
+}
+
+var serviceActivator activator
+
+// DependOn 声明依赖本结点的服务，需要在OnInit中调用
+func (s *Service) DependOn(serviceName ...string) {
+	s.depend.LocalList = append(s.depend.LocalList, serviceName...)
+}
+
+// DependOnRemote 声明依赖集群中的服务，需要在OnInit中调用
+func (s *Service) DependOnRemote(serviceName ...string) {
+	s.depend.RemoteList = append(s.depend.RemoteList, serviceName...)
+}
+
+// SetDependTimeout 设置等待依赖就绪的超时时间，需要在OnInit中调用
+func (s *Service) SetDependTimeout(timeout time.Duration) {
+	s.depend.Timeout = timeout
+}
+
+// GetDepend 获取服务依赖声明
+func (s *Service) GetDepend() ServiceDepend {
+	return s.depend
+}
+
+// IsStarted 服务是否已经启动
+func (s *Service) IsStarted() bool {
+	return s.startStatus
+}
+
+func hasDepend(s IService) bool {
+	depend := s.GetDepend()
+	return len(depend.LocalList) > 0 || len(depend.RemoteList) > 0
+}
+
+// sortByDepend 按依赖关系排序，被依赖的服务在前，无依赖关系的服务保持原有顺序
+func sortByDepend(serviceList []IService) ([]IService, error) {
+	mapService := make(map[string]IService, len(serviceList))
+	for _, s := range serviceList {
+		mapService[s.GetName()] = s
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	mapState := make(map[string]int, len(serviceList))
+	sortList := make([]IService, 0, len(serviceList))
+	var visit func(s IService, path []string) error
+	visit = func(s IService, path []string) error {
+		switch mapState[s.GetName()] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("circular service dependency %s", strings.Join(append(path, s.GetName()), "->"))
+		}
+
+		mapState[s.GetName()] = visiting
+		for _, dependName := range s.GetDepend().LocalList {
+			dependService, ok := mapService[dependName]
+			if ok == false {
+				continue
+			}
+
+			if err := visit(dependService, append(path, s.GetName())); err != nil {
+				return err
+			}
+		}
+		mapState[s.GetName()] = visited
+		sortList = append(sortList, s)
+		return nil
+	}
+
+	for _, s := range serviceList {
+		if err := visit(s, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return sortList, nil
+}
+
+// getMissingDepend 获取尚未就绪的依赖
+func getMissingDepend(s IService) []string {
+	var missingList []string
+	depend := s.GetDepend()
+	for _, serviceName := range depend.LocalList {
+		dependService := GetService(serviceName)
+		if dependService == nil {
+			missingList = append(missingList, serviceName)
+			continue
+		}
+
+		if dependService.IsStarted() == false {
+			missingList = append(missingList, serviceName)
+			continue
+		}
+
+		if status, _ := dependService.GetHealth(); status == NotReady {
+			missingList = append(missingList, serviceName)
+		}
+	}
+
+	for _, serviceName := range depend.RemoteList {
+		if RemoteServiceReadyFun == nil || RemoteServiceReadyFun(serviceName) == false {
+			missingList = append(missingList, "[remote]"+serviceName)
+		}
+	}
+
+	return missingList
+}
+
+// getDependWaitReason 服务等待依赖时返回等待原因
+func getDependWaitReason(serviceName string) (string, bool) {
+	serviceActivator.locker.Lock()
+	defer serviceActivator.locker.Unlock()
+
+	reason, ok := serviceActivator.mapWaitService[serviceName]
+	return reason, ok
+}
+
+func (a *activator) setWait(serviceName string, missingList []string) {
+	a.locker.Lock()
+	defer a.locker.Unlock()
+
+	if a.mapWaitService == nil {
+		a.mapWaitService = map[string]string{}
+	}
+	a.mapWaitService[serviceName] = "waiting for dependencies: " + strings.Join(missingList, ",")
+}
+
+func (a *activator) removeWait(serviceName string) {
+	a.locker.Lock()
+	defer a.locker.Unlock()
+
+	delete(a.mapWaitService, serviceName)
+}
+
+// start 按依赖顺序启动服务，存在未就绪依赖的服务由协程等待就绪后启动
+func (a *activator) start(serviceList []IService) {
+	var waitList []IService
+	var reportList []dependReport
+	for _, s := range serviceList {
+		missingList := getMissingDepend(s)
+		if len(missingList) > 0 {
+			a.setWait(s.GetName(), missingList)
+			waitList = append(waitList, s)
+			continue
+		}
+
+		s.Start()
+		if hasDepend(s) == true {
+			reportList = append(reportList, dependReport{serviceName: s.GetName()})
+		}
+	}
+
+	if len(waitList) == 0 {
+		logDependReport(reportList)
+		return
+	}
+
+	a.closeSig = make(chan struct{})
+	a.wg.Add(1)
+	go a.run(waitList, reportList, a.closeSig)
+}
+
+func (a *activator) run(waitList []IService, reportList []dependReport, closeSig chan struct{}) {
+	defer a.wg.Done()
+
+	ticker := time.NewTicker(dependCheckInterval)
+	defer ticker.Stop()
+
+	beginTime := time.Now()
+	for len(waitList) > 0 {
+		select {
+		case <-closeSig:
+			for _, s := range waitList {
+				a.removeWait(s.GetName())
+			}
+			return
+		case <-ticker.C:
+		}
+
+		//waitList已按依赖排序，被依赖的服务先启动
+		var stillWaitList []IService
+		for _, s := range waitList {
+			missingList := getMissingDepend(s)
+			waitTime := time.Since(beginTime)
+			timeout := s.GetDepend().Timeout
+			if timeout <= 0 {
+				timeout = DefaultDependTimeout
+			}
+
+			if len(missingList) > 0 && waitTime < timeout {
+				a.setWait(s.GetName(), missingList)
+				stillWaitList = append(stillWaitList, s)
+				continue
+			}
+
+			report := dependReport{serviceName: s.GetName(), waitTime: waitTime, missingList: missingList}
+			if len(missingList) > 0 {
+				report.timeout = true
+				log.Warn("wait for service dependencies timeout", log.String("serviceName", s.GetName()), log.String("missing", strings.Join(missingList, ",")))
+			}
+
+			a.removeWait(s.GetName())
+			s.Start()
+			reportList = append(reportList, report)
+		}
+		waitList = stillWaitList
+	}
+
+	logDependReport(reportList)
+}
+
+func (a *activator) stop() {
+	if a.closeSig == nil {
+		return
+	}
+
+	close(a.closeSig)
+	a.wg.Wait()
+	a.closeSig = nil
+}
+
+func logDependReport(reportList []dependReport) {
+	for _, report := range reportList {
+		if report.timeout == true {
+			log.Warn("service startup report", log.String("serviceName", report.serviceName), log.String("waitTime", report.waitTime.String()), log.Bool("timeout", true), log.String("missing", strings.Join(report.missingList, ",")))
+			continue
+		}
+
+		log.Info("service startup report", log.String("serviceName", report.serviceName), log.String("waitTime", report.waitTime.String()), log.Bool("timeout", false))
+	}
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newDependTestService(name string, dependList ...string) *Service {
+	s := &Service{}
+	s.SetName(name)
+	s.DependOn(dependList...)
+	return s
+}
+
+func TestSortByDepend(t *testing.T) {
+	serviceList := []IService{
+		newDependTestService("BattleService", "DBService", "RankService"),
+		newDependTestService("GateService"),
+		newDependTestService("RankService", "DBService"),
+		newDependTestService("DBService"),
+	}
+
+	sortList, err := sortByDepend(serviceList)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var nameList []string
+	for _, s := range sortList {
+		nameList = append(nameList, s.GetName())
+	}
+
+	expectList := []string{"DBService", "RankService", "BattleService", "GateService"}
+	for i := range expectList {
+		if nameList[i] != expectList[i] {
+			t.Fatalf("unexpected order %v", nameList)
+		}
+	}
+
+	//循环依赖
+	serviceList = []IService{
+		newDependTestService("A", "B"),
+		newDependTestService("B", "C"),
+		newDependTestService("C", "A"),
+	}
+	if _, err = sortByDepend(serviceList); err == nil {
+		t.Fatal("circular dependency is not detected")
+	}
+}
+
+// activatorTestService 记录是否被启动，不运行真正的服务协程
+type activatorTestService struct {
+	*Service
+	started atomic.Bool
+}
+
+func (s *activatorTestService) Start() {
+	s.started.Store(true)
+}
+
+func (s *activatorTestService) IsStarted() bool {
+	return s.started.Load()
+}
+
+func setupActivatorTestService(t *testing.T, name string, timeout time.Duration, dependList ...string) *activatorTestService {
+	t.Helper()
+	s := &activatorTestService{Service: newDependTestService(name, dependList...)}
+	s.SetDependTimeout(timeout)
+	if Setup(s) == false {
+		t.Fatalf("setup %s fail", name)
+	}
+	t.Cleanup(func() { Remove(name) })
+	return s
+}
+
+func waitStarted(t *testing.T, s *activatorTestService, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for s.IsStarted() == false {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s is not started", s.GetName())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestActivatorLocalDepend(t *testing.T) {
+	dbService := setupActivatorTestService(t, "ActivatorDBService", 0)
+	dbService.SetHealth(NotReady, "loading")
+	dbService.Start()
+	gameService := setupActivatorTestService(t, "ActivatorGameService", 10*time.Second, "ActivatorDBService")
+
+	serviceActivator.start([]IService{gameService})
+	defer serviceActivator.stop()
+
+	//依赖处于NotReady时不启动，等待期间自身也是NotReady
+	time.Sleep(3 * dependCheckInterval)
+	if gameService.IsStarted() == true {
+		t.Fatal("service started before its dependency is ready")
+	}
+	if status, reason := gameService.GetHealth(); status != NotReady || strings.Contains(reason, "ActivatorDBService") == false {
+		t.Fatalf("status %s reason %s, want not ready", status, reason)
+	}
+
+	dbService.SetHealth(Healthy, "")
+	waitStarted(t, gameService, time.Second)
+	if status, reason := gameService.GetHealth(); status != Healthy {
+		t.Fatalf("status %s reason %s, want healthy", status, reason)
+	}
+}
+
+func TestActivatorRemoteDepend(t *testing.T) {
+	var remoteReady atomic.Bool
+	RemoteServiceReadyFun = func(serviceName string) bool {
+		return serviceName == "ActivatorRemoteService" && remoteReady.Load()
+	}
+	defer func() { RemoteServiceReadyFun = nil }()
+
+	gameService := setupActivatorTestService(t, "ActivatorGameService", 10*time.Second)
+	gameService.DependOnRemote("ActivatorRemoteService")
+
+	serviceActivator.start([]IService{gameService})
+	defer serviceActivator.stop()
+
+	time.Sleep(3 * dependCheckInterval)
+	if gameService.IsStarted() == true {
+		t.Fatal("service started before remote dependency is routable")
+	}
+	if status, reason := gameService.GetHealth(); status != NotReady || strings.Contains(reason, "[remote]ActivatorRemoteService") == false {
+		t.Fatalf("status %s reason %s, want not ready", status, reason)
+	}
+
+	remoteReady.Store(true)
+	waitStarted(t, gameService, time.Second)
+}
+
+func TestActivatorDependTimeout(t *testing.T) {
+	const timeout = 3 * dependCheckInterval
+	gameService := setupActivatorTestService(t, "ActivatorGameService", timeout, "ActivatorMissingService")
+
+	beginTime := time.Now()
+	serviceActivator.start([]IService{gameService})
+	defer serviceActivator.stop()
+
+	//依赖一直未就绪，超时后仍然启动
+	if gameService.IsStarted() == true {
+		t.Fatal("service started before timeout")
+	}
+	waitStarted(t, gameService, timeout+time.Second)
+	if waitTime := time.Since(beginTime); waitTime < timeout {
+		t.Fatalf("service started after %s, before timeout %s", waitTime, timeout)
+	}
+	if _, ok := getDependWaitReason(gameService.GetName()); ok == true {
+		t.Fatal("wait reason should be removed after start")
+	}
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/duanhf2012/origin/v2/concurrent"
+	"github.com/duanhf2012/origin/v2/event"
+)
+
+// DrainChecker 返回尚未完成的数量，如连接中的客户端数，在排空检查协程中调用，实现需要保证协程安全
+type DrainChecker func() int
+
+type serviceDrain struct {
+	draining   int32
+	locker     sync.Mutex
+	mapChecker map[string]DrainChecker
+}
+
+// DrainPending 服务尚未排空的项
+type DrainPending struct {
+	ServiceName string
+	Name        string
+	Num         int
+}
+
+// RegDrainChecker 注册排空检查，如网络模块注册连接数
+func (s *Service) RegDrainChecker(name string, checker DrainChecker) {
+	s.drain.locker.Lock()
+	defer s.drain.locker.Unlock()
+
+	if s.drain.mapChecker == nil {
+		s.drain.mapChecker = map[string]DrainChecker{}
+	}
+	s.drain.mapChecker[name] = checker
+}
+
+// UnRegDrainChecker 取消排空检查
+func (s *Service) UnRegDrainChecker(name string) {
+	s.drain.locker.Lock()
+	defer s.drain.locker.Unlock()
+
+	delete(s.drain.mapChecker, name)
+}
+
+// Drain 进入排空阶段，OnDrain将在服务协程中回调，重复调用无效
+func (s *Service) Drain() {
+	if atomic.CompareAndSwapInt32(&s.drain.draining, 0, 1) == false {
+		return
+	}
+
+	ev := event.NewEvent()
+	ev.Type = event.Sys_Event_Drain
+	s.pushEvent(ev)
+}
+
+// IsDraining 服务是否处于排空阶段
+func (s *Service) IsDraining() bool {
+	return atomic.LoadInt32(&s.drain.draining) == 1
+}
+
+// OnDrain 排空阶段开始时回调，可以在此拒绝新请求、通知客户端断开等
+func (s *Service) OnDrain() {
+}
+
+// GetDrainPending 获取服务尚未排空的项，包括事件队列、并发任务以及注册的排空检查
+func (s *Service) GetDrainPending() []DrainPending {
+	var pendingList []DrainPending
+	if eventNum := s.eventLanes.len(); eventNum > 0 {
+		pendingList = append(pendingList, DrainPending{ServiceName: s.GetName(), Name: "events", Num: eventNum})
+	}
+
+	if cr, ok := s.IConcurrent.(*concurrent.Concurrent); ok == true {
+		if taskNum := cr.GetPendingTaskNum(); taskNum > 0 {
+			pendingList = append(pendingList, DrainPending{ServiceName: s.GetName(), Name: "concurrent", Num: taskNum})
+		}
+	}
+
+	s.drain.locker.Lock()
+	nameList := make([]string, 0, len(s.drain.mapChecker))
+	checkers := make(map[string]DrainChecker, len(s.drain.mapChecker))
+	for name, checker := range s.drain.mapChecker {
+		nameList = append(nameList, name)
+		checkers[name] = checker
+	}
+	s.drain.locker.Unlock()
+
+	sort.Strings(nameList)
+	for _, name := range nameList {
+		if num := checkers[name](); num > 0 {
+			pendingList = append(pendingList, DrainPending{ServiceName: s.GetName(), Name: name, Num: num})
+		}
+	}
+
+	return pendingList
+}
+
+// NotifyAllServiceDrain 通知所有服务进入排空阶段
+func NotifyAllServiceDrain() {
+	serviceList := getServiceList()
+	for i := len(serviceList) - 1; i >= 0; i-- {
+		serviceList[i].Drain()
+	}
+}
+
+// GetAllServiceDrainPending 获取所有服务尚未排空的项
+func GetAllServiceDrainPending() []DrainPending {
+	var pendingList []DrainPending
+	for _, s := range getServiceList() {
+		pendingList = append(pendingList, s.GetDrainPending()...)
+	}
+
+	return pendingList
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/event"
+)
+
+// EventLane 服务事件优先级通道，数值越小优先级越高
+type EventLane int
+
+const (
+	LaneSystem      EventLane = 0 //退休、服务发现、结点连接等系统事件
+	LaneRpcResponse EventLane = 1 //Rpc返回
+	LaneRpcRequest  EventLane = 2 //Rpc请求
+	LaneNetwork     EventLane = 3 //Tcp、WebSocket、Kcp、Http等网络事件
+	LaneUser        EventLane = 4 //用户自定义事件
+	LaneNum                   = 5
+)
+
+// 系统通道默认容量，不占用服务的事件容量。
+// 其他通道默认容量均为服务的事件容量，且共享该容量，
+// 即单个通道最多可以用满服务的事件容量，所有非系统通道的事件总数也不超过该容量
+const defaultSystemLaneCapacity = 10000
+
+// 队列为空且底层数组超过该长度时释放，避免突发流量后长期占用内存
+const laneQueueShrinkSize = 4096
+
+// 默认权重，每轮调度中各通道最多连续处理的事件数，保证低优先级通道不会被饿死
+var defaultLaneWeight = [LaneNum]int{16, 8, 4, 2, 1}
+
+var mapEventLane = map[event.EventType]EventLane{
+	event.ServiceRpcResponseEvent: LaneRpcResponse,
+	event.ServiceRpcRequestEvent:  LaneRpcRequest,
+	event.Sys_Event_Tcp:           LaneNetwork,
+	event.Sys_Event_Http_Event:    LaneNetwork,
+	event.Sys_Event_WebSocket:     LaneNetwork,
+	event.Sys_Event_Kcp:           LaneNetwork,
+	event.Sys_Event_Gin_Event:     LaneNetwork,
+	event.Sys_Event_EventBus:      LaneUser,
+}
+
+func (lane EventLane) String() string {
+	switch lane {
+	case LaneSystem:
+		return "System"
+	case LaneRpcResponse:
+		return "RpcResponse"
+	case LaneRpcRequest:
+		return "RpcRequest"
+	case LaneNetwork:
+		return "Network"
+	case LaneUser:
+		return "User"
+	}
+
+	return fmt.Sprintf("EventLane(%d)", int(lane))
+}
+
+// RegEventLane 指定事件类型使用的通道，需要在服务启动前设置
+func RegEventLane(eventType event.EventType, lane EventLane) {
+	if lane < 0 || lane >= LaneNum {
+		panic("invalid event lane")
+	}
+
+	mapEventLane[eventType] = lane
+}
+
+// SetDefaultLaneWeight 设置通道默认的调度权重，需要在服务初始化前设置
+func SetDefaultLaneWeight(lane EventLane, weight int) {
+	if lane < 0 || lane >= LaneNum || weight <= 0 {
+		return
+	}
+
+	defaultLaneWeight[lane] = weight
+}
+
+// getEventLane 未指定的系统事件使用系统通道，其他使用用户通道
+func getEventLane(eventType event.EventType) EventLane {
+	lane, ok := mapEventLane[eventType]
+	if ok == true {
+		return lane
+	}
+
+	if eventType < event.Sys_Event_User_Define {
+		return LaneSystem
+	}
+
+	return LaneUser
+}
+
+// laneItem 通道中的事件，pushTime为投递时间，未开启记录时为0
+type laneItem struct {
+	ev       event.IEvent
+	pushTime int64
+}
+
+// laneQueue 按需增长的先进先出队列，不预先分配容量
+type laneQueue struct {
+	itemList []laneItem
+	head     int
+}
+
+func (q *laneQueue) len() int {
+	return len(q.itemList) - q.head
+}
+
+func (q *laneQueue) push(item laneItem) {
+	q.itemList = append(q.itemList, item)
+}
+
+func (q *laneQueue) pop() (laneItem, bool) {
+	if q.head >= len(q.itemList) {
+		return laneItem{}, false
+	}
+
+	item := q.itemList[q.head]
+	q.itemList[q.head] = laneItem{}
+	q.head++
+
+	if q.head == len(q.itemList) {
+		if cap(q.itemList) > laneQueueShrinkSize {
+			q.itemList = nil
+		} else {
+			q.itemList = q.itemList[:0]
+		}
+		q.head = 0
+	} else if q.head >= laneQueueShrinkSize && q.head*2 >= len(q.itemList) {
+		//已取出的部分过半时前移，避免底层数组无限增长
+		n := copy(q.itemList, q.itemList[q.head:])
+		clear(q.itemList[n:])
+		q.itemList = q.itemList[:n]
+		q.head = 0
+	}
+
+	return item, true
+}
+
+// eventLanes 按优先级分通道的服务事件队列，按权重轮询取出事件
+type eventLanes struct {
+	totalCapacity int //非系统通道共享的事件容量
+	capacity      [LaneNum]int
+	weight        [LaneNum]int
+	signal        chan struct{} //每投递一个事件写入一个信号，服务协程通过信号感知事件
+	recordWait    bool          //记录投递时间，用于统计事件在队列中的等待时间
+
+	locker   sync.Mutex
+	lanes    [LaneNum]laneQueue
+	totalNum int //非系统通道中的事件总数
+	cursor   int
+	credit   int
+}
+
+func (el *eventLanes) isInit() bool {
+	return el.signal != nil
+}
+
+func (el *eventLanes) setTotalCapacity(totalCapacity int) {
+	if el.isInit() == true {
+		panic("this stage cannot be set")
+	}
+
+	el.totalCapacity = totalCapacity
+}
+
+func (el *eventLanes) setCapacity(lane EventLane, capacity int) {
+	if el.isInit() == true {
+		panic("this stage cannot be set")
+	}
+
+	el.capacity[lane] = capacity
+}
+
+func (el *eventLanes) setWeight(lane EventLane, weight int) {
+	el.locker.Lock()
+	defer el.locker.Unlock()
+
+	el.weight[lane] = weight
+}
+
+func (el *eventLanes) init(defaultTotalCapacity int) {
+	if el.totalCapacity <= 0 {
+		el.totalCapacity = defaultTotalCapacity
+	}
+
+	for lane := EventLane(0); lane < LaneNum; lane++ {
+		if el.capacity[lane] <= 0 {
+			if lane == LaneSystem {
+				el.capacity[lane] = defaultSystemLaneCapacity
+			} else {
+				el.capacity[lane] = el.totalCapacity
+			}
+		}
+
+		if el.weight[lane] <= 0 {
+			el.weight[lane] = defaultLaneWeight[lane]
+		}
+	}
+
+	el.credit = el.weight[LaneSystem]
+	//信号不占用元素内存，容量为系统通道与共享容量之和
+	el.signal = make(chan struct{}, el.capacity[LaneSystem]+el.totalCapacity)
+}
+
+func (el *eventLanes) push(ev event.IEvent) error {
+	lane := getEventLane(ev.GetEventType())
+	item := laneItem{ev: ev}
+	if el.recordWait == true {
+		item.pushTime = time.Now().UnixNano()
+	}
+
+	el.locker.Lock()
+	if el.lanes[lane].len() >= el.capacity[lane] {
+		el.locker.Unlock()
+		return errors.New("the " + lane.String() + " event lane in the service is full")
+	}
+	if lane != LaneSystem {
+		if el.totalNum >= el.totalCapacity {
+			el.locker.Unlock()
+			return errors.New("the event lanes in the service are full")
+		}
+		el.totalNum++
+	}
+	el.lanes[lane].push(item)
+	el.locker.Unlock()
+
+	//入队数量不超过信号容量，不会阻塞
+	el.signal <- struct{}{}
+	return nil
+}
+
+// pop 收到信号后调用，按权重从高优先级通道开始轮询
+func (el *eventLanes) pop() event.IEvent {
+	ev, _ := el.popWithTime()
+	return ev
+}
+
+// popWithTime 与pop相同，同时返回事件的投递时间
+func (el *eventLanes) popWithTime() (event.IEvent, int64) {
+	el.locker.Lock()
+	defer el.locker.Unlock()
+
+	for {
+		if el.credit > 0 {
+			if item, ok := el.lanes[el.cursor].pop(); ok == true {
+				el.credit--
+				if el.cursor != int(LaneSystem) {
+					el.totalNum--
+				}
+				return item.ev, item.pushTime
+			}
+		}
+
+		el.cursor = (el.cursor + 1) % LaneNum
+		el.credit = el.weight[el.cursor]
+	}
+}
+
+func (el *eventLanes) len() int {
+	el.locker.Lock()
+	defer el.locker.Unlock()
+
+	return el.totalNum + el.lanes[LaneSystem].len()
+}
+
+// load 返回事件总数与最满通道的占用比例
+func (el *eventLanes) load() (int, float64) {
+	el.locker.Lock()
+	defer el.locker.Unlock()
+
+	var fullestRate float64
+	for lane := EventLane(0); lane < LaneNum; lane++ {
+		rate := float64(el.lanes[lane].len()) / float64(el.capacity[lane])
+		fullestRate = max(fullestRate, rate)
+	}
+
+	return el.totalNum + el.lanes[LaneSystem].len(), fullestRate
+}
+
+// sharedLoad 返回非系统通道的事件总数与共享容量
+func (el *eventLanes) sharedLoad() (int, int) {
+	el.locker.Lock()
+	defer el.locker.Unlock()
+
+	return el.totalNum, el.totalCapacity
+}
+
+func (el *eventLanes) laneLen(lane EventLane) int {
+	el.locker.Lock()
+	defer el.locker.Unlock()
+
+	return el.lanes[lane].len()
+}
+
+func (el *eventLanes) laneCap(lane EventLane) int {
+	return el.capacity[lane]
+}
+
+// SetEventLaneCapacity 设置事件通道容量，需要在服务Init前设置。
+// 非系统通道默认容量为服务的事件容量，设置后该通道最多只能缓存capacity个事件
+
+func (s *Service) SetEventLaneCapacity(lane EventLane, capacity int) {
+	s.eventLanes.setCapacity(lane, capacity)
+}
+
+// SetEventLaneWeight 设置事件通道的调度权重
+func (s *Service) SetEventLaneWeight(lane EventLane, weight int) {
+	if weight <= 0 {
+		return
+	}
+
+	s.eventLanes.setWeight(lane, weight)
+}
+
+// GetEventLaneNum 获取事件通道中待处理的事件数
+func (s *Service) GetEventLaneNum(lane EventLane) int {
+	return s.eventLanes.laneLen(lane)
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/duanhf2012/origin/v2/event"
+)
+
+func pushTestEvent(t *testing.T, el *eventLanes, eventType event.EventType, num int) {
+	for i := 0; i < num; i++ {
+		ev := &event.Event{Type: eventType}
+		if err := el.push(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestEventLanesWeight(t *testing.T) {
+	var el eventLanes
+	el.init(400)
+
+	pushTestEvent(t, &el, event.Sys_Event_User_Define, 50)
+	pushTestEvent(t, &el, event.Sys_Event_Tcp, 50)
+	pushTestEvent(t, &el, event.ServiceRpcResponseEvent, 10)
+	pushTestEvent(t, &el, event.Sys_Event_Retire, 1)
+
+	//第一轮按权重取出：系统1个，Rpc返回8个，网络2个，用户1个
+	var mapLaneNum [LaneNum]int
+	for i := 0; i < 12; i++ {
+		<-el.signal
+		ev := el.pop()
+		mapLaneNum[getEventLane(ev.GetEventType())]++
+	}
+
+	if mapLaneNum[LaneSystem] != 1 || mapLaneNum[LaneRpcResponse] != 8 || mapLaneNum[LaneNetwork] != 2 || mapLaneNum[LaneUser] != 1 {
+		t.Fatalf("unexpected lane order %v", mapLaneNum)
+	}
+
+	//新的系统事件在下一轮优先取出
+	pushTestEvent(t, &el, event.Sys_Event_Retire, 1)
+	<-el.signal
+	if ev := el.pop(); ev.GetEventType() != event.Sys_Event_Retire {
+		t.Fatalf("system event is not first, got %d", ev.GetEventType())
+	}
+
+	if el.len() != 99 {
+		t.Fatalf("unexpected event num %d", el.len())
+	}
+}
+
+func TestEventLanesFull(t *testing.T) {
+	var el eventLanes
+	el.setCapacity(LaneUser, 2)
+	el.init(400)
+
+	pushTestEvent(t, &el, event.Sys_Event_User_Define, 2)
+	if err := el.push(&event.Event{Type: event.Sys_Event_User_Define}); err == nil {
+		t.Fatal("user lane should be full")
+	}
+
+	//其他通道不受影响
+	pushTestEvent(t, &el, event.ServiceRpcRequestEvent, 1)
+}
+
+func TestEventLanesShareCapacity(t *testing.T) {
+	var el eventLanes
+	el.init(10)
+
+	//单个通道可以用满服务的事件容量
+	if el.laneCap(LaneNetwork) != 10 {
+		t.Fatalf("unexpected lane capacity %d", el.laneCap(LaneNetwork))
+	}
+	pushTestEvent(t, &el, event.Sys_Event_Tcp, 10)
+	if err := el.push(&event.Event{Type: event.Sys_Event_User_Define}); err == nil {
+		t.Fatal("event lanes should be full")
+	}
+
+	//系统通道不占用共享容量
+	pushTestEvent(t, &el, event.Sys_Event_Retire, 1)
+
+	<-el.signal
+	el.pop()
+	<-el.signal
+	el.pop()
+	pushTestEvent(t, &el, event.Sys_Event_User_Define, 1)
+	if el.len() != 10 {
+		t.Fatalf("unexpected event num %d", el.len())
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+)
+
+// HealthStatus 服务健康状态，数值越大状态越差
+type HealthStatus int32
+
+const (
+	Healthy  HealthStatus = 0 //正常
+	Degraded HealthStatus = 1 //降级，仍然可以路由
+	NotReady HealthStatus = 2 //未就绪，路由时将被略过
+)
+
+// 事件队列使用率达到该比例时判定为降级，队列满时判定为未就绪
+var eventChannelDegradedRate = 0.8
+
+// SetEventChannelDegradedRate 设置事件队列判定为降级的使用率，范围(0,1]
+func SetEventChannelDegradedRate(rate float64) {
+	if rate <= 0 || rate > 1 {
+		return
+	}
+
+	eventChannelDegradedRate = rate
+}
+
+func (hs HealthStatus) String() string {
+	switch hs {
+	case Healthy:
+		return "Healthy"
+	case Degraded:
+		return "Degraded"
+	case NotReady:
+		return "NotReady"
+	}
+
+	return fmt.Sprintf("HealthStatus(%d)", int32(hs))
+}
+
+// IHealthChecker 健康检查接口，CheckHealth在健康检查协程中调用，实现需要保证协程安全
+type IHealthChecker interface {
+	CheckHealth() (HealthStatus, string)
+}
+
+// ServiceHealth 服务健康信息
+type ServiceHealth struct {
+	ServiceName string
+	Status      HealthStatus
+	Reason      string
+}
+
+type serviceHealth struct {
+	locker           sync.Mutex
+	status           HealthStatus //服务主动上报的状态
+	reason           string
+	mapHealthChecker map[string]IHealthChecker
+}
+
+// SetHealth 服务主动上报健康状态，如依赖的资源尚未加载完成时上报NotReady
+func (s *Service) SetHealth(status HealthStatus, reason string) {
+	s.health.locker.Lock()
+	defer s.health.locker.Unlock()
+
+	s.health.status = status
+	s.health.reason = reason
+}
+
+// RegHealthChecker 注册健康检查，如MongoModule的连接检查，MySQLModule作为模块加入服务时自动注册
+// 健康状态会被频繁查询，CheckHealth需要快速返回，不要在其中执行阻塞的IO
+func (s *Service) RegHealthChecker(name string, checker IHealthChecker) {
+	s.health.locker.Lock()
+	defer s.health.locker.Unlock()
+
+	if s.health.mapHealthChecker == nil {
+		s.health.mapHealthChecker = map[string]IHealthChecker{}
+	}
+	s.health.mapHealthChecker[name] = checker
+}
+
+// UnRegHealthChecker 取消健康检查
+func (s *Service) UnRegHealthChecker(name string) {
+	s.health.locker.Lock()
+	defer s.health.locker.Unlock()
+
+	delete(s.health.mapHealthChecker, name)
+}
+
+// GetHealth 获取服务当前的健康状态，取上报状态、事件队列与所有健康检查中最差的结果
+func (s *Service) GetHealth() (HealthStatus, string) {
+	//等待依赖就绪期间不参与路由
+	if reason, ok := getDependWaitReason(s.GetName()); ok == true {
+		return NotReady, reason
+	}
+
+	s.health.locker.Lock()
+	status := s.health.status
+	reason := s.health.reason
+	checkers := make(map[string]IHealthChecker, len(s.health.mapHealthChecker))
+	for name, checker := range s.health.mapHealthChecker {
+		checkers[name] = checker
+	}
+	s.health.locker.Unlock()
+
+	//非系统通道共用容量，各通道都未满时总数也可能已满
+	if totalNum, totalCapacity := s.eventLanes.sharedLoad(); totalCapacity > 0 {
+		if totalNum >= totalCapacity {
+			return NotReady, fmt.Sprintf("event lanes are full(%d)", totalCapacity)
+		}
+
+		if status == Healthy && float64(totalNum) >= float64(totalCapacity)*eventChannelDegradedRate {
+			status = Degraded
+			reason = fmt.Sprintf("event lanes are saturated(%d/%d)", totalNum, totalCapacity)
+		}
+	}
+
+	//事件通道饱和检查
+	for lane := EventLane(0); lane < LaneNum; lane++ {
+		capacity := s.eventLanes.laneCap(lane)
+		if capacity == 0 {
+			continue
+		}
+
+		eventNum := s.eventLanes.laneLen(lane)
+		if eventNum >= capacity {
+			return NotReady, fmt.Sprintf("%s event lane is full(%d)", lane.String(), capacity)
+		}
+
+		if status == Healthy && float64(eventNum) >= float64(capacity)*eventChannelDegradedRate {
+			status = Degraded
+			reason = fmt.Sprintf("%s event lane is saturated(%d/%d)", lane.String(), eventNum, capacity)
+		}
+	}
+
+	for name, checker := range checkers {
+		checkStatus, checkReason := checker.CheckHealth()
+		if checkStatus > status {
+			status = checkStatus
+			reason = name + ": " + checkReason
+		}
+	}
+
+	return status, reason
+}
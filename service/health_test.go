@@ -0,0 +1,29 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/duanhf2012/origin/v2/event"
+)
+
+func TestHealthSharedLaneCapacity(t *testing.T) {
+	var s Service
+	s.SetName("HealthTestService")
+	s.eventLanes.init(10)
+
+	//两个通道各占一半时，单个通道未满但共享容量已满
+	pushTestEvent(t, &s.eventLanes, event.ServiceRpcRequestEvent, 4)
+	pushTestEvent(t, &s.eventLanes, event.Sys_Event_Tcp, 4)
+	if status, reason := s.GetHealth(); status != Degraded {
+		t.Fatalf("status %d reason %s, want degraded", status, reason)
+	}
+
+	pushTestEvent(t, &s.eventLanes, event.ServiceRpcRequestEvent, 1)
+	pushTestEvent(t, &s.eventLanes, event.Sys_Event_Tcp, 1)
+	if err := s.eventLanes.push(&event.Event{Type: event.Sys_Event_User_Define}); err == nil {
+		t.Fatal("event lanes should be full")
+	}
+	if status, reason := s.GetHealth(); status != NotReady {
+		t.Fatalf("status %d reason %s, want not ready", status, reason)
+	}
+}
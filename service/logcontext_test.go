@@ -0,0 +1,35 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/rpc"
+)
+
+func TestRpcLogContext(t *testing.T) {
+	request := rpc.MakeRpcRequest(&rpc.PBProcessor{}, 1, 0, "GameService.RPC_Login", false, nil)
+	defer rpc.ReleaseRpcRequest(request)
+	requestData := request.RpcRequestData.(*rpc.PBRpcRequestData)
+	requestData.SetTraceMeta("t-100", "node-2")
+
+	//写日志时才从请求生成字段
+	var ctx log.LogContext
+	ctx.SetSource((*rpcLogContext)(request))
+	fieldMap := map[string]string{}
+	for _, field := range ctx.Get() {
+		fieldMap[field.Key] = field.String
+	}
+
+	if fieldMap["rpcMethod"] != "GameService.RPC_Login" || fieldMap["traceId"] != "t-100" || fieldMap["callerNodeId"] != "node-2" {
+		t.Fatalf("log context fields %v", fieldMap)
+	}
+	if getTraceId(request) != "t-100" {
+		t.Fatal("trace id is wrong")
+	}
+
+	ctx.Clear()
+	if len(ctx.Get()) != 0 {
+		t.Fatal("log context should be empty after clear")
+	}
+}
@@ -60,6 +60,9 @@ type Module struct {
 	//事件管道
 	eventHandler event.IEventHandler
 	concurrent.IConcurrent
+
+	logger     *log.NamedLogger //命名日志，首次获取时创建
+	logContext log.LogContext   //日志上下文，只使用始祖的，服务下所有模块共用
 }
 
 func (m *Module) SetModuleId(moduleId uint32) bool {
@@ -79,6 +82,26 @@ func (m *Module) GetModuleName() string {
 	return m.moduleName
 }
 
+// GetLogger 获取命名日志，服务使用服务名，模块使用"服务名.模块名"，级别可以在启动时通过-loglevels或运行时通过AdminService单独设置
+// 每行日志附加结点Id与服务名，服务单协程处理Rpc、事件与定时器时还会附加方法名、事件类型或定时器名
+// 上下文只在服务协程中读写，在其他协程中写日志时使用log.NewNamedLogger创建的日志
+func (m *Module) GetLogger() *log.NamedLogger {
+	if m.logger == nil {
+		serviceName := m.GetService().GetName()
+		name := serviceName
+		if m.parent != nil {
+			name += "." + m.GetModuleName()
+		}
+		m.logger = log.NewContextLogger(name, m.getLogContext(), log.String("service", serviceName))
+	}
+
+	return m.logger
+}
+
+func (m *Module) getLogContext() *log.LogContext {
+	return &m.GetAncestor().getBaseModule().(*Module).logContext
+}
+
 func (m *Module) OnInit() error {
 	return nil
 }
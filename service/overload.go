@@ -0,0 +1,166 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/duanhf2012/origin/v2/log"
+)
+
+var ErrServiceOverloaded = errors.New("service is overloaded")
+
+// 默认水位，为事件总容量的比例，同时按相同比例检查每个通道
+const (
+	defaultHighWatermarkRate = 0.8
+	defaultLowWatermarkRate  = 0.5
+)
+
+// OverloadCallback 服务过载状态变化回调，在触发变化的协程中执行，需要保证协程安全且尽快返回
+type OverloadCallback func(overloaded bool, eventNum int)
+
+// OverloadNotifyFun 服务过载状态变化时通知cluster，用于及时发布到服务发现
+var OverloadNotifyFun func(serviceName string, overloaded bool)
+
+// overloadState 事件队列水位，事件总数或任一通道超过高水位进入过载，均降到低水位以下恢复
+type overloadState struct {
+	highWatermark int
+	lowWatermark  int
+	highRate      float64 //单个通道的高水位比例
+	lowRate       float64 //单个通道的低水位比例
+	overloaded    int32
+
+	locker    sync.Mutex
+	recoverCh chan struct{} //过载期间有效，恢复时关闭
+	callbacks []OverloadCallback
+}
+
+func (ol *overloadState) init(totalCapacity int) {
+	if ol.highWatermark <= 0 {
+		ol.highWatermark = int(float64(totalCapacity) * defaultHighWatermarkRate)
+	}
+
+	if ol.lowWatermark <= 0 || ol.lowWatermark >= ol.highWatermark {
+		ol.lowWatermark = int(float64(ol.highWatermark) * defaultLowWatermarkRate / defaultHighWatermarkRate)
+	}
+
+	//通道水位与总水位使用相同的比例，单个通道容量可能小于总容量
+	ol.highRate = float64(ol.highWatermark) / float64(totalCapacity)
+	ol.lowRate = float64(ol.lowWatermark) / float64(totalCapacity)
+}
+
+func (ol *overloadState) isOverloaded() bool {
+	return atomic.LoadInt32(&ol.overloaded) == 1
+}
+
+// setOverloaded 设置过载状态，状态发生变化时返回回调列表
+func (ol *overloadState) setOverloaded(overloaded bool) ([]OverloadCallback, bool) {
+	ol.locker.Lock()
+	defer ol.locker.Unlock()
+
+	if overloaded == true {
+		if atomic.CompareAndSwapInt32(&ol.overloaded, 0, 1) == false {
+			return nil, false
+		}
+		ol.recoverCh = make(chan struct{})
+	} else {
+		if atomic.CompareAndSwapInt32(&ol.overloaded, 1, 0) == false {
+			return nil, false
+		}
+		close(ol.recoverCh)
+		ol.recoverCh = nil
+	}
+
+	return ol.callbacks, true
+}
+
+func (ol *overloadState) getRecoverCh() chan struct{} {
+	ol.locker.Lock()
+	defer ol.locker.Unlock()
+
+	return ol.recoverCh
+}
+
+// SetEventWatermark 设置事件队列的高低水位，需要在服务Init前设置，默认为事件总容量的80%与50%。
+// 每个通道按水位占总容量的比例检查，避免容量较小的通道写满时仍未过载
+func (s *Service) SetEventWatermark(highWatermark int, lowWatermark int) {
+	s.overload.highWatermark = highWatermark
+	s.overload.lowWatermark = lowWatermark
+}
+
+// RegOverloadCallback 注册过载状态变化回调
+func (s *Service) RegOverloadCallback(cb OverloadCallback) {
+	s.overload.locker.Lock()
+	defer s.overload.locker.Unlock()
+
+	s.overload.callbacks = append(s.overload.callbacks, cb)
+}
+
+// IsOverloaded 服务是否过载，过载期间Rpc请求直接返回ErrServiceOverloaded
+func (s *Service) IsOverloaded() bool {
+	return s.overload.isOverloaded()
+}
+
+// WaitOverloadRecover 服务过载时阻塞直到恢复或服务停止，网络模块在读取客户端消息前调用
+func (s *Service) WaitOverloadRecover() {
+	if s.overload.isOverloaded() == false {
+		return
+	}
+
+	recoverCh := s.overload.getRecoverCh()
+	if recoverCh == nil {
+		return
+	}
+
+	select {
+	case <-recoverCh:
+	case <-s.closeSig:
+	}
+}
+
+func (s *Service) checkHighWatermark() {
+	if s.overload.isOverloaded() == true {
+		return
+	}
+
+	eventNum, fullestRate := s.eventLanes.load()
+	if eventNum < s.overload.highWatermark && fullestRate < s.overload.highRate {
+		return
+	}
+
+	s.setOverloaded(true, eventNum)
+}
+
+func (s *Service) checkLowWatermark() {
+	if s.overload.isOverloaded() == false {
+		return
+	}
+
+	eventNum, fullestRate := s.eventLanes.load()
+	if eventNum > s.overload.lowWatermark || fullestRate > s.overload.lowRate {
+		return
+	}
+
+	s.setOverloaded(false, eventNum)
+}
+
+func (s *Service) setOverloaded(overloaded bool, eventNum int) {
+	callbacks, changed := s.overload.setOverloaded(overloaded)
+	if changed == false {
+		return
+	}
+
+	if overloaded == true {
+		log.Warn("service is overloaded", log.String("serviceName", s.GetName()), log.Int("eventNum", eventNum), log.Int("highWatermark", s.overload.highWatermark))
+	} else {
+		log.Info("service has recovered from overload", log.String("serviceName", s.GetName()), log.Int("eventNum", eventNum), log.Int("lowWatermark", s.overload.lowWatermark))
+	}
+
+	for _, cb := range callbacks {
+		cb(overloaded, eventNum)
+	}
+
+	if OverloadNotifyFun != nil {
+		OverloadNotifyFun(s.GetName(), overloaded)
+	}
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/duanhf2012/origin/v2/event"
+	"github.com/duanhf2012/origin/v2/rpc"
+)
+
+func TestOverloadWatermark(t *testing.T) {
+	var s Service
+	s.closeSig = make(chan struct{})
+	s.eventLanes.init(400)
+	s.SetEventWatermark(80, 40)
+	s.overload.init(s.eventLanes.totalCapacity)
+
+	var changeList []bool
+	s.RegOverloadCallback(func(overloaded bool, eventNum int) {
+		changeList = append(changeList, overloaded)
+	})
+
+	for i := 0; i < 80; i++ {
+		if err := s.pushEvent(&event.Event{Type: event.Sys_Event_Tcp}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if s.IsOverloaded() == false {
+		t.Fatal("service should be overloaded")
+	}
+
+	if err := s.PushRpcRequest(&rpc.RpcRequest{}); err != ErrServiceOverloaded {
+		t.Fatalf("unexpected push error %v", err)
+	}
+
+	//降到低水位后恢复
+	for i := 0; i < 40; i++ {
+		<-s.eventLanes.signal
+		s.eventLanes.pop()
+		s.checkLowWatermark()
+	}
+
+	if s.IsOverloaded() == true {
+		t.Fatal("service should be recovered")
+	}
+	s.WaitOverloadRecover()
+
+	if len(changeList) != 2 || changeList[0] != true || changeList[1] != false {
+		t.Fatalf("unexpected overload callback %v", changeList)
+	}
+}
+
+func TestOverloadSingleLane(t *testing.T) {
+	var s Service
+	s.closeSig = make(chan struct{})
+	s.SetEventLaneCapacity(LaneUser, 20)
+	s.eventLanes.init(400)
+	s.overload.init(s.eventLanes.totalCapacity)
+
+	//只写用户通道，总数远低于总水位，通道达到80%时过载
+	for i := 0; i < 16; i++ {
+		if s.IsOverloaded() == true {
+			t.Fatalf("service should not be overloaded at %d events", i)
+		}
+		if err := s.pushEvent(&event.Event{Type: event.Sys_Event_User_Define}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if s.IsOverloaded() == false {
+		t.Fatal("service should be overloaded")
+	}
+
+	//通道降到50%时恢复
+	for i := 0; i < 6; i++ {
+		if s.IsOverloaded() == false {
+			t.Fatalf("service should not be recovered at %d events", s.eventLanes.len())
+		}
+		<-s.eventLanes.signal
+		s.eventLanes.pop()
+		s.checkLowWatermark()
+	}
+
+	if s.IsOverloaded() == true {
+		t.Fatal("service should be recovered")
+	}
+}
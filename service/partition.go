@@ -0,0 +1,280 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/event"
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/profiler"
+	"github.com/duanhf2012/origin/v2/rpc"
+	"github.com/duanhf2012/origin/v2/util/timer"
+)
+
+// PartitionFun 分区函数，返回事件的分区键，ok为false时事件在服务主协程中处理
+// 在服务主协程中调用，相同键的事件总是在同一个分区协程中串行处理
+type PartitionFun func(ev event.IEvent) (key uint64, ok bool)
+
+const (
+	defaultPartitionQueueLen = 10000
+	partitionTimerChannelNum = 10000
+)
+
+var ErrPartitionFull = errors.New("partition task queue is full")
+
+type partitionTask struct {
+	ev       event.IEvent
+	pushTime int64 //事件投递时间
+	fn       func()
+}
+
+// partitionWorker 分区协程，拥有独立的任务队列与定时器
+type partitionWorker struct {
+	service        *Service
+	index          int
+	chanTask       chan partitionTask
+	dispatcher     *timer.Dispatcher
+	mapActiveTimer map[timer.ITimer]struct{} //只在分区协程中访问
+	goid           uint64                    //分区协程Id，协程开始时获取
+}
+
+type partition struct {
+	partitionFun PartitionFun
+	workers      []*partitionWorker
+	wg           sync.WaitGroup
+	closeSig     chan struct{}
+}
+
+// StringPartitionKey 将字符串键(如客户端连接id)转换为分区键
+func StringPartitionKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// GetRpcRequest 获取Rpc请求事件中的请求，非Rpc请求事件返回nil，用于在分区函数中根据参数分区
+func GetRpcRequest(ev event.IEvent) *rpc.RpcRequest {
+	if ev.GetEventType() != event.ServiceRpcRequestEvent {
+		return nil
+	}
+
+	cEvent, ok := ev.(*event.Event)
+	if ok == false {
+		return nil
+	}
+
+	rpcRequest, _ := cEvent.Data.(*rpc.RpcRequest)
+	return rpcRequest
+}
+
+// OpenPartition 开启分区并行处理，需要在OnInit中调用
+// 分区函数返回键的事件在对应的分区协程中处理，不同分区并行执行，其他事件、Rpc返回与Concurrent回调仍在服务主协程中处理
+// 分区之间共享的状态需要自行保证协程安全，可以使用GetPartitionIndex按分区划分状态
+func (s *Service) OpenPartition(workerNum int, queueLen int, fun PartitionFun) bool {
+	if s.startStatus == true || s.goroutineNum > 1 || s.partition != nil {
+		log.Error("open partition is not allowed in this stage", log.String("serviceName", s.GetName()))
+		return false
+	}
+
+	if workerNum <= 0 || fun == nil {
+		log.Error("invalid partition param", log.String("serviceName", s.GetName()), log.Int("workerNum", workerNum))
+		return false
+	}
+
+	if queueLen <= 0 {
+		queueLen = defaultPartitionQueueLen
+	}
+
+	p := &partition{partitionFun: fun, closeSig: make(chan struct{})}
+	for i := 0; i < workerNum; i++ {
+		p.workers = append(p.workers, &partitionWorker{
+			service:        s,
+			index:          i,
+			chanTask:       make(chan partitionTask, queueLen),
+			dispatcher:     timer.NewDispatcher(partitionTimerChannelNum),
+			mapActiveTimer: map[timer.ITimer]struct{}{},
+		})
+	}
+
+	for _, worker := range p.workers {
+		p.wg.Add(1)
+		go worker.run(&p.wg, p.closeSig)
+	}
+
+	s.partition = p
+	return true
+}
+
+// IsPartitionOpen 是否开启了分区
+func (s *Service) IsPartitionOpen() bool {
+	return s.partition != nil
+}
+
+// GetPartitionNum 获取分区数，未开启分区时为0
+func (s *Service) GetPartitionNum() int {
+	if s.partition == nil {
+		return 0
+	}
+
+	return len(s.partition.workers)
+}
+
+// GetPartitionIndex 获取键所在分区的索引，未开启分区时为-1
+func (s *Service) GetPartitionIndex(key uint64) int {
+	if s.partition == nil {
+		return -1
+	}
+
+	return s.partition.getWorker(key).index
+}
+
+// RunOnPartition 将函数投递到键所在的分区协程中执行，如在Rpc返回或Concurrent回调中回到分区处理
+// 分区队列满时不阻塞，返回ErrPartitionFull
+func (s *Service) RunOnPartition(key uint64, fn func()) error {
+	if s.partition == nil {
+		return errors.New("partition is not open")
+	}
+
+	return s.partition.getWorker(key).pushTask(partitionTask{fn: fn})
+}
+
+// PartitionAfterFunc 在键所在的分区协程中创建定时器，回调在分区协程中执行，可以在任意协程中调用
+// 定时器异步创建，需要取消时在回调中使用参数中的定时器
+func (s *Service) PartitionAfterFunc(key uint64, d time.Duration, cb func(*timer.Timer)) error {
+	return s.runOnPartitionWorker(key, func(worker *partitionWorker) {
+		worker.dispatcher.AfterFunc(d, nil, cb, worker.onCloseTimer, worker.onAddTimer)
+	})
+}
+
+// PartitionNewTicker 在键所在的分区协程中创建Ticker，回调在分区协程中执行，可以在任意协程中调用
+func (s *Service) PartitionNewTicker(key uint64, d time.Duration, cb func(*timer.Ticker)) error {
+	return s.runOnPartitionWorker(key, func(worker *partitionWorker) {
+		worker.dispatcher.TickerFunc(d, nil, cb, worker.onCloseTimer, worker.onAddTimer)
+	})
+}
+
+// PartitionCronFunc 在键所在的分区协程中创建Cron定时器，回调在分区协程中执行，可以在任意协程中调用
+func (s *Service) PartitionCronFunc(key uint64, cronExpr *timer.CronExpr, cb func(*timer.Cron)) error {
+	return s.runOnPartitionWorker(key, func(worker *partitionWorker) {
+		worker.dispatcher.CronFunc(cronExpr, nil, cb, worker.onCloseTimer, worker.onAddTimer)
+	})
+}
+
+// runOnPartitionWorker 在键所在的分区协程中执行，用于在分区协程中访问分区的定时器
+func (s *Service) runOnPartitionWorker(key uint64, fn func(worker *partitionWorker)) error {
+	if s.partition == nil {
+		return errors.New("partition is not open")
+	}
+
+	worker := s.partition.getWorker(key)
+	return worker.pushTask(partitionTask{fn: func() { fn(worker) }})
+}
+
+func (p *partition) getWorker(key uint64) *partitionWorker {
+	return p.workers[key%uint64(len(p.workers))]
+}
+
+// dispatch 在服务主协程中调用，事件被分区处理时返回true，分区队列满时返回ErrPartitionFull
+func (p *partition) dispatch(ev event.IEvent, pushTime int64) (bool, error) {
+	if p == nil {
+		return false, nil
+	}
+
+	key, ok := p.partitionFun(ev)
+	if ok == false {
+		return false, nil
+	}
+
+	return true, p.getWorker(key).pushTask(partitionTask{ev: ev, pushTime: pushTime})
+}
+
+// pushTask 不阻塞投递任务，避免分区处理慢时阻塞服务主协程
+func (pw *partitionWorker) pushTask(task partitionTask) error {
+	select {
+	case pw.chanTask <- task:
+		return nil
+	default:
+		return ErrPartitionFull
+	}
+}
+
+// close 处理完队列中剩余的任务后关闭所有分区协程
+func (p *partition) close() {
+	if p == nil {
+		return
+	}
+
+	close(p.closeSig)
+	p.wg.Wait()
+}
+
+func (pw *partitionWorker) run(wg *sync.WaitGroup, closeSig chan struct{}) {
+	defer wg.Done()
+	pw.goid = profiler.GetGoroutineId()
+
+	for {
+		select {
+		case <-closeSig:
+			pw.drain()
+			return
+		case task := <-pw.chanTask:
+			pw.doTask(task)
+		case t := <-pw.dispatcher.ChanTimer:
+			pw.doTimer(t)
+		}
+	}
+}
+
+func (pw *partitionWorker) drain() {
+	for {
+		select {
+		case task := <-pw.chanTask:
+			pw.doTask(task)
+		default:
+			for t := range pw.mapActiveTimer {
+				t.Cancel()
+			}
+			pw.mapActiveTimer = map[timer.ITimer]struct{}{}
+			return
+		}
+	}
+}
+
+func (pw *partitionWorker) doTask(task partitionTask) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.StackError(fmt.Sprint(r))
+		}
+	}()
+
+	if task.ev != nil {
+		pw.service.processEvent(task.ev, task.pushTime, pw.goid)
+		return
+	}
+
+	task.fn()
+}
+
+func (pw *partitionWorker) doTimer(t timer.ITimer) {
+	var analyzer *profiler.Analyzer
+	if pw.service.profiler != nil {
+		analyzer = pw.service.profiler.PushWaitGoroutine("[timer]"+t.GetName(), getTimerWait(t), pw.goid)
+	}
+	t.Do()
+	if analyzer != nil {
+		analyzer.Pop()
+	}
+}
+
+func (pw *partitionWorker) onAddTimer(t timer.ITimer) {
+	if t != nil {
+		pw.mapActiveTimer[t] = struct{}{}
+	}
+}
+
+func (pw *partitionWorker) onCloseTimer(t timer.ITimer) {
+	delete(pw.mapActiveTimer, t)
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/event"
+	"github.com/duanhf2012/origin/v2/util/timer"
+)
+
+func TestPartitionSerial(t *testing.T) {
+	var s Service
+	s.goroutineNum = 1
+	ok := s.OpenPartition(4, 0, func(ev event.IEvent) (uint64, bool) {
+		return 0, false
+	})
+	if ok == false {
+		t.Fatal("open partition fail")
+	}
+
+	//相同键的任务按投递顺序串行执行
+	const keyNum = 8
+	var keyList [keyNum][]int
+	for i := 0; i < 100; i++ {
+		for key := uint64(0); key < keyNum; key++ {
+			s.RunOnPartition(key, func() {
+				keyList[key] = append(keyList[key], i)
+			})
+		}
+	}
+	s.partition.close()
+
+	for key := 0; key < keyNum; key++ {
+		if len(keyList[key]) != 100 {
+			t.Fatalf("key %d task num is %d", key, len(keyList[key]))
+		}
+		for i, seq := range keyList[key] {
+			if seq != i {
+				t.Fatalf("key %d task order is wrong", key)
+			}
+		}
+	}
+
+	if s.GetPartitionIndex(5) != s.GetPartitionIndex(9) {
+		t.Fatal("same partition expected")
+	}
+}
+
+func TestPartitionFull(t *testing.T) {
+	var s Service
+	s.goroutineNum = 1
+	ok := s.OpenPartition(1, 1, func(ev event.IEvent) (uint64, bool) {
+		return 0, true
+	})
+	if ok == false {
+		t.Fatal("open partition fail")
+	}
+
+	//阻塞分区协程，队列写满后不阻塞投递方
+	release := make(chan struct{})
+	started := make(chan struct{})
+	if err := s.RunOnPartition(0, func() {
+		close(started)
+		<-release
+	}); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	if bPartition, err := s.partition.dispatch(&event.Event{Type: event.Sys_Event_Tcp}, 0); bPartition == false || err != nil {
+		t.Fatalf("dispatch %v %v", bPartition, err)
+	}
+	if _, err := s.partition.dispatch(&event.Event{Type: event.Sys_Event_Tcp}, 0); err != ErrPartitionFull {
+		t.Fatalf("unexpected dispatch error %v", err)
+	}
+	if err := s.RunOnPartition(0, func() {}); err != ErrPartitionFull {
+		t.Fatalf("unexpected run error %v", err)
+	}
+
+	close(release)
+	s.partition.close()
+}
+
+var startTimerOnce sync.Once
+
+func TestPartitionTimer(t *testing.T) {
+	startTimerOnce.Do(func() {
+		timer.StartTimer(time.Millisecond, 100)
+	})
+
+	var s Service
+	s.goroutineNum = 1
+	ok := s.OpenPartition(2, 0, func(ev event.IEvent) (uint64, bool) {
+		return 0, false
+	})
+	if ok == false {
+		t.Fatal("open partition fail")
+	}
+	defer s.partition.close()
+
+	//在其他协程中创建，定时器在分区协程中登记与触发
+	fired := make(chan int, 1)
+	err := s.PartitionAfterFunc(1, time.Millisecond, func(*timer.Timer) {
+		fired <- len(s.partition.getWorker(1).mapActiveTimer)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case activeNum := <-fired:
+		if activeNum != 1 {
+			t.Fatalf("active timer num %d", activeNum)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("partition timer is not fired")
+	}
+}
@@ -10,10 +10,12 @@ import (
 	"github.com/duanhf2012/origin/v2/profiler"
 	"github.com/duanhf2012/origin/v2/rpc"
 	"github.com/duanhf2012/origin/v2/util/timer"
+	"go.uber.org/zap"
 	"reflect"
 	"strconv"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 var timerDispatcherLen = 100000
@@ -44,6 +46,26 @@ type IService interface {
 
 	SetRetire()     //设置服务退休状态
 	IsRetire() bool //服务是否退休
+
+	Drain()                          //进入排空阶段
+	OnDrain()                        //排空阶段开始回调，在服务协程中执行
+	IsDraining() bool                //服务是否处于排空阶段
+	GetDrainPending() []DrainPending //获取尚未排空的项
+	RegDrainChecker(name string, checker DrainChecker)
+
+	GetHealth() (HealthStatus, string) //获取服务健康状态与原因
+	RegHealthChecker(name string, checker IHealthChecker)
+	UnRegHealthChecker(name string)
+	GetDepend() ServiceDepend          //获取服务依赖声明
+	IsStarted() bool                   //服务是否已经启动
+	IsOverloaded() bool                //事件队列是否超过高水位
+	WaitOverloadRecover()              //过载时阻塞直到恢复
+
+	NotifyGlobalConfigChanged(oldCfg interface{}, newCfg interface{}) //通知全局配置变化
+	NotifyServiceCfgChanged(newCfg interface{}, cb func(err error))   //通知服务配置变化，cb在服务协程中回调
+	OnConfigChanged(newCfg interface{}) error                         //服务配置变化回调，在服务协程中执行，返回错误时拒绝新配置
+	GetServiceCfgVersion() uint64                                     //获取当前生效的服务配置版本号
+	OnGlobalConfigChanged(oldCfg interface{}, newCfg interface{})     //全局配置变化回调，在服务协程中执行
 }
 
 type Service struct {
@@ -53,6 +75,8 @@ type Service struct {
 	name                   string         //service name
 	wg                     sync.WaitGroup
 	serviceCfg             interface{}
+	serviceCfgLocker       sync.RWMutex //服务配置可以热更新
+	serviceCfgVersion      uint64       //服务配置版本号，配置文件中的初始配置为0
 	goroutineNum           int32
 	startStatus            bool
 	isRelease              int32
@@ -62,8 +86,14 @@ type Service struct {
 	nodeConnLister         rpc.INodeConnListener
 	natsConnListener       rpc.INatsConnListener
 	discoveryServiceLister rpc.IDiscoveryServiceListener
-	chanEvent              chan event.IEvent
+	eventLanes             eventLanes //按优先级分通道的事件队列
 	closeSig               chan struct{}
+	health                 serviceHealth     //健康状态
+	overload               overloadState     //事件队列水位
+	partition              *partition        //按键分区并行处理，未开启时为nil
+	depend                 ServiceDepend     //服务依赖
+	drain                  serviceDrain      //排空状态
+	checkpoint             serviceCheckpoint //快照
 }
 
 // DiscoveryServiceEvent 发现服务结点
@@ -102,6 +132,16 @@ func (s *Service) OpenProfiler() {
 	if s.profiler == nil {
 		log.Fatal("profiler.RegProfiler " + s.GetName() + " fail.")
 	}
+
+	s.eventLanes.recordWait = true
+
+	//各事件通道深度
+	for lane := EventLane(0); lane < LaneNum; lane++ {
+		eventLane := lane
+		s.profiler.RegQueueDepth("[Lane]"+eventLane.String(), func() int {
+			return s.eventLanes.laneLen(eventLane)
+		})
+	}
 }
 
 func (s *Service) IsRetire() bool {
@@ -117,12 +157,23 @@ func (s *Service) SetRetire() {
 	s.pushEvent(ev)
 }
 
+// NotifyGlobalConfigChanged 投递全局配置变化事件，OnGlobalConfigChanged将在服务协程中回调
+func (s *Service) NotifyGlobalConfigChanged(oldCfg interface{}, newCfg interface{}) {
+	ev := event.NewEvent()
+	ev.Type = event.Sys_Event_GlobalConfigChanged
+	ev.AnyExt[0] = oldCfg
+	ev.AnyExt[1] = newCfg
+
+	s.pushEvent(ev)
+}
+
 func (s *Service) Init(iService IService, getClientFun rpc.FuncRpcClient, getServerFun rpc.FuncRpcServer, serviceCfg interface{}) {
 	s.closeSig = make(chan struct{})
 	s.dispatcher = timer.NewDispatcher(timerDispatcherLen)
-	if s.chanEvent == nil {
-		s.chanEvent = make(chan event.IEvent, maxServiceEventChannelNum)
+	if s.eventLanes.isInit() == false {
+		s.eventLanes.init(maxServiceEventChannelNum)
 	}
+	s.overload.init(s.eventLanes.totalCapacity)
 
 	s.rpcHandler.InitRpcHandler(iService.(rpc.IRpcHandler), getClientFun, getServerFun, iService.(rpc.IRpcHandlerChannel))
 	s.IRpcHandler = &s.rpcHandler
@@ -145,7 +196,9 @@ func (s *Service) Start() {
 	atomic.StoreInt32(&s.isRelease, 0)
 	var waitRun sync.WaitGroup
 	log.Info(s.GetName() + " service is running")
+	s.restoreCheckpoint()
 	s.self.(IService).OnStart()
+	s.startCheckpointTicker()
 
 	for i := int32(0); i < s.goroutineNum; i++ {
 		s.wg.Add(1)
@@ -165,78 +218,36 @@ func (s *Service) run() {
 
 	cr := s.IConcurrent.(*concurrent.Concurrent)
 	concurrentCBChannel := cr.GetCallBackChannel()
+	goid := profiler.GetGoroutineId()
 
 	for {
 		var analyzer *profiler.Analyzer
 		select {
 		case <-s.closeSig:
 			bStop = true
+			s.partition.close()
 			s.Release()
 			cr.Close()
 		case cb := <-concurrentCBChannel:
 			cr.DoCallback(cb)
-		case ev := <-s.chanEvent:
-			switch ev.GetEventType() {
-			case event.Sys_Event_Retire:
-				log.Info("service OnRetire", log.String("serviceName", s.GetName()))
-				s.self.(IService).OnRetire()
-			case event.ServiceRpcRequestEvent:
-				cEvent, ok := ev.(*event.Event)
-				if ok == false {
-					log.Error("Type event conversion error")
-					break
-				}
-				rpcRequest, ok := cEvent.Data.(*rpc.RpcRequest)
-				if ok == false {
-					log.Error("Type *rpc.RpcRequest conversion error")
-					break
-				}
-				if s.profiler != nil {
-					analyzer = s.profiler.Push("[RpcReq]" + rpcRequest.RpcRequestData.GetServiceMethod()+"."+strconv.Itoa(int(rpcRequest.RpcRequestData.GetRpcMethodId())))
-				}
-
-				s.GetRpcHandler().HandlerRpcRequest(rpcRequest)
-				if analyzer != nil {
-					analyzer.Pop()
-					analyzer = nil
-				}
-				event.DeleteEvent(cEvent)
-			case event.ServiceRpcResponseEvent:
-				cEvent, ok := ev.(*event.Event)
-				if ok == false {
-					log.Error("Type event conversion error")
-					break
-				}
-				rpcResponseCB, ok := cEvent.Data.(*rpc.Call)
-				if ok == false {
-					log.Error("Type *rpc.Call conversion error")
-					break
-				}
-				if s.profiler != nil {
-					analyzer = s.profiler.Push("[Res]" + rpcResponseCB.ServiceMethod)
-				}
-				s.GetRpcHandler().HandlerRpcResponseCB(rpcResponseCB)
-				if analyzer != nil {
-					analyzer.Pop()
-					analyzer = nil
-				}
-				event.DeleteEvent(cEvent)
-			default:
-				if s.profiler != nil {
-					analyzer = s.profiler.Push("[SEvent]" + strconv.Itoa(int(ev.GetEventType())))
-				}
-				s.eventProcessor.EventHandler(ev)
-				if analyzer != nil {
-					analyzer.Pop()
-					analyzer = nil
-				}
+		case <-s.eventLanes.signal:
+			ev, pushTime := s.eventLanes.popWithTime()
+			s.checkLowWatermark()
+			bPartition, err := s.partition.dispatch(ev, pushTime)
+			if err != nil {
+				s.rejectEvent(ev, err)
+			} else if bPartition == false {
+				s.processEvent(ev, pushTime, goid)
 			}
-
 		case t := <-s.dispatcher.ChanTimer:
 			if s.profiler != nil {
-				analyzer = s.profiler.Push("[timer]" + t.GetName())
+				analyzer = s.profiler.PushWaitGoroutine("[timer]"+t.GetName(), getTimerWait(t), goid)
+			}
+			if s.isLogContextEnabled() == true {
+				s.logContext.Set(log.String("timer", t.GetName()))
 			}
 			t.Do()
+			s.logContext.Clear()
 			if analyzer != nil {
 				analyzer.Pop()
 				analyzer = nil
@@ -249,6 +260,173 @@ func (s *Service) run() {
 	}
 }
 
+// rejectEvent 事件无法处理时丢弃，Rpc请求直接返回错误
+func (s *Service) rejectEvent(ev event.IEvent, err error) {
+	log.Error("event is rejected", log.String("serviceName", s.GetName()), log.Int("eventType", int(ev.GetEventType())), log.ErrorField("err", err))
+	if rpcRequest := GetRpcRequest(ev); rpcRequest != nil {
+		rpcRequest.ReplyError(rpc.RpcError(err.Error()))
+		event.DeleteEvent(ev)
+	}
+}
+
+// getEventWait 事件在队列中的等待时间
+func getEventWait(pushTime int64) time.Duration {
+	if pushTime == 0 {
+		return 0
+	}
+
+	return max(time.Duration(time.Now().UnixNano()-pushTime), 0)
+}
+
+// getTimerWait 定时器从到期到开始执行的时间
+func getTimerWait(t timer.ITimer) time.Duration {
+	return max(timer.Now().Sub(t.GetFireTime()), 0)
+}
+
+// isLogContextEnabled 开启分区或多协程时多个协程共用服务日志，不设置日志上下文
+func (s *Service) isLogContextEnabled() bool {
+	return s.partition == nil && s.goroutineNum == 1
+}
+
+// rpcLogContext 处理Rpc请求时的日志上下文，写日志时才从请求生成字段
+type rpcLogContext rpc.RpcRequest
+
+// AppendLogFields 请求方法名，请求携带调用链信息时附加TraceId与调用方结点
+func (ctx *rpcLogContext) AppendLogFields(fields []zap.Field) []zap.Field {
+	requestData := ctx.RpcRequestData
+	fields = append(fields, log.String("rpcMethod", requestData.GetServiceMethod()))
+	if rawRpcId := requestData.GetRpcMethodId(); rawRpcId > 0 {
+		fields = append(fields, log.Uint32("rpcMethodId", rawRpcId))
+	}
+
+	meta, ok := requestData.(rpc.IRpcRequestMeta)
+	if ok == false {
+		return fields
+	}
+
+	if traceId := meta.GetTraceId(); traceId != "" {
+		fields = append(fields, log.String("traceId", traceId))
+	}
+	if callerNodeId := meta.GetCallerNodeId(); callerNodeId != "" {
+		fields = append(fields, log.String("callerNodeId", callerNodeId))
+	}
+
+	return fields
+}
+
+// getTraceId 请求携带的TraceId
+func getTraceId(rpcRequest *rpc.RpcRequest) string {
+	if meta, ok := rpcRequest.RpcRequestData.(rpc.IRpcRequestMeta); ok == true {
+		return meta.GetTraceId()
+	}
+
+	return ""
+}
+
+// processEvent 处理事件，开启分区时可能在分区协程中执行，pushTime为事件投递时间，goid为执行协程的Id
+func (s *Service) processEvent(ev event.IEvent, pushTime int64, goid uint64) {
+	var analyzer *profiler.Analyzer
+	bLogContext := s.isLogContextEnabled()
+	if bLogContext == true {
+		defer s.logContext.Clear()
+	}
+
+	switch ev.GetEventType() {
+	case event.Sys_Event_Retire:
+		log.Info("service OnRetire", log.String("serviceName", s.GetName()))
+		s.self.(IService).OnRetire()
+	case event.Sys_Event_Drain:
+		log.Info("service OnDrain", log.String("serviceName", s.GetName()))
+		s.self.(IService).OnDrain()
+	case event.Sys_Event_ServiceConfigChanged:
+		cEvent, ok := ev.(*event.Event)
+		if ok == false {
+			log.Error("Type event conversion error")
+			break
+		}
+		cb, _ := cEvent.AnyExt[1].(func(err error))
+		s.onServiceCfgChanged(cEvent.AnyExt[0], cb)
+	case event.Sys_Event_GlobalConfigChanged:
+		cEvent, ok := ev.(*event.Event)
+		if ok == false {
+			log.Error("Type event conversion error")
+			break
+		}
+		s.self.(IService).OnGlobalConfigChanged(cEvent.AnyExt[0], cEvent.AnyExt[1])
+	case event.ServiceRpcRequestEvent:
+		cEvent, ok := ev.(*event.Event)
+		if ok == false {
+			log.Error("Type event conversion error")
+			break
+		}
+		rpcRequest, ok := cEvent.Data.(*rpc.RpcRequest)
+		if ok == false {
+			log.Error("Type *rpc.RpcRequest conversion error")
+			break
+		}
+		//处理期间在本协程中发送的请求沿用该请求的TraceId，分区协程各自设置
+		traceId := getTraceId(rpcRequest)
+		if traceId != "" {
+			s.rpcHandler.SetTraceId(traceId)
+		}
+		if bLogContext == true {
+			s.logContext.SetSource((*rpcLogContext)(rpcRequest))
+		}
+		if s.profiler != nil {
+			analyzer = s.profiler.PushWaitGoroutine("[RpcReq]"+rpcRequest.RpcRequestData.GetServiceMethod()+"."+strconv.Itoa(int(rpcRequest.RpcRequestData.GetRpcMethodId())), getEventWait(pushTime), goid)
+		}
+
+		s.GetRpcHandler().HandlerRpcRequest(rpcRequest)
+		//返回后请求可能已被回收
+		if bLogContext == true {
+			s.logContext.Clear()
+		}
+		if traceId != "" {
+			s.rpcHandler.SetTraceId("")
+		}
+		if analyzer != nil {
+			analyzer.Pop()
+			analyzer = nil
+		}
+		event.DeleteEvent(cEvent)
+	case event.ServiceRpcResponseEvent:
+		cEvent, ok := ev.(*event.Event)
+		if ok == false {
+			log.Error("Type event conversion error")
+			break
+		}
+		rpcResponseCB, ok := cEvent.Data.(*rpc.Call)
+		if ok == false {
+			log.Error("Type *rpc.Call conversion error")
+			break
+		}
+		if bLogContext == true {
+			s.logContext.Set(log.String("rpcResponse", rpcResponseCB.ServiceMethod))
+		}
+		if s.profiler != nil {
+			analyzer = s.profiler.PushWaitGoroutine("[Res]"+rpcResponseCB.ServiceMethod, getEventWait(pushTime), goid)
+		}
+		s.GetRpcHandler().HandlerRpcResponseCB(rpcResponseCB)
+		if analyzer != nil {
+			analyzer.Pop()
+			analyzer = nil
+		}
+		event.DeleteEvent(cEvent)
+	default:
+		if bLogContext == true {
+			s.logContext.Set(log.Int("eventType", int(ev.GetEventType())))
+		}
+		if s.profiler != nil {
+			analyzer = s.profiler.PushWaitGoroutine("[SEvent]"+strconv.Itoa(int(ev.GetEventType())), getEventWait(pushTime), goid)
+		}
+		s.eventProcessor.EventHandler(ev)
+		if analyzer != nil {
+			analyzer.Pop()
+			analyzer = nil
+		}
+	}
+}
+
 func (s *Service) GetName() string {
 	return s.name
 }
@@ -265,6 +443,7 @@ func (s *Service) Release() {
 	}()
 
 	if atomic.AddInt32(&s.isRelease, -1) == -1 {
+		s.saveCheckpoint()
 		s.self.OnRelease()
 		for i:=len(s.child)-1; i>=0; i-- {
 			s.ReleaseModule(s.child[i].GetModuleId())
@@ -287,20 +466,24 @@ func (s *Service) Stop() {
 }
 
 func (s *Service) GetServiceCfg() interface{} {
+	s.serviceCfgLocker.RLock()
+	defer s.serviceCfgLocker.RUnlock()
+
 	return s.serviceCfg
 }
 
 func (s *Service) ParseServiceCfg(cfg interface{}) error {
-	if s.serviceCfg == nil {
+	serviceCfg := s.GetServiceCfg()
+	if serviceCfg == nil {
 		return errors.New("no service configuration found")
 	}
 
-	rv := reflect.ValueOf(s.serviceCfg)
+	rv := reflect.ValueOf(serviceCfg)
 	if rv.Kind() == reflect.Ptr && rv.IsNil() {
 		return errors.New("no service configuration found")
 	}
 
-	bytes, err := json.Marshal(s.serviceCfg)
+	bytes, err := json.Marshal(serviceCfg)
 	if err != nil {
 		return err
 	}
@@ -388,6 +571,10 @@ func (s *Service) UnRegDiscoverListener() {
 }
 
 func (s *Service) PushRpcRequest(rpcRequest *rpc.RpcRequest) error {
+	if s.IsOverloaded() == true {
+		return ErrServiceOverloaded
+	}
+
 	ev := event.NewEvent()
 	ev.Type = event.ServiceRpcRequestEvent
 	ev.Data = rpcRequest
@@ -408,30 +595,27 @@ func (s *Service) PushEvent(ev event.IEvent) error {
 }
 
 func (s *Service) pushEvent(ev event.IEvent) error {
-	if len(s.chanEvent) >= maxServiceEventChannelNum {
-		err := errors.New("the event channel in the service is full")
-		log.Error(err.Error())
+	err := s.eventLanes.push(ev)
+	if err != nil {
+		log.Error(err.Error(), log.String("serviceName", s.GetName()))
 		return err
 	}
 
-	s.chanEvent <- ev
+	s.checkHighWatermark()
 	return nil
 }
 
 func (s *Service) GetServiceEventChannelNum() int {
-	return len(s.chanEvent)
+	return s.eventLanes.len()
 }
 
 func (s *Service) GetServiceTimerChannelNum() int {
 	return len(s.dispatcher.ChanTimer)
 }
 
+// SetEventChannelNum 设置服务的事件容量，非系统通道共享该容量，单个通道默认也可以用满该容量
 func (s *Service) SetEventChannelNum(num int) {
-	if s.chanEvent == nil {
-		s.chanEvent = make(chan event.IEvent, num)
-	} else {
-		panic("this stage cannot be set")
-	}
+	s.eventLanes.setTotalCapacity(num)
 }
 
 // Deprecated: replace it with the OpenConcurrent function
@@ -442,9 +626,17 @@ func (s *Service) SetGoRoutineNum(goroutineNum int32) bool {
 		return false
 	}
 
+	if s.partition != nil {
+		log.Error("open partition mode is not allowed to set Multi-coroutine.")
+		return false
+	}
+
 	s.goroutineNum = goroutineNum
 	return true
 }
 
 func (s *Service) OnRetire() {
 }
+
+func (s *Service) OnGlobalConfigChanged(oldCfg interface{}, newCfg interface{}) {
+}
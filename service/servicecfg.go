@@ -0,0 +1,49 @@
+package service
+
+import (
+	"github.com/duanhf2012/origin/v2/event"
+	"github.com/duanhf2012/origin/v2/log"
+)
+
+// NotifyServiceCfgChanged 投递服务配置变化事件，OnConfigChanged将在服务协程中回调，cb返回是否接受新配置
+func (s *Service) NotifyServiceCfgChanged(newCfg interface{}, cb func(err error)) {
+	ev := event.NewEvent()
+	ev.Type = event.Sys_Event_ServiceConfigChanged
+	ev.AnyExt[0] = newCfg
+	ev.AnyExt[1] = cb
+
+	s.pushEvent(ev)
+}
+
+// OnConfigChanged 服务配置变化回调，返回错误时拒绝新配置，默认接受
+// 回调返回后GetServiceCfg与ParseServiceCfg将使用新配置
+func (s *Service) OnConfigChanged(newCfg interface{}) error {
+	return nil
+}
+
+// GetServiceCfgVersion 获取当前生效的服务配置版本号，每次接受新配置时加1
+func (s *Service) GetServiceCfgVersion() uint64 {
+	s.serviceCfgLocker.RLock()
+	defer s.serviceCfgLocker.RUnlock()
+
+	return s.serviceCfgVersion
+}
+
+func (s *Service) onServiceCfgChanged(newCfg interface{}, cb func(err error)) {
+	err := s.self.(IService).OnConfigChanged(newCfg)
+	if err != nil {
+		log.Warn("service rejected the config change", log.String("serviceName", s.GetName()), log.Uint64("version", s.GetServiceCfgVersion()), log.ErrorField("err", err))
+	} else {
+		s.serviceCfgLocker.Lock()
+		s.serviceCfg = newCfg
+		s.serviceCfgVersion++
+		version := s.serviceCfgVersion
+		s.serviceCfgLocker.Unlock()
+
+		log.Info("service config has changed", log.String("serviceName", s.GetName()), log.Uint64("version", version))
+	}
+
+	if cb != nil {
+		cb(err)
+	}
+}
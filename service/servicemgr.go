@@ -2,12 +2,15 @@ package service
 
 import (
 	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/profiler"
 	"os"
+	"sync"
 )
 
 //本地所有的service
 var mapServiceName map[string]IService
 var setupServiceList []IService
+var serviceLocker sync.RWMutex
 
 type RegRpcEventFunType func(serviceName string)
 type RegDiscoveryServiceEventFunType func(serviceName string)
@@ -19,8 +22,15 @@ func init(){
 	setupServiceList = []IService{}
 }
 
+func getServiceList() []IService {
+	serviceLocker.RLock()
+	defer serviceLocker.RUnlock()
+
+	return append([]IService{}, setupServiceList...)
+}
+
 func Init() {
-	for _,s := range setupServiceList {
+	for _,s := range getServiceList() {
 		err := s.OnInit()
 		if err != nil {
 			log.Error("Failed to initialize "+s.GetName()+" service",log.ErrorField("err",err))
@@ -30,6 +40,9 @@ func Init() {
 }
 
 func Setup(s IService) bool {
+	serviceLocker.Lock()
+	defer serviceLocker.Unlock()
+
 	_,ok := mapServiceName[s.GetName()]
 	if ok == true {
 		return false
@@ -40,7 +53,38 @@ func Setup(s IService) bool {
 	return true
 }
 
+// Remove 移除已经停止的服务，用于运行时释放的服务
+func Remove(serviceName string) IService {
+	serviceLocker.Lock()
+	s,ok := mapServiceName[serviceName]
+	if ok == false {
+		serviceLocker.Unlock()
+		return nil
+	}
+
+	delete(mapServiceName, serviceName)
+	for i := 0; i < len(setupServiceList); i++ {
+		if setupServiceList[i] == s {
+			setupServiceList = append(setupServiceList[:i:i], setupServiceList[i+1:]...)
+			break
+		}
+	}
+	serviceLocker.Unlock()
+
+	if UnRegRpcEventFun != nil {
+		UnRegRpcEventFun(serviceName)
+	}
+	if s.GetProfiler() != nil {
+		profiler.UnRegProfiler(serviceName)
+	}
+
+	return s
+}
+
 func GetService(serviceName string) IService {
+	serviceLocker.RLock()
+	defer serviceLocker.RUnlock()
+
 	s,ok := mapServiceName[serviceName]
 	if ok == false {
 		return nil
@@ -49,20 +93,42 @@ func GetService(serviceName string) IService {
 	return s
 }
 
+// Start 按依赖顺序启动服务，依赖未就绪的服务等待就绪或超时后启动
 func Start(){
-	for _,s := range setupServiceList {
-		s.Start()
+	serviceList, err := sortByDepend(getServiceList())
+	if err != nil {
+		log.Error("Failed to sort services by dependency", log.ErrorField("err", err))
+		os.Exit(1)
 	}
+
+	serviceActivator.start(serviceList)
 }
 
+// StopAllService 按依赖的逆序停止服务
 func StopAllService(){
-	for i := len(setupServiceList) - 1; i >= 0; i-- {
-		setupServiceList[i].Stop()
+	serviceActivator.stop()
+
+	serviceList, err := sortByDepend(getServiceList())
+	if err != nil {
+		serviceList = getServiceList()
+	}
+
+	for i := len(serviceList) - 1; i >= 0; i-- {
+		serviceList[i].Stop()
 	}
 }
 
 func NotifyAllServiceRetire(){
-	for i := len(setupServiceList) - 1; i >= 0; i-- {
-		setupServiceList[i].SetRetire()
+	serviceList := getServiceList()
+	for i := len(serviceList) - 1; i >= 0; i-- {
+		serviceList[i].SetRetire()
+	}
+}
+
+// NotifyAllServiceGlobalConfigChanged 通知所有服务全局配置发生变化
+func NotifyAllServiceGlobalConfigChanged(oldCfg interface{}, newCfg interface{}){
+	serviceList := getServiceList()
+	for _, s := range serviceList {
+		s.NotifyGlobalConfigChanged(oldCfg, newCfg)
 	}
-}
\ No newline at end of file
+}
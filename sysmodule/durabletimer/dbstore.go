@@ -0,0 +1,153 @@
+package durabletimer
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/sysmodule/mongodbmodule"
+	"github.com/duanhf2012/origin/v2/sysmodule/mysqlmodule"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoStore MongoDB存储，以(Owner,Name)为主键
+type MongoStore struct {
+	mongo      *mongodbmodule.MongoModule
+	db         string
+	collection string
+}
+
+type mongoTimerDoc struct {
+	Id       string    `bson:"_id"`
+	Owner    string    `bson:"Owner"`
+	Name     string    `bson:"Name"`
+	Kind     string    `bson:"Kind"`
+	FireTime time.Time `bson:"FireTime"`
+	CronExpr string    `bson:"CronExpr"`
+	Payload  []byte    `bson:"Payload"`
+}
+
+// NewMongoStore mm需要已经Start，建议对Owner字段建立索引
+func NewMongoStore(mm *mongodbmodule.MongoModule, db string, collection string) *MongoStore {
+	return &MongoStore{mongo: mm, db: db, collection: collection}
+}
+
+func getMongoId(owner string, name string) string {
+	return owner + "/" + name
+}
+
+func (ms *MongoStore) Save(owner string, record *TimerRecord) error {
+	s := ms.mongo.TakeSession()
+	ctxTimeout, cancel := s.GetDefaultContext()
+	defer cancel()
+
+	doc := mongoTimerDoc{
+		Id:       getMongoId(owner, record.Name),
+		Owner:    owner,
+		Name:     record.Name,
+		Kind:     record.Kind,
+		FireTime: record.FireTime,
+		CronExpr: record.CronExpr,
+		Payload:  record.Payload,
+	}
+	_, err := s.Collection(ms.db, ms.collection).ReplaceOne(ctxTimeout, bson.M{"_id": doc.Id}, &doc, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (ms *MongoStore) Delete(owner string, name string) error {
+	s := ms.mongo.TakeSession()
+	ctxTimeout, cancel := s.GetDefaultContext()
+	defer cancel()
+
+	_, err := s.Collection(ms.db, ms.collection).DeleteOne(ctxTimeout, bson.M{"_id": getMongoId(owner, name)})
+	return err
+}
+
+func (ms *MongoStore) LoadAll(owner string) ([]*TimerRecord, error) {
+	s := ms.mongo.TakeSession()
+	ctxTimeout, cancel := s.GetDefaultContext()
+	defer cancel()
+
+	cursor, err := s.Collection(ms.db, ms.collection).Find(ctxTimeout, bson.M{"Owner": owner})
+	if err != nil {
+		return nil, err
+	}
+
+	var docList []mongoTimerDoc
+	if err = cursor.All(ctxTimeout, &docList); err != nil {
+		return nil, err
+	}
+
+	recordList := make([]*TimerRecord, 0, len(docList))
+	for _, doc := range docList {
+		recordList = append(recordList, &TimerRecord{Name: doc.Name, Kind: doc.Kind, FireTime: doc.FireTime, CronExpr: doc.CronExpr, Payload: doc.Payload})
+	}
+
+	return recordList, nil
+}
+
+// MySQLStore MySQL存储，mysqlmodule会拒绝包含特殊字符的字符串参数，所以各字段以十六进制保存
+// 建表语句：CREATE TABLE durable_timer (owner VARCHAR(256) NOT NULL, name VARCHAR(512) NOT NULL, data MEDIUMTEXT NOT NULL, PRIMARY KEY (owner, name))
+type MySQLStore struct {
+	mysql *mysqlmodule.MySQLModule
+	table string
+}
+
+type mysqlTimerRow struct {
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+	Data  string `json:"data"`
+}
+
+func NewMySQLStore(mysql *mysqlmodule.MySQLModule, table string) *MySQLStore {
+	return &MySQLStore{mysql: mysql, table: table}
+}
+
+func (ms *MySQLStore) Save(owner string, record *TimerRecord) error {
+	byteData, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	strSql := fmt.Sprintf("REPLACE INTO %s (owner,name,data) VALUES(?,?,?)", ms.table)
+	_, err = ms.mysql.Exec(strSql, hex.EncodeToString([]byte(owner)), hex.EncodeToString([]byte(record.Name)), hex.EncodeToString(byteData))
+	return err
+}
+
+func (ms *MySQLStore) Delete(owner string, name string) error {
+	strSql := fmt.Sprintf("DELETE FROM %s WHERE owner=? AND name=?", ms.table)
+	_, err := ms.mysql.Exec(strSql, hex.EncodeToString([]byte(owner)), hex.EncodeToString([]byte(name)))
+	return err
+}
+
+func (ms *MySQLStore) LoadAll(owner string) ([]*TimerRecord, error) {
+	strSql := fmt.Sprintf("SELECT owner,name,data FROM %s WHERE owner=?", ms.table)
+	dataSet, err := ms.mysql.Query(strSql, hex.EncodeToString([]byte(owner)))
+	if err != nil {
+		return nil, err
+	}
+
+	var rowList []mysqlTimerRow
+	if err = dataSet.UnMarshal(&rowList); err != nil {
+		return nil, err
+	}
+
+	recordList := make([]*TimerRecord, 0, len(rowList))
+	for _, row := range rowList {
+		byteData, err := hex.DecodeString(row.Data)
+		if err != nil {
+			return nil, errors.New("invalid timer data of " + row.Name)
+		}
+
+		var record TimerRecord
+		if err = json.Unmarshal(byteData, &record); err != nil {
+			return nil, err
+		}
+		recordList = append(recordList, &record)
+	}
+
+	return recordList, nil
+}
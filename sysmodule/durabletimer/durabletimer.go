@@ -0,0 +1,304 @@
+package durabletimer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/cluster"
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/service"
+	"github.com/duanhf2012/origin/v2/util/timer"
+)
+
+// 存储写入队列长度，队列满时标记为待写入，定时重试
+var storeQueueLen = 10000
+
+const storeRetryInterval = time.Second
+
+// TimerHandler 定时器回调，在所属服务协程中执行
+type TimerHandler func(name string, payload []byte)
+
+// DurableTimerModule 持久化定时器模块，进程重启后从存储中恢复，停止期间已经到期的定时器在服务启动后触发一次
+// 使用时先NewDurableTimerModule，RegHandler注册所有类型的回调后再AddModule，初始化时加载定时器
+// 存储的写入在独立协程中按顺序执行，写入队列满时标记为待写入，之后定时重试最后一次写入，释放时全部写入
+// 到期的一次性定时器在回调后删除，回调前进程退出时重启后会再次触发
+type DurableTimerModule struct {
+	service.Module
+
+	store      IStore
+	owner      string
+	mapHandler map[string]TimerHandler
+	mapTimer   map[string]*durableTimer
+
+	chanOp        chan storeOp
+	wg            sync.WaitGroup
+	mapDirty      map[string]storeOp //写入队列满时未能写入的定时器，只保留最后一次写入
+	retryTickerId uint64
+}
+
+type durableTimer struct {
+	record   TimerRecord
+	timerId  uint64
+	cronExpr *timer.CronExpr
+}
+
+type storeOp struct {
+	record *TimerRecord //为nil时删除
+	name   string
+}
+
+func NewDurableTimerModule(store IStore) *DurableTimerModule {
+	return &DurableTimerModule{store: store, mapHandler: map[string]TimerHandler{}}
+}
+
+// SetOwner 设置存储中的所有者，默认为结点Id_服务名，需要在AddModule前调用
+func (dm *DurableTimerModule) SetOwner(owner string) {
+	dm.owner = owner
+}
+
+// RegHandler 注册定时器类型的回调，需要在AddModule前注册加载的定时器所用的类型
+func (dm *DurableTimerModule) RegHandler(kind string, handler TimerHandler) {
+	dm.mapHandler[kind] = handler
+}
+
+func (dm *DurableTimerModule) OnInit() error {
+	if dm.store == nil {
+		return errors.New("durable timer store is nil")
+	}
+
+	if dm.owner == "" {
+		dm.owner = cluster.GetCluster().GetLocalNodeInfo().NodeId + "_" + dm.GetService().GetName()
+	}
+
+	recordList, err := dm.store.LoadAll(dm.owner)
+	if err != nil {
+		return fmt.Errorf("load durable timer fail: %w", err)
+	}
+
+	dm.mapTimer = make(map[string]*durableTimer, len(recordList))
+	dm.mapDirty = map[string]storeOp{}
+	dm.chanOp = make(chan storeOp, storeQueueLen)
+	dm.wg.Add(1)
+	go dm.runStore(dm.chanOp)
+	dm.SafeNewTicker(&dm.retryTickerId, storeRetryInterval, nil, dm.onRetryTicker)
+
+	for _, record := range recordList {
+		dt := &durableTimer{record: *record}
+		if record.CronExpr != "" {
+			dt.cronExpr, err = timer.NewCronExpr(record.CronExpr)
+			if err != nil {
+				log.Error("invalid durable timer cron expr", log.String("owner", dm.owner), log.String("name", record.Name), log.ErrorField("err", err))
+				continue
+			}
+		}
+
+		dm.mapTimer[record.Name] = dt
+		dm.schedule(dt)
+	}
+
+	log.Info("durable timer has been loaded", log.String("owner", dm.owner), log.Int("timerNum", len(dm.mapTimer)))
+	return nil
+}
+
+// OnRelease 写入所有待写入的定时器并等待存储写入完成，之后的修改不再写入存储
+func (dm *DurableTimerModule) OnRelease() {
+	if dm.chanOp == nil {
+		return
+	}
+
+	for _, op := range dm.mapDirty {
+		dm.chanOp <- op
+	}
+	dm.mapDirty = nil
+
+	close(dm.chanOp)
+	dm.chanOp = nil
+	dm.wg.Wait()
+}
+
+// AfterFunc 创建d时间后触发的定时器，同名定时器已存在时替换
+func (dm *DurableTimerModule) AfterFunc(kind string, name string, d time.Duration, payload []byte) {
+	dm.At(kind, name, timer.Now().Add(d), payload)
+}
+
+// At 创建在fireTime触发的定时器，同名定时器已存在时替换
+func (dm *DurableTimerModule) At(kind string, name string, fireTime time.Time, payload []byte) {
+	dm.add(&durableTimer{record: TimerRecord{Name: name, Kind: kind, FireTime: fireTime, Payload: payload}})
+}
+
+// CronFunc 创建Cron定时器，同名定时器已存在时替换
+func (dm *DurableTimerModule) CronFunc(kind string, name string, cronExpr string, payload []byte) error {
+	expr, err := timer.NewCronExpr(cronExpr)
+	if err != nil {
+		return err
+	}
+
+	nextTime := expr.Next(timer.Now())
+	if nextTime.IsZero() {
+		return fmt.Errorf("cron expr %s will never fire", cronExpr)
+	}
+
+	dm.add(&durableTimer{record: TimerRecord{Name: name, Kind: kind, FireTime: nextTime, CronExpr: cronExpr, Payload: payload}, cronExpr: expr})
+	return nil
+}
+
+// Cancel 取消定时器并从存储中删除
+func (dm *DurableTimerModule) Cancel(name string) bool {
+	dt, ok := dm.mapTimer[name]
+	if ok == false {
+		return false
+	}
+
+	dm.cancelTimer(dt)
+	delete(dm.mapTimer, name)
+	dm.pushOp(storeOp{name: name})
+	return true
+}
+
+// Reschedule 修改定时器的触发时间，Cron定时器在新的时间触发后按表达式继续
+func (dm *DurableTimerModule) Reschedule(name string, fireTime time.Time) bool {
+	dt, ok := dm.mapTimer[name]
+	if ok == false {
+		return false
+	}
+
+	dt.record.FireTime = fireTime
+	dm.schedule(dt)
+	dm.save(dt)
+	return true
+}
+
+// Get 获取定时器
+func (dm *DurableTimerModule) Get(name string) (TimerRecord, bool) {
+	dt, ok := dm.mapTimer[name]
+	if ok == false {
+		return TimerRecord{}, false
+	}
+
+	return dt.record, true
+}
+
+// GetTimerNum 获取定时器数量
+func (dm *DurableTimerModule) GetTimerNum() int {
+	return len(dm.mapTimer)
+}
+
+func (dm *DurableTimerModule) add(dt *durableTimer) {
+	if old, ok := dm.mapTimer[dt.record.Name]; ok == true {
+		dm.cancelTimer(old)
+	}
+
+	dm.mapTimer[dt.record.Name] = dt
+	dm.schedule(dt)
+	dm.save(dt)
+}
+
+// schedule 按触发时间创建内存定时器，已经到期的立即触发
+func (dm *DurableTimerModule) schedule(dt *durableTimer) {
+	d := dt.record.FireTime.Sub(timer.Now())
+	if d < 0 {
+		d = 0
+	}
+
+	dm.SafeAfterFunc(&dt.timerId, d, dt.record.Name, dm.onTimer)
+}
+
+func (dm *DurableTimerModule) cancelTimer(dt *durableTimer) {
+	if dt.timerId != 0 {
+		dm.CancelTimerId(&dt.timerId)
+	}
+}
+
+func (dm *DurableTimerModule) save(dt *durableTimer) {
+	record := dt.record
+	dm.pushOp(storeOp{record: &record, name: record.Name})
+}
+
+func (dm *DurableTimerModule) onTimer(timerId uint64, additionData interface{}) {
+	name, _ := additionData.(string)
+	dt, ok := dm.mapTimer[name]
+	if ok == false || dt.timerId != timerId {
+		return
+	}
+	dt.timerId = 0
+
+	handler, ok := dm.mapHandler[dt.record.Kind]
+	if ok == false {
+		//保留在存储中，注册回调后重启时再触发
+		log.Warn("durable timer handler is not registered", log.String("owner", dm.owner), log.String("name", name), log.String("kind", dt.record.Kind))
+		delete(dm.mapTimer, name)
+		return
+	}
+
+	payload := dt.record.Payload
+	if dt.cronExpr != nil {
+		nextTime := dt.cronExpr.Next(timer.Now())
+		if nextTime.IsZero() == false {
+			dt.record.FireTime = nextTime
+			dm.schedule(dt)
+			dm.save(dt)
+			handler(name, payload)
+			return
+		}
+	}
+
+	delete(dm.mapTimer, name)
+	handler(name, payload)
+
+	//回调中没有重新创建同名定时器时从存储中删除
+	if _, ok = dm.mapTimer[name]; ok == false {
+		dm.pushOp(storeOp{name: name})
+	}
+}
+
+// pushOp 不阻塞服务协程，写入队列已满时标记为待写入，释放后丢弃并记录日志
+func (dm *DurableTimerModule) pushOp(op storeOp) {
+	if dm.chanOp == nil {
+		log.Error("durable timer module is released, drop store op", log.String("owner", dm.owner), log.String("name", op.name), log.Bool("delete", op.record == nil))
+		return
+	}
+
+	//已经待写入时替换为最新的写入，由重试写入，保证同名定时器的写入顺序
+	if _, ok := dm.mapDirty[op.name]; ok == true {
+		dm.mapDirty[op.name] = op
+		return
+	}
+
+	select {
+	case dm.chanOp <- op:
+	default:
+		dm.mapDirty[op.name] = op
+		log.Warn("durable timer store queue is full, retry later", log.String("owner", dm.owner), log.String("name", op.name), log.Bool("delete", op.record == nil))
+	}
+}
+
+// onRetryTicker 重试待写入的定时器，队列仍然满时等待下次重试
+func (dm *DurableTimerModule) onRetryTicker(uint64, interface{}) {
+	for name, op := range dm.mapDirty {
+		select {
+		case dm.chanOp <- op:
+			delete(dm.mapDirty, name)
+		default:
+			return
+		}
+	}
+}
+
+func (dm *DurableTimerModule) runStore(chanOp chan storeOp) {
+	defer dm.wg.Done()
+
+	for op := range chanOp {
+		var err error
+		if op.record != nil {
+			err = dm.store.Save(dm.owner, op.record)
+		} else {
+			err = dm.store.Delete(dm.owner, op.name)
+		}
+
+		if err != nil {
+			log.Error("write durable timer fail", log.String("owner", dm.owner), log.String("name", op.name), log.Bool("delete", op.record == nil), log.ErrorField("err", err))
+		}
+	}
+}
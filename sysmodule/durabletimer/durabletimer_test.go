@@ -0,0 +1,280 @@
+package durabletimer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/service"
+	"github.com/duanhf2012/origin/v2/util/timer"
+)
+
+const testOwner = "node_1_TestService"
+
+type testService struct {
+	service.Service
+}
+
+type fireRecord struct {
+	name string
+	time time.Time
+}
+
+// startTestModule 在虚拟时钟下启动带有定时器模块的服务，停止服务后存储写入已完成
+func startTestModule(t *testing.T, store IStore, regHandler func(dm *DurableTimerModule)) (*DurableTimerModule, func()) {
+	svc := &testService{}
+	svc.SetName("TestService")
+	svc.Init(svc, nil, nil, nil)
+
+	dm := NewDurableTimerModule(store)
+	dm.SetOwner(testOwner)
+	regHandler(dm)
+	if _, err := svc.AddModule(dm); err != nil {
+		t.Fatal(err)
+	}
+	svc.Start()
+
+	bStop := false
+	stop := func() {
+		if bStop == false {
+			bStop = true
+			svc.Stop()
+		}
+	}
+	t.Cleanup(stop)
+	return dm, stop
+}
+
+func startVirtualClock(t *testing.T) time.Time {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	timer.EnableVirtualClock(start)
+	timer.StartTimer(time.Millisecond, 100)
+	t.Cleanup(timer.DisableVirtualClock)
+	return start
+}
+
+func loadRecord(t *testing.T, dir string) map[string]*TimerRecord {
+	recordList, err := NewFileStore(dir).LoadAll(testOwner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mapRecord := map[string]*TimerRecord{}
+	for _, record := range recordList {
+		mapRecord[record.Name] = record
+	}
+	return mapRecord
+}
+
+func TestDurableTimerReload(t *testing.T) {
+	start := startVirtualClock(t)
+	dir := t.TempDir()
+	store := NewFileStore(dir)
+	store.Save(testOwner, &TimerRecord{Name: "overdue", Kind: "Auction", FireTime: start.Add(-time.Hour), Payload: []byte("1")})
+	store.Save(testOwner, &TimerRecord{Name: "future", Kind: "Auction", FireTime: start.Add(2 * time.Hour), Payload: []byte("2")})
+
+	var fireList []fireRecord
+	dm, stop := startTestModule(t, NewFileStore(dir), func(dm *DurableTimerModule) {
+		dm.RegHandler("Auction", func(name string, payload []byte) {
+			fireList = append(fireList, fireRecord{name: name, time: timer.Now()})
+		})
+	})
+	if dm.GetTimerNum() != 2 {
+		t.Fatalf("unexpected timer num %d", dm.GetTimerNum())
+	}
+
+	//停止期间到期的定时器启动后立即触发一次
+	timer.Advance(time.Hour)
+	if len(fireList) != 1 || fireList[0].name != "overdue" || fireList[0].time.Equal(start) == false {
+		t.Fatalf("unexpected fire list %v", fireList)
+	}
+
+	timer.Advance(2 * time.Hour)
+	if len(fireList) != 2 || fireList[1].name != "future" || fireList[1].time.Equal(start.Add(2*time.Hour)) == false {
+		t.Fatalf("unexpected fire list %v", fireList)
+	}
+
+	//回调后从存储中删除
+	stop()
+	if mapRecord := loadRecord(t, dir); len(mapRecord) != 0 {
+		t.Fatalf("fired timers should be deleted from store, %v", mapRecord)
+	}
+}
+
+func TestDurableTimerCancelAndReschedule(t *testing.T) {
+	start := startVirtualClock(t)
+	dir := t.TempDir()
+
+	var fireList []fireRecord
+	dm, stop := startTestModule(t, NewFileStore(dir), func(dm *DurableTimerModule) {
+		dm.RegHandler("Mail", func(name string, payload []byte) {
+			fireList = append(fireList, fireRecord{name: name, time: timer.Now()})
+		})
+	})
+
+	dm.AfterFunc("Mail", "cancel", time.Hour, nil)
+	dm.AfterFunc("Mail", "reschedule", time.Hour, nil)
+	dm.AfterFunc("Mail", "keep", 5*time.Hour, nil)
+	if dm.Cancel("cancel") == false || dm.Cancel("cancel") == true {
+		t.Fatal("cancel should succeed only once")
+	}
+	if dm.Reschedule("reschedule", start.Add(3*time.Hour)) == false {
+		t.Fatal("reschedule fail")
+	}
+
+	timer.Advance(2 * time.Hour)
+	if len(fireList) != 0 {
+		t.Fatalf("unexpected fire list %v", fireList)
+	}
+
+	timer.Advance(time.Hour)
+	if len(fireList) != 1 || fireList[0].name != "reschedule" || fireList[0].time.Equal(start.Add(3*time.Hour)) == false {
+		t.Fatalf("unexpected fire list %v", fireList)
+	}
+
+	stop()
+	mapRecord := loadRecord(t, dir)
+	if len(mapRecord) != 1 || mapRecord["keep"] == nil {
+		t.Fatalf("unexpected store records %v", mapRecord)
+	}
+}
+
+func TestDurableTimerCron(t *testing.T) {
+	start := startVirtualClock(t)
+	dir := t.TempDir()
+
+	var fireList []fireRecord
+	dm, stop := startTestModule(t, NewFileStore(dir), func(dm *DurableTimerModule) {
+		dm.RegHandler("DailyReset", func(name string, payload []byte) {
+			fireList = append(fireList, fireRecord{name: name, time: timer.Now()})
+		})
+	})
+
+	if err := dm.CronFunc("DailyReset", "daily", "0 0 5 * * *", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	timer.Advance(2 * 24 * time.Hour)
+	if len(fireList) != 2 || fireList[0].time.Equal(start.Add(5*time.Hour)) == false || fireList[1].time.Equal(start.Add(29*time.Hour)) == false {
+		t.Fatalf("unexpected fire list %v", fireList)
+	}
+
+	//每次触发后重新计算下次触发时间并保存
+	record, ok := dm.Get("daily")
+	if ok == false || record.FireTime.Equal(start.Add(53*time.Hour)) == false {
+		t.Fatalf("unexpected cron record %+v", record)
+	}
+
+	stop()
+	mapRecord := loadRecord(t, dir)
+	if mapRecord["daily"] == nil || mapRecord["daily"].FireTime.Equal(start.Add(53*time.Hour)) == false {
+		t.Fatalf("unexpected store records %v", mapRecord)
+	}
+}
+
+func TestDurableTimerReleased(t *testing.T) {
+	startVirtualClock(t)
+	dir := t.TempDir()
+
+	dm, stop := startTestModule(t, NewFileStore(dir), func(dm *DurableTimerModule) {})
+	dm.AfterFunc("Mail", "mail", time.Hour, nil)
+	stop()
+
+	//释放后的修改丢弃，不会向已关闭的队列写入
+	if dm.Cancel("mail") == false {
+		t.Fatal("cancel fail")
+	}
+	if mapRecord := loadRecord(t, dir); len(mapRecord) != 1 {
+		t.Fatalf("unexpected store records %v", mapRecord)
+	}
+}
+
+// blockingStore 打开阻塞后写入等待释放，模拟存储变慢
+type blockingStore struct {
+	*FileStore
+	block   chan struct{}
+	blocked chan struct{}
+}
+
+func (bs *blockingStore) wait() {
+	select {
+	case <-bs.block:
+	default:
+		bs.blocked <- struct{}{}
+		<-bs.block
+	}
+}
+
+func (bs *blockingStore) Save(owner string, record *TimerRecord) error {
+	bs.wait()
+	return bs.FileStore.Save(owner, record)
+}
+
+func (bs *blockingStore) Delete(owner string, name string) error {
+	bs.wait()
+	return bs.FileStore.Delete(owner, name)
+}
+
+func TestDurableTimerStoreQueueFull(t *testing.T) {
+	storeQueueLen = 1
+	defer func() { storeQueueLen = 10000 }()
+
+	startVirtualClock(t)
+	dir := t.TempDir()
+	store := &blockingStore{FileStore: NewFileStore(dir), block: make(chan struct{}), blocked: make(chan struct{}, 1)}
+	dm, stop := startTestModule(t, store, func(dm *DurableTimerModule) {})
+
+	//第一个写入阻塞在存储中，第二个占满队列
+	dm.AfterFunc("Mail", "first", time.Hour, nil)
+	<-store.blocked
+	dm.AfterFunc("Mail", "second", time.Hour, nil)
+
+	//队列满时不丢弃，按最后一次修改重试
+	dm.AfterFunc("Mail", "cancel", time.Hour, nil)
+	dm.Cancel("cancel")
+	dm.AfterFunc("Mail", "keep", time.Hour, []byte("1"))
+	dm.AfterFunc("Mail", "keep", 2*time.Hour, []byte("2"))
+	if len(dm.mapDirty) != 2 {
+		t.Fatalf("unexpected dirty timers %v", dm.mapDirty)
+	}
+
+	close(store.block)
+	deadline := time.Now().Add(time.Second)
+	for len(dm.mapDirty) > 0 && time.Now().Before(deadline) {
+		timer.Advance(storeRetryInterval)
+		time.Sleep(time.Millisecond)
+	}
+	if len(dm.mapDirty) != 0 {
+		t.Fatalf("dirty timers are not retried %v", dm.mapDirty)
+	}
+
+	stop()
+	mapRecord := loadRecord(t, dir)
+	if len(mapRecord) != 3 || mapRecord["first"] == nil || mapRecord["second"] == nil || mapRecord["cancel"] != nil {
+		t.Fatalf("unexpected store records %v", mapRecord)
+	}
+	if string(mapRecord["keep"].Payload) != "2" {
+		t.Fatalf("unexpected keep record %+v", mapRecord["keep"])
+	}
+}
+
+func TestDurableTimerReleaseWritesDirty(t *testing.T) {
+	storeQueueLen = 1
+	defer func() { storeQueueLen = 10000 }()
+
+	startVirtualClock(t)
+	dir := t.TempDir()
+	store := &blockingStore{FileStore: NewFileStore(dir), block: make(chan struct{}), blocked: make(chan struct{}, 1)}
+	dm, stop := startTestModule(t, store, func(dm *DurableTimerModule) {})
+
+	dm.AfterFunc("Mail", "first", time.Hour, nil)
+	<-store.blocked
+	dm.AfterFunc("Mail", "second", time.Hour, nil)
+	dm.AfterFunc("Mail", "dirty", time.Hour, nil)
+
+	//释放时写入所有待写入的定时器
+	close(store.block)
+	stop()
+	if mapRecord := loadRecord(t, dir); len(mapRecord) != 3 || mapRecord["dirty"] == nil {
+		t.Fatalf("unexpected store records %v", mapRecord)
+	}
+}
@@ -0,0 +1,130 @@
+package durabletimer
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// TimerRecord 持久化的定时器
+type TimerRecord struct {
+	Name     string    //定时器名，同一所有者中唯一
+	Kind     string    //定时器类型，按类型查找回调
+	FireTime time.Time //下次触发时间
+	CronExpr string    //Cron表达式，为空时为一次性定时器
+	Payload  []byte    //附加数据
+}
+
+// IStore 定时器存储，在写入协程与模块初始化时调用
+type IStore interface {
+	Save(owner string, record *TimerRecord) error
+	Delete(owner string, name string) error
+	LoadAll(owner string) ([]*TimerRecord, error)
+}
+
+// FileStore 本地文件存储，每个所有者一个文件，每次修改重写整个文件，适合定时器数量较少的场景
+type FileStore struct {
+	dir string
+
+	locker      sync.Mutex
+	mapOwnerRec map[string]map[string]*TimerRecord
+}
+
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir, mapOwnerRec: map[string]map[string]*TimerRecord{}}
+}
+
+func (fs *FileStore) getFileName(owner string) string {
+	return filepath.Join(fs.dir, owner+".timer")
+}
+
+// load 需要在锁中调用
+func (fs *FileStore) load(owner string) (map[string]*TimerRecord, error) {
+	if mapRecord, ok := fs.mapOwnerRec[owner]; ok == true {
+		return mapRecord, nil
+	}
+
+	mapRecord := map[string]*TimerRecord{}
+	byteData, err := os.ReadFile(fs.getFileName(owner))
+	if err != nil && errors.Is(err, os.ErrNotExist) == false {
+		return nil, err
+	}
+
+	if len(byteData) > 0 {
+		if err = json.Unmarshal(byteData, &mapRecord); err != nil {
+			return nil, err
+		}
+	}
+
+	fs.mapOwnerRec[owner] = mapRecord
+	return mapRecord, nil
+}
+
+// flush 需要在锁中调用，先写临时文件再替换
+func (fs *FileStore) flush(owner string, mapRecord map[string]*TimerRecord) error {
+	byteData, err := json.Marshal(mapRecord)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(fs.dir, 0755); err != nil {
+		return err
+	}
+
+	fileName := fs.getFileName(owner)
+	if err = os.WriteFile(fileName+".tmp", byteData, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(fileName+".tmp", fileName)
+}
+
+func (fs *FileStore) Save(owner string, record *TimerRecord) error {
+	fs.locker.Lock()
+	defer fs.locker.Unlock()
+
+	mapRecord, err := fs.load(owner)
+	if err != nil {
+		return err
+	}
+
+	mapRecord[record.Name] = record
+	return fs.flush(owner, mapRecord)
+}
+
+func (fs *FileStore) Delete(owner string, name string) error {
+	fs.locker.Lock()
+	defer fs.locker.Unlock()
+
+	mapRecord, err := fs.load(owner)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := mapRecord[name]; ok == false {
+		return nil
+	}
+
+	delete(mapRecord, name)
+	return fs.flush(owner, mapRecord)
+}
+
+func (fs *FileStore) LoadAll(owner string) ([]*TimerRecord, error) {
+	fs.locker.Lock()
+	defer fs.locker.Unlock()
+
+	mapRecord, err := fs.load(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	recordList := make([]*TimerRecord, 0, len(mapRecord))
+	for _, record := range mapRecord {
+		recordList = append(recordList, record)
+	}
+
+	return recordList, nil
+}
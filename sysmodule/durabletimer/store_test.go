@@ -0,0 +1,44 @@
+package durabletimer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(dir)
+
+	fireTime := time.Now().Add(72 * time.Hour).Round(time.Second)
+	err := store.Save("node_1_AuctionService", &TimerRecord{Name: "auction_1", Kind: "AuctionEnd", FireTime: fireTime, Payload: []byte("1001")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Save("node_1_AuctionService", &TimerRecord{Name: "daily_reset", Kind: "DailyReset", CronExpr: "0 0 5 * * *"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Delete("node_1_AuctionService", "daily_reset"); err != nil {
+		t.Fatal(err)
+	}
+
+	//重新打开后从文件加载
+	recordList, err := NewFileStore(dir).LoadAll("node_1_AuctionService")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recordList) != 1 {
+		t.Fatalf("unexpected record num %d", len(recordList))
+	}
+
+	record := recordList[0]
+	if record.Name != "auction_1" || record.Kind != "AuctionEnd" || record.FireTime.Equal(fireTime) == false || string(record.Payload) != "1001" {
+		t.Fatalf("unexpected record %+v", record)
+	}
+
+	recordList, err = NewFileStore(dir).LoadAll("node_2_AuctionService")
+	if err != nil || len(recordList) != 0 {
+		t.Fatalf("unexpected records of other owner %v %v", recordList, err)
+	}
+}
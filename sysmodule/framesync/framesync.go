@@ -0,0 +1,106 @@
+package framesync
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/service"
+	"github.com/duanhf2012/origin/v2/sysmodule/frametimer"
+)
+
+// FrameSyncModule 帧同步房间管理，基于FrameTimer按帧率广播玩家输入
+// 使用时先AddModule FrameTimer，再AddModule NewFrameSyncModule(ft)，在网络模块的消息回调中调用PushInput与OnClientDisconnect
+type FrameSyncModule struct {
+	service.Module
+
+	ft        *frametimer.FrameTimer
+	mapRoom   map[string]*Room
+	mapClient map[string]clientRoom //clientId->所在房间
+}
+
+type clientRoom struct {
+	room     *Room
+	playerId string
+}
+
+func NewFrameSyncModule(ft *frametimer.FrameTimer) *FrameSyncModule {
+	return &FrameSyncModule{ft: ft}
+}
+
+func (fm *FrameSyncModule) OnInit() error {
+	if fm.ft == nil {
+		return errors.New("frame timer is nil")
+	}
+
+	fm.mapRoom = map[string]*Room{}
+	fm.mapClient = map[string]clientRoom{}
+	return nil
+}
+
+func (fm *FrameSyncModule) OnRelease() {
+	for roomId := range fm.mapRoom {
+		fm.CloseRoom(roomId)
+	}
+}
+
+// NewRoom 创建房间，添加玩家后调用Start开始广播
+func (fm *FrameSyncModule) NewRoom(roomId string, cfg RoomCfg, sender IFrameSender) (*Room, error) {
+	if _, ok := fm.mapRoom[roomId]; ok == true {
+		return nil, fmt.Errorf("frame sync room %s already exists", roomId)
+	}
+
+	if sender == nil {
+		return nil, errors.New("frame sender is nil")
+	}
+
+	room := newRoom(fm, roomId, cfg, sender)
+	if ftFps := fm.ft.GetFps(); ftFps > 0 && time.Second/time.Duration(room.cfg.Fps) < time.Second/time.Duration(ftFps) {
+		return nil, fmt.Errorf("room fps %d is greater than frame timer fps %d", room.cfg.Fps, ftFps)
+	}
+
+	fm.mapRoom[roomId] = room
+	return room, nil
+}
+
+// GetRoom 获取房间
+func (fm *FrameSyncModule) GetRoom(roomId string) *Room {
+	return fm.mapRoom[roomId]
+}
+
+// CloseRoom 关闭房间并回调输入记录
+func (fm *FrameSyncModule) CloseRoom(roomId string) {
+	room, ok := fm.mapRoom[roomId]
+	if ok == false {
+		return
+	}
+
+	delete(fm.mapRoom, roomId)
+	room.close()
+}
+
+// PushInput 收集客户端对frameId帧的输入
+func (fm *FrameSyncModule) PushInput(clientId string, frameId uint32, data []byte) error {
+	cr, ok := fm.mapClient[clientId]
+	if ok == false {
+		return fmt.Errorf("client %s is not in any frame sync room", clientId)
+	}
+
+	return cr.room.PushInput(cr.playerId, frameId, data)
+}
+
+// OnClientDisconnect 客户端断开连接
+func (fm *FrameSyncModule) OnClientDisconnect(clientId string) {
+	cr, ok := fm.mapClient[clientId]
+	if ok == false {
+		return
+	}
+
+	cr.room.OnDisconnect(cr.playerId)
+}
+
+func (fm *FrameSyncModule) removeClient(clientId string, room *Room) {
+	if cr, ok := fm.mapClient[clientId]; ok == true && cr.room == room {
+		delete(fm.mapClient, clientId)
+	}
+}
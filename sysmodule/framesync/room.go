@@ -0,0 +1,322 @@
+package framesync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/sysmodule/frametimer"
+	"github.com/duanhf2012/origin/v2/util/timer"
+)
+
+const (
+	defaultFps            = 15
+	defaultMaxAheadFrames = 30
+	defaultReplayBatch    = 100
+)
+
+// PlayerInput 玩家在某一帧的输入
+type PlayerInput struct {
+	PlayerId string
+	Data     []byte
+}
+
+// Frame 广播的一帧，FrameId从1开始
+type Frame struct {
+	FrameId   uint32
+	InputList []PlayerInput
+}
+
+// IFrameSender 帧数据发送接口，由使用者按网络模块与协议转换后发送
+type IFrameSender interface {
+	SendFrame(clientId string, frameList []*Frame) error
+}
+
+// FrameSenderFunc 以函数实现IFrameSender
+type FrameSenderFunc func(clientId string, frameList []*Frame) error
+
+func (f FrameSenderFunc) SendFrame(clientId string, frameList []*Frame) error {
+	return f(clientId, frameList)
+}
+
+// RoomCfg 房间配置
+type RoomCfg struct {
+	Fps            uint32               //逻辑帧率，不能超过FrameTimer的帧率，默认15
+	DelayFrames    uint32               //延迟广播的帧数，第N帧在第N+DelayFrames个逻辑帧时广播，期间到达的输入仍进入原帧
+	MaxAheadFrames uint32               //输入允许超前当前逻辑帧的帧数，默认30
+	ReplayBatch    int                  //断线重连补发时每次发送的帧数，默认100
+	OnClose        func(record *Record) //房间关闭时回调完整的输入记录
+}
+
+// Record 房间完整的输入记录，用于回放
+type Record struct {
+	RoomId     string
+	Fps        uint32
+	StartTime  time.Time
+	PlayerList []string
+	FrameList  []*Frame
+}
+
+// Save 以json格式写入
+func (r *Record) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// LoadRecord 读取Save写入的记录
+func LoadRecord(r io.Reader) (*Record, error) {
+	var record Record
+	if err := json.NewDecoder(r).Decode(&record); err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+type roomPlayer struct {
+	playerId    string
+	clientId    string
+	online      bool
+	sentFrameId uint32 //已发送给客户端的最后一帧
+}
+
+// Room 帧同步房间，所有方法需要在所属服务协程中调用
+type Room struct {
+	fm     *FrameSyncModule
+	roomId string
+	cfg    RoomCfg
+	sender IFrameSender
+
+	group   *frametimer.FrameGroup
+	timerId frametimer.FrameTimerID
+
+	startTime    time.Time
+	tickNum      uint32                   //已经过的逻辑帧数
+	frameList    []*Frame                 //已广播的帧，下标为FrameId-1
+	mapPending   map[uint32][]PlayerInput //未广播的帧的输入
+	mapPlayer    map[string]*roomPlayer
+	playerList   []string //按加入顺序，离开的玩家保留在记录中
+	lateInputNum int
+	closed       bool
+}
+
+func newRoom(fm *FrameSyncModule, roomId string, cfg RoomCfg, sender IFrameSender) *Room {
+	if cfg.Fps == 0 {
+		cfg.Fps = defaultFps
+	}
+	if cfg.MaxAheadFrames == 0 {
+		cfg.MaxAheadFrames = defaultMaxAheadFrames
+	}
+	if cfg.ReplayBatch <= 0 {
+		cfg.ReplayBatch = defaultReplayBatch
+	}
+
+	return &Room{
+		fm:         fm,
+		roomId:     roomId,
+		cfg:        cfg,
+		sender:     sender,
+		mapPending: map[uint32][]PlayerInput{},
+		mapPlayer:  map[string]*roomPlayer{},
+	}
+}
+
+// GetRoomId 获取房间Id
+func (r *Room) GetRoomId() string {
+	return r.roomId
+}
+
+// Start 开始按帧率广播
+func (r *Room) Start() {
+	if r.group != nil || r.closed == true {
+		return
+	}
+
+	r.startTime = timer.Now()
+	r.group = r.fm.ft.NewGroup()
+	r.group.FrameNewTicker(&r.timerId, time.Second/time.Duration(r.cfg.Fps), context.Background(), r.onTick)
+}
+
+// Pause 暂停广播
+func (r *Room) Pause() {
+	if r.group != nil {
+		r.group.Pause()
+	}
+}
+
+// Resume 恢复广播
+func (r *Room) Resume() {
+	if r.group != nil {
+		r.group.Resume()
+	}
+}
+
+// SetMultiple 设置倍速，允许范围1-5
+func (r *Room) SetMultiple(multiple uint8) error {
+	if r.group == nil {
+		return fmt.Errorf("room %s is not started", r.roomId)
+	}
+
+	return r.group.SetMultiple(multiple)
+}
+
+// GetFrameId 获取已广播的最后一帧
+func (r *Room) GetFrameId() uint32 {
+	return uint32(len(r.frameList))
+}
+
+// GetLateInputNum 获取晚到后并入后续帧的输入数
+func (r *Room) GetLateInputNum() int {
+	return r.lateInputNum
+}
+
+// AddPlayer 玩家加入房间
+func (r *Room) AddPlayer(playerId string, clientId string) error {
+	if _, ok := r.mapPlayer[playerId]; ok == true {
+		return fmt.Errorf("player %s is already in room %s", playerId, r.roomId)
+	}
+
+	r.mapPlayer[playerId] = &roomPlayer{playerId: playerId, clientId: clientId, online: true, sentFrameId: r.GetFrameId()}
+	if slices.Contains(r.playerList, playerId) == false {
+		r.playerList = append(r.playerList, playerId)
+	}
+	r.fm.mapClient[clientId] = clientRoom{room: r, playerId: playerId}
+	return nil
+}
+
+// RemovePlayer 玩家离开房间，已记录的输入保留
+func (r *Room) RemovePlayer(playerId string) {
+	p, ok := r.mapPlayer[playerId]
+	if ok == false {
+		return
+	}
+
+	delete(r.mapPlayer, playerId)
+	r.fm.removeClient(p.clientId, r)
+}
+
+// OnDisconnect 玩家断线，停止发送，房间继续运行
+func (r *Room) OnDisconnect(playerId string) {
+	p, ok := r.mapPlayer[playerId]
+	if ok == false {
+		return
+	}
+
+	p.online = false
+	r.fm.removeClient(p.clientId, r)
+}
+
+// Reconnect 玩家重连，从lastFrameId之后补发所有已广播的帧
+func (r *Room) Reconnect(playerId string, clientId string, lastFrameId uint32) error {
+	p, ok := r.mapPlayer[playerId]
+	if ok == false {
+		return fmt.Errorf("player %s is not in room %s", playerId, r.roomId)
+	}
+
+	if p.online == true {
+		r.fm.removeClient(p.clientId, r)
+	}
+
+	p.clientId = clientId
+	p.online = true
+	p.sentFrameId = min(lastFrameId, r.GetFrameId())
+	r.fm.mapClient[clientId] = clientRoom{room: r, playerId: playerId}
+
+	log.Info("player reconnect frame sync room", log.String("roomId", r.roomId), log.String("playerId", playerId), log.Uint64("lastFrameId", uint64(lastFrameId)), log.Uint64("frameId", uint64(r.GetFrameId())))
+	r.sendFrame(p)
+	return nil
+}
+
+// PushInput 收集玩家对frameId帧的输入，该帧已广播时并入下一个未广播的帧
+func (r *Room) PushInput(playerId string, frameId uint32, data []byte) error {
+	p, ok := r.mapPlayer[playerId]
+	if ok == false || p.online == false {
+		return fmt.Errorf("player %s is not online in room %s", playerId, r.roomId)
+	}
+
+	openFrameId := r.GetFrameId() + 1
+	if frameId < openFrameId {
+		frameId = openFrameId
+		r.lateInputNum++
+	}
+
+	if frameId > r.tickNum+r.cfg.MaxAheadFrames {
+		return fmt.Errorf("input frame %d of player %s is too far ahead of frame %d", frameId, playerId, r.tickNum)
+	}
+
+	r.mapPending[frameId] = append(r.mapPending[frameId], PlayerInput{PlayerId: playerId, Data: data})
+	return nil
+}
+
+func (r *Room) onTick(_ context.Context, _ frametimer.FrameTimerID) {
+	if r.closed == true {
+		return
+	}
+
+	r.tick()
+}
+
+// tick 逻辑帧前进，广播延迟帧数之前的帧
+func (r *Room) tick() {
+	r.tickNum++
+	if r.tickNum <= r.cfg.DelayFrames {
+		return
+	}
+
+	frameId := r.tickNum - r.cfg.DelayFrames
+	frame := &Frame{FrameId: frameId, InputList: r.mapPending[frameId]}
+	delete(r.mapPending, frameId)
+	r.frameList = append(r.frameList, frame)
+
+	for _, playerId := range r.playerList {
+		if p, ok := r.mapPlayer[playerId]; ok == true && p.online == true {
+			r.sendFrame(p)
+		}
+	}
+}
+
+// sendFrame 发送玩家尚未收到的帧，数量较多时分批发送
+func (r *Room) sendFrame(p *roomPlayer) {
+	for p.sentFrameId < r.GetFrameId() {
+		end := min(p.sentFrameId+uint32(r.cfg.ReplayBatch), r.GetFrameId())
+		if err := r.sender.SendFrame(p.clientId, r.frameList[p.sentFrameId:end]); err != nil {
+			log.Warn("send frame fail", log.String("roomId", r.roomId), log.String("playerId", p.playerId), log.ErrorField("err", err))
+			return
+		}
+		p.sentFrameId = end
+	}
+}
+
+// GetRecord 获取已广播的完整输入记录
+func (r *Room) GetRecord() *Record {
+	return &Record{
+		RoomId:     r.roomId,
+		Fps:        r.cfg.Fps,
+		StartTime:  r.startTime,
+		PlayerList: append([]string{}, r.playerList...),
+		FrameList:  r.frameList,
+	}
+}
+
+func (r *Room) close() {
+	if r.closed == true {
+		return
+	}
+	r.closed = true
+
+	if r.group != nil {
+		r.group.CancelTimer(r.timerId)
+		r.group.Close()
+	}
+
+	for _, p := range r.mapPlayer {
+		r.fm.removeClient(p.clientId, r)
+	}
+
+	if r.cfg.OnClose != nil {
+		r.cfg.OnClose(r.GetRecord())
+	}
+}
@@ -0,0 +1,134 @@
+package framesync
+
+import (
+	"bytes"
+	"testing"
+)
+
+type testSender struct {
+	mapFrame map[string][]*Frame
+	sendNum  int
+}
+
+func (ts *testSender) SendFrame(clientId string, frameList []*Frame) error {
+	ts.mapFrame[clientId] = append(ts.mapFrame[clientId], frameList...)
+	ts.sendNum++
+	return nil
+}
+
+func newTestRoom(cfg RoomCfg) (*FrameSyncModule, *Room, *testSender) {
+	fm := &FrameSyncModule{mapRoom: map[string]*Room{}, mapClient: map[string]clientRoom{}}
+	sender := &testSender{mapFrame: map[string][]*Frame{}}
+	room := newRoom(fm, "room_1", cfg, sender)
+	fm.mapRoom[room.roomId] = room
+	return fm, room, sender
+}
+
+func TestRoomBroadcast(t *testing.T) {
+	fm, room, sender := newTestRoom(RoomCfg{DelayFrames: 2})
+	_ = room.AddPlayer("p1", "c1")
+	_ = room.AddPlayer("p2", "c2")
+
+	//延迟2帧，第1帧在第3个逻辑帧广播
+	if err := fm.PushInput("c1", 1, []byte("move")); err != nil {
+		t.Fatal(err)
+	}
+	room.tick()
+	room.tick()
+	if len(sender.mapFrame["c1"]) != 0 {
+		t.Fatal("frame should be delayed")
+	}
+
+	_ = fm.PushInput("c2", 1, []byte("jump"))
+	room.tick()
+	frameList := sender.mapFrame["c2"]
+	if len(frameList) != 1 || frameList[0].FrameId != 1 || len(frameList[0].InputList) != 2 {
+		t.Fatalf("unexpected frame %+v", frameList)
+	}
+	if frameList[0].InputList[0].PlayerId != "p1" || string(frameList[0].InputList[1].Data) != "jump" {
+		t.Fatalf("unexpected input order %+v", frameList[0].InputList)
+	}
+
+	//第1帧已广播，晚到的输入并入第2帧
+	_ = fm.PushInput("c1", 1, []byte("late"))
+	room.tick()
+	frameList = sender.mapFrame["c1"]
+	if len(frameList) != 2 || len(frameList[1].InputList) != 1 || string(frameList[1].InputList[0].Data) != "late" || room.GetLateInputNum() != 1 {
+		t.Fatalf("unexpected late input %+v", frameList)
+	}
+
+	//超前过多的输入被拒绝
+	if err := fm.PushInput("c1", room.tickNum+defaultMaxAheadFrames+1, nil); err == nil {
+		t.Fatal("input too far ahead should fail")
+	}
+}
+
+func TestRoomReconnect(t *testing.T) {
+	fm, room, sender := newTestRoom(RoomCfg{ReplayBatch: 4})
+	_ = room.AddPlayer("p1", "c1")
+	_ = room.AddPlayer("p2", "c2")
+
+	room.tick()
+	fm.OnClientDisconnect("c2")
+	if err := fm.PushInput("c2", 2, nil); err == nil {
+		t.Fatal("input of disconnected client should fail")
+	}
+
+	for i := 0; i < 9; i++ {
+		_ = fm.PushInput("c1", room.GetFrameId()+1, []byte{byte(i)})
+		room.tick()
+	}
+	if len(sender.mapFrame["c2"]) != 1 {
+		t.Fatalf("disconnected client should not receive frames %d", len(sender.mapFrame["c2"]))
+	}
+
+	//从第1帧之后补发，每批4帧
+	sender.sendNum = 0
+	if err := room.Reconnect("p2", "c3", 1); err != nil {
+		t.Fatal(err)
+	}
+	frameList := sender.mapFrame["c3"]
+	if len(frameList) != 9 || frameList[0].FrameId != 2 || frameList[8].FrameId != 10 || sender.sendNum != 3 {
+		t.Fatalf("unexpected replay %d frames in %d batches", len(frameList), sender.sendNum)
+	}
+
+	if err := fm.PushInput("c3", room.GetFrameId()+1, nil); err != nil {
+		t.Fatal(err)
+	}
+	room.tick()
+	if len(sender.mapFrame["c3"]) != 10 {
+		t.Fatalf("unexpected frame num after reconnect %d", len(sender.mapFrame["c3"]))
+	}
+}
+
+func TestRoomRecord(t *testing.T) {
+	fm, room, _ := newTestRoom(RoomCfg{})
+	_ = room.AddPlayer("p1", "c1")
+
+	var record *Record
+	room.cfg.OnClose = func(r *Record) { record = r }
+	for i := 0; i < 5; i++ {
+		_ = fm.PushInput("c1", room.GetFrameId()+1, []byte{byte(i)})
+		room.tick()
+	}
+	fm.CloseRoom("room_1")
+
+	if record == nil || len(record.FrameList) != 5 || len(record.PlayerList) != 1 {
+		t.Fatalf("unexpected record %+v", record)
+	}
+	if _, ok := fm.mapClient["c1"]; ok == true {
+		t.Fatal("client should be removed after close")
+	}
+
+	var buf bytes.Buffer
+	if err := record.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadRecord(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.FrameList) != 5 || loaded.FrameList[4].InputList[0].Data[0] != 4 {
+		t.Fatalf("unexpected loaded record %+v", loaded)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"github.com/duanhf2012/origin/v2/event"
 	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/util/timer"
 	"time"
 )
 
@@ -95,6 +96,9 @@ func (fg *FrameGroup) onceTick() {
 		ev := event.NewEvent()
 		ev.Type = event.Sys_Event_FrameTick
 		ev.Data = t
+		if timer.IsVirtualClock() == true {
+			timer.AddVirtualTask()
+		}
 		fg.ft.NotifyEvent(ev)
 		fg.ft.removeTimerData(t.timerID)
 
@@ -198,6 +202,9 @@ func (fg *FrameGroup) CancelTimer(timerID FrameTimerID) {
 }
 
 func (fg *FrameGroup) Close(){
+	fg.ft.locker.Lock()
+	defer fg.ft.locker.Unlock()
+
 	fg.ft.removeGroup(fg.groupID, fg.preGlobalFrameNum)
 	delete(fg.ft.mapGroup,fg.groupID)
 }
\ No newline at end of file
@@ -5,6 +5,7 @@ import (
 	"github.com/duanhf2012/origin/v2/event"
 	"github.com/duanhf2012/origin/v2/log"
 	"github.com/duanhf2012/origin/v2/service"
+	"github.com/duanhf2012/origin/v2/util/timer"
 	"sync"
 	"time"
 )
@@ -45,6 +46,26 @@ type FrameTimer struct {
 
 	locker        sync.Mutex
 	sleepInterval time.Duration
+
+	clock *frameClock //虚拟时钟下驱动帧
+}
+
+// frameClock 虚拟时钟下按经过的时间推进帧
+type frameClock struct {
+	ft        *FrameTimer
+	startTime time.Time
+	frameNum  FrameNumber
+}
+
+func (fc *frameClock) NextFireTime() (time.Time, bool) {
+	return fc.startTime.Add(time.Duration(fc.frameNum+1) * fc.ft.oneFrameTime), true
+}
+
+func (fc *frameClock) OnClock(now time.Time) {
+	frameMax := FrameNumber(now.Sub(fc.startTime) / fc.ft.oneFrameTime)
+	for ; fc.frameNum < frameMax; fc.frameNum++ {
+		fc.ft.frameTick()
+	}
 }
 
 func (ft *FrameTimer) getTimerData(timerID FrameTimerID) *timerData {
@@ -143,11 +164,20 @@ func (ft *FrameTimer) OnInit() error {
 			log.Error("convert *timerData error")
 			return
 		}
+		if timer.IsVirtualClock() == true {
+			defer timer.DoneVirtualTask()
+		}
 		td.cb(td.ctx, td.timerID)
 		event.DeleteEvent(e)
 	})
 
 	ft.oneFrameTime = time.Second / time.Duration(ft.fps)
+	if timer.IsVirtualClock() == true {
+		ft.clock = &frameClock{ft: ft, startTime: timer.Now()}
+		timer.RegVirtualClockListener(ft.clock)
+		return nil
+	}
+
 	ft.ticker = time.NewTicker(ft.oneFrameTime)
 
 	if ft.sleepInterval == 0 {
@@ -172,6 +202,12 @@ func (ft *FrameTimer) OnInit() error {
 	return nil
 }
 
+func (ft *FrameTimer) OnRelease() {
+	if ft.clock != nil {
+		timer.UnRegVirtualClockListener(ft.clock)
+	}
+}
+
 // SetFps 设置帧率，越大误差越低。如果有倍数加速需求，可以适当加大fps，以减少误差。默认50fps
 func (ft *FrameTimer) SetFps(fps uint32) {
 	if fps > maxFps {
@@ -181,6 +217,11 @@ func (ft *FrameTimer) SetFps(fps uint32) {
 	ft.fps = fps
 }
 
+// GetFps 获取帧率
+func (ft *FrameTimer) GetFps() uint32 {
+	return ft.fps
+}
+
 // SetAccuracyInterval 设置时间间隔精度，在循环中sleep该时间进行判断。实际上因为sleep有误差，所以暂时不使用fps得出。默认为3ms
 func (ft *FrameTimer) SetAccuracyInterval(interval time.Duration) {
 	ft.sleepInterval = interval
@@ -0,0 +1,55 @@
+package mongodbmodule
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CheckpointStore 基于MongoDB的服务快照存储，使用service.SetCheckpointStore设置
+type CheckpointStore struct {
+	mongo      *MongoModule
+	db         string
+	collection string
+}
+
+type checkpointDoc struct {
+	Key        string    `bson:"_id"`
+	Data       []byte    `bson:"Data"`
+	UpdateTime time.Time `bson:"UpdateTime"`
+}
+
+// NewCheckpointStore mongo需要已经Start
+func NewCheckpointStore(mm *MongoModule, db string, collection string) *CheckpointStore {
+	return &CheckpointStore{mongo: mm, db: db, collection: collection}
+}
+
+func (cs *CheckpointStore) Save(key string, data []byte) error {
+	s := cs.mongo.TakeSession()
+	ctxTimeout, cancel := s.GetDefaultContext()
+	defer cancel()
+
+	doc := checkpointDoc{Key: key, Data: data, UpdateTime: time.Now()}
+	_, err := s.Collection(cs.db, cs.collection).ReplaceOne(ctxTimeout, bson.M{"_id": key}, &doc, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (cs *CheckpointStore) Load(key string) ([]byte, error) {
+	s := cs.mongo.TakeSession()
+	ctxTimeout, cancel := s.GetDefaultContext()
+	defer cancel()
+
+	var doc checkpointDoc
+	err := s.Collection(cs.db, cs.collection).FindOne(ctxTimeout, bson.M{"_id": key}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return doc.Data, nil
+}
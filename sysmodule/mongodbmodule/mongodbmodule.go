@@ -2,15 +2,27 @@ package mongodbmodule
 
 import (
 	"context"
+	"github.com/duanhf2012/origin/v2/service"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"sync"
 	"time"
 )
 
+const (
+	pingInterval = 5 * time.Second
+	pingTimeout  = 3 * time.Second
+)
+
 type MongoModule struct {
 	client             *mongo.Client
 	maxOperatorTimeOut time.Duration
+
+	pingLocker sync.Mutex
+	pingErr    error         //最近一次ping的结果
+	bPing      bool          //是否已经开始ping
+	pingExit   chan struct{} //关闭ping协程
 }
 
 type Session struct {
@@ -44,13 +56,65 @@ func (mm *MongoModule) Start() error {
 		return err
 	}
 
+	mm.pingLocker.Lock()
+	mm.bPing = true
+	mm.pingErr = nil
+	mm.pingExit = make(chan struct{})
+	mm.pingLocker.Unlock()
+	go mm.runPing(mm.pingExit)
+
 	return nil
 }
 
 func (mm *MongoModule) Stop() error {
+	mm.pingLocker.Lock()
+	if mm.pingExit != nil {
+		close(mm.pingExit)
+		mm.pingExit = nil
+	}
+	mm.bPing = false
+	mm.pingLocker.Unlock()
+
 	return mm.client.Disconnect(context.Background())
 }
 
+// runPing 定时ping，CheckHealth只读取最近一次的结果，不阻塞调用者
+func (mm *MongoModule) runPing(pingExit chan struct{}) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-pingExit:
+			return
+		case <-ticker.C:
+			ctxTimeout, cancel := context.WithTimeout(context.Background(), pingTimeout)
+			err := mm.client.Ping(ctxTimeout, nil)
+			cancel()
+
+			mm.pingLocker.Lock()
+			mm.pingErr = err
+			mm.pingLocker.Unlock()
+		}
+	}
+}
+
+// CheckHealth 实现service.IHealthChecker，根据Start后后台定时ping的最近一次结果判断
+// MongoModule不是服务模块，需要在服务中调用RegHealthChecker注册才会参与服务健康状态
+func (mm *MongoModule) CheckHealth() (service.HealthStatus, string) {
+	mm.pingLocker.Lock()
+	defer mm.pingLocker.Unlock()
+
+	if mm.bPing == false {
+		return service.NotReady, "mongo is not connected"
+	}
+	if mm.pingErr != nil {
+		return service.NotReady, "mongo ping fail: " + mm.pingErr.Error()
+	}
+
+	return service.Healthy, ""
+}
+
 func (mm *MongoModule) TakeSession() Session {
 	return Session{Client: mm.client, maxOperatorTimeOut: mm.maxOperatorTimeOut}
 }
@@ -38,6 +38,9 @@ type MySQLModule struct {
 	slowDuration  time.Duration
 	pingCoroutine PingExecute
 	waitGroup     sync.WaitGroup
+
+	pingLocker sync.Mutex
+	pingErr    error //最近一次ping的结果
 }
 
 // Tx ...
@@ -77,6 +80,20 @@ func (m *MySQLModule) Init(url string, userName string, password string, dbname
 	return m.connect(maxConn)
 }
 
+// OnInit 作为模块加入服务时自动注册连接的健康检查
+func (m *MySQLModule) OnInit() error {
+	m.GetService().RegHealthChecker(m.getHealthCheckerName(), m)
+	return nil
+}
+
+func (m *MySQLModule) OnRelease() {
+	m.GetService().UnRegHealthChecker(m.getHealthCheckerName())
+}
+
+func (m *MySQLModule) getHealthCheckerName() string {
+	return fmt.Sprintf("MySQLModule%d.db", m.GetModuleId())
+}
+
 func (m *MySQLModule) SetQuerySlowTime(slowDuration time.Duration) {
 	m.slowDuration = slowDuration
 }
@@ -159,12 +176,31 @@ func (m *MySQLModule) runPing() {
 			return
 		case <-m.pingCoroutine.tickerPing.C:
 			if m.db != nil {
-				m.db.Ping()
+				err := m.db.Ping()
+				m.pingLocker.Lock()
+				m.pingErr = err
+				m.pingLocker.Unlock()
 			}
 		}
 	}
 }
 
+// CheckHealth 实现service.IHealthChecker，根据最近一次ping的结果判断
+// 以AddModule加入服务时自动注册，作为普通成员使用时需要调用service.RegHealthChecker注册
+func (m *MySQLModule) CheckHealth() (service.HealthStatus, string) {
+	if m.db == nil {
+		return service.NotReady, "mysql is not connected"
+	}
+
+	m.pingLocker.Lock()
+	defer m.pingLocker.Unlock()
+	if m.pingErr != nil {
+		return service.NotReady, "mysql ping fail: " + m.pingErr.Error()
+	}
+
+	return service.Healthy, ""
+}
+
 func checkArgs(args ...interface{}) error {
 	for _, val := range args {
 		if reflect.TypeOf(val).Kind() == reflect.String {
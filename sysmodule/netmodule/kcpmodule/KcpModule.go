@@ -60,6 +60,20 @@ func (km *KcpModule) OnInit() error {
 	return nil
 }
 
+// WaitClientsOnDrain 排空阶段等待客户端全部断开，需要在OnInit之后调用
+// 模块不会主动断开客户端，服务需要在OnDrain中通知客户端断开或调用Close，否则将一直等待到排空超时
+func (km *KcpModule) WaitClientsOnDrain() {
+	km.GetService().RegDrainChecker(fmt.Sprintf("KcpModule%d.clients", km.GetModuleId()), km.GetClientNum)
+}
+
+// GetClientNum 获取当前连接的客户端数
+func (km *KcpModule) GetClientNum() int {
+	km.mapClientLocker.RLock()
+	defer km.mapClientLocker.RUnlock()
+
+	return len(km.mapClient)
+}
+
 func (km *KcpModule) Init(kcpCfg *network.KcpCfg, process processor.IRawProcessor) {
 	km.kcpCfg = kcpCfg
 	km.process = process
@@ -172,6 +186,8 @@ func (c *Client) Run() {
 
 	c.kcpModule.OnConnected(c)
 	for c.kcpConn != nil {
+		//服务过载时暂停读取
+		c.kcpModule.GetService().WaitOverloadRecover()
 		c.kcpConn.SetReadDeadline(*c.kcpModule.kcpCfg.ReadDeadlineMill)
 		msgBuff, err := c.kcpConn.ReadMsg()
 		if err != nil {
@@ -79,9 +79,24 @@ func (tm *TcpModule) OnInit() error {
 
 	//4.设置网络事件处理
 	tm.GetEventProcessor().RegEventReceiverFunc(event.Sys_Event_Tcp, tm.GetEventHandler(), tm.tcpEventHandler)
+
 	return nil
 }
 
+// WaitClientsOnDrain 排空阶段等待客户端全部断开，需要在OnInit之后调用
+// 模块不会主动断开客户端，服务需要在OnDrain中通知客户端断开或调用Close，否则将一直等待到排空超时
+func (tm *TcpModule) WaitClientsOnDrain() {
+	tm.GetService().RegDrainChecker(fmt.Sprintf("TcpModule%d.clients", tm.GetModuleId()), tm.GetClientNum)
+}
+
+// GetClientNum 获取当前连接的客户端数
+func (tm *TcpModule) GetClientNum() int {
+	tm.mapClientLocker.RLock()
+	defer tm.mapClientLocker.RUnlock()
+
+	return len(tm.mapClient)
+}
+
 func (tm *TcpModule) Init(tcpCfg *TcpCfg, process processor.IRawProcessor) {
 	tm.tcpCfg = tcpCfg
 	tm.process = process
@@ -130,6 +145,8 @@ func (slf *Client) Run() {
 
 	slf.tcpModule.NotifyEvent(&event.Event{Type: event.Sys_Event_Tcp, Data: TcpPack{ClientId: slf.id, Type: TPTConnected}})
 	for slf.tcpConn != nil {
+		//服务过载时暂停读取，由Tcp的接收窗口向客户端反压
+		slf.tcpModule.GetService().WaitOverloadRecover()
 		slf.tcpConn.SetReadDeadline(slf.tcpModule.tcpServer.ReadDeadline)
 		bytes, err := slf.tcpConn.ReadMsg()
 		if err != nil {
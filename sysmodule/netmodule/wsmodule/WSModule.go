@@ -90,6 +90,20 @@ func (ws *WSModule) OnInit() error {
 	return nil
 }
 
+// WaitClientsOnDrain 排空阶段等待客户端全部断开，需要在OnInit之后调用
+// 模块不会主动断开客户端，服务需要在OnDrain中通知客户端断开或调用Close，否则将一直等待到排空超时
+func (ws *WSModule) WaitClientsOnDrain() {
+	ws.GetService().RegDrainChecker(fmt.Sprintf("WSModule%d.clients", ws.GetModuleId()), ws.GetClientNum)
+}
+
+// GetClientNum 获取当前连接的客户端数
+func (ws *WSModule) GetClientNum() int {
+	ws.mapClientLocker.RLock()
+	defer ws.mapClientLocker.RUnlock()
+
+	return len(ws.mapClient)
+}
+
 func (ws *WSModule) Init(wsCfg *WSCfg, process processor.IRawProcessor) {
 	ws.wsCfg = wsCfg
 	ws.process = process
@@ -134,6 +148,8 @@ func (wc *WSClient) GetId() string {
 func (wc *WSClient) Run() {
 	wc.wsModule.NotifyEvent(&event.Event{Type: event.Sys_Event_WebSocket, Data: &WSPack{ClientId: wc.id, Type: WPTConnected}})
 	for {
+		//服务过载时暂停读取
+		wc.wsModule.GetService().WaitOverloadRecover()
 		bytes, err := wc.wsConn.ReadMsg()
 		if err != nil {
 			log.Debug("read client is error", log.String("clientId", wc.id), log.ErrorField("err", err))
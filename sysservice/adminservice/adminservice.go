@@ -0,0 +1,181 @@
+package adminservice
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/cluster"
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/node"
+	"github.com/duanhf2012/origin/v2/rpc"
+	"github.com/duanhf2012/origin/v2/service"
+)
+
+// AdminService 结点管理服务，供外部编排系统通过Rpc管理本结点
+// 使用时需要node.Setup(&adminservice.AdminService{})，并在结点的ServiceList中配置AdminService
+type AdminService struct {
+	service.Service
+}
+
+// SpawnServiceReq 创建模板服务实例请求
+type SpawnServiceReq struct {
+	ServiceName         string      //新服务名
+	TemplateServiceName string      //模板服务名
+	Public              bool        //是否对外公开
+	ServiceCfg          interface{} //服务配置，为空时使用配置文件中ServiceName对应的配置
+}
+
+// ReleaseServiceReq 释放模板服务实例请求
+type ReleaseServiceReq struct {
+	ServiceName string
+}
+
+// PushGlobalConfigReq 推送全局配置请求
+type PushGlobalConfigReq struct {
+	Version uint64      //配置版本号，为0时使用本结点当前版本号加1
+	Global  interface{} //新的Global配置
+}
+
+// GetServiceConfigVersionReq 查询服务配置版本请求
+type GetServiceConfigVersionReq struct {
+	ServiceName string //为空时查询本结点所有服务
+}
+
+// ServiceConfigVersion 服务当前生效的配置
+type ServiceConfigVersion struct {
+	ServiceName string
+	Version     uint64      //配置版本号，配置文件中的初始配置为0
+	ServiceCfg  interface{} //当前生效的配置
+}
+
+// GetServiceConfigVersionRes 查询服务配置版本返回
+type GetServiceConfigVersionRes struct {
+	ServiceList []ServiceConfigVersion
+}
+
+// SetLogLevelReq 设置日志级别请求
+type SetLogLevelReq struct {
+	Name      string //服务名或"服务名.模块名"，为空时设置全局级别
+	Level     string //debug|info|warn|error|fatal，为空时取消Name的覆盖级别
+	RevertSec int    //大于0时到期后自动恢复
+}
+
+// GetLogLevelRes 查询日志级别返回
+type GetLogLevelRes struct {
+	Level          string
+	NamedLevelList []log.NamedLogLevel
+}
+
+// RPC_SpawnService 运行时创建模板服务实例
+func (as *AdminService) RPC_SpawnService(req *SpawnServiceReq, _ *service.Empty) error {
+	err := node.SpawnTemplateService(req.ServiceName, req.TemplateServiceName, req.Public, req.ServiceCfg)
+	if err != nil {
+		log.Error("spawn service fail", log.String("serviceName", req.ServiceName), log.String("templateServiceName", req.TemplateServiceName), log.ErrorField("err", err))
+		return err
+	}
+
+	return nil
+}
+
+// RPC_ReleaseService 退休并释放模板服务实例
+func (as *AdminService) RPC_ReleaseService(req *ReleaseServiceReq, _ *service.Empty) error {
+	err := node.ReleaseTemplateService(req.ServiceName)
+	if err != nil {
+		log.Error("release service fail", log.String("serviceName", req.ServiceName), log.ErrorField("err", err))
+		return err
+	}
+
+	return nil
+}
+
+// RPC_PushGlobalConfig 推送全局配置到集群所有结点，返回已确认与未确认的结点
+func (as *AdminService) RPC_PushGlobalConfig(responder rpc.Responder, req *PushGlobalConfigReq) {
+	err := cluster.PushGlobalCfg(as, req.Version, req.Global, func(res *cluster.PushGlobalCfgRes, err error) {
+		if err != nil {
+			log.Error("push global config fail", log.Uint64("version", req.Version), log.ErrorField("err", err))
+			responder(&cluster.PushGlobalCfgRes{}, rpc.ConvertError(err))
+			return
+		}
+
+		log.Info("push global config finish", log.Uint64("version", res.Version), log.Any("ackNodeList", res.AckNodeList), log.Any("failNodeList", res.FailNodeList))
+		responder(res, rpc.NilError)
+	})
+
+	if err != nil {
+		log.Error("push global config fail", log.Uint64("version", req.Version), log.ErrorField("err", err))
+		responder(&cluster.PushGlobalCfgRes{}, rpc.ConvertError(err))
+	}
+}
+
+// RPC_GetServiceConfigVersion 查询本结点服务当前生效的配置版本
+func (as *AdminService) RPC_GetServiceConfigVersion(req *GetServiceConfigVersionReq, res *GetServiceConfigVersionRes) error {
+	for _, serviceName := range cluster.GetCluster().GetLocalNodeInfo().ServiceList {
+		serviceName = strings.Split(serviceName, ":")[0]
+		if req.ServiceName != "" && req.ServiceName != serviceName {
+			continue
+		}
+
+		s := service.GetService(serviceName)
+		if s == nil {
+			continue
+		}
+
+		res.ServiceList = append(res.ServiceList, ServiceConfigVersion{ServiceName: serviceName, Version: s.GetServiceCfgVersion(), ServiceCfg: s.GetServiceCfg()})
+	}
+
+	if req.ServiceName != "" && len(res.ServiceList) == 0 {
+		return fmt.Errorf("service %s is not found", req.ServiceName)
+	}
+
+	return nil
+}
+
+// RPC_SetLogLevel 设置本结点全局或者服务、模块的日志级别
+func (as *AdminService) RPC_SetLogLevel(req *SetLogLevelReq, _ *service.Empty) error {
+	revertAfter := time.Duration(req.RevertSec) * time.Second
+	if req.Level == "" {
+		if req.Name == "" {
+			return fmt.Errorf("level is empty")
+		}
+
+		log.ResetNamedLogLevel(req.Name)
+		log.Info("reset named log level", log.String("name", req.Name))
+		return nil
+	}
+
+	level, err := log.ParseLevel(req.Level)
+	if err != nil {
+		return err
+	}
+
+	if req.Name != "" {
+		err = log.SetNamedLogLevel(req.Name, level, revertAfter)
+		if err == nil {
+			log.Info("set named log level", log.String("name", req.Name), log.String("level", level.String()), log.Int("revertSec", req.RevertSec))
+		}
+		return err
+	}
+
+	oldLevel := log.GetLogLevel()
+	log.SetLogLevel(level)
+	log.Info("set log level", log.String("level", level.String()), log.Int("revertSec", req.RevertSec))
+	if revertAfter > 0 {
+		time.AfterFunc(revertAfter, func() {
+			//期间被再次设置时不恢复
+			if log.GetLogLevel() == level {
+				log.SetLogLevel(oldLevel)
+				log.Info("log level is reverted", log.String("level", oldLevel.String()))
+			}
+		})
+	}
+
+	return nil
+}
+
+// RPC_GetLogLevel 查询本结点的全局级别与覆盖的级别
+func (as *AdminService) RPC_GetLogLevel(_ *service.Empty, res *GetLogLevelRes) error {
+	res.Level = log.GetLogLevel().String()
+	res.NamedLevelList = log.GetNamedLogLevelList()
+	return nil
+}
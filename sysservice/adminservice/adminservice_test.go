@@ -0,0 +1,76 @@
+package adminservice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/log"
+	"go.uber.org/zap/zapcore"
+)
+
+func getNamedLogLevel(name string) (string, bool) {
+	for _, namedLevel := range log.GetNamedLogLevelList() {
+		if namedLevel.Name == name {
+			return namedLevel.Level, true
+		}
+	}
+
+	return "", false
+}
+
+func waitFor(t *testing.T, desc string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for cond() == false {
+		if time.Now().After(deadline) {
+			t.Fatalf("wait for %s timeout", desc)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSetLogLevelRevert(t *testing.T) {
+	var as AdminService
+	oldLevel := log.GetLogLevel()
+	defer log.SetLogLevel(oldLevel)
+
+	//服务的覆盖级别到期后恢复为继承
+	if err := as.RPC_SetLogLevel(&SetLogLevelReq{Name: "GameService", Level: "debug", RevertSec: 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if level, ok := getNamedLogLevel("GameService"); ok == false || level != zapcore.DebugLevel.String() {
+		t.Fatalf("named log level %s %v", level, ok)
+	}
+
+	//全局级别到期后恢复
+	if err := as.RPC_SetLogLevel(&SetLogLevelReq{Level: "error", RevertSec: 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if log.GetLogLevel() != zapcore.ErrorLevel {
+		t.Fatalf("log level is %s", log.GetLogLevel())
+	}
+
+	waitFor(t, "named log level revert", func() bool {
+		_, ok := getNamedLogLevel("GameService")
+		return ok == false
+	})
+	waitFor(t, "log level revert", func() bool {
+		return log.GetLogLevel() == oldLevel
+	})
+
+	//期间被再次设置时不恢复
+	if err := as.RPC_SetLogLevel(&SetLogLevelReq{Level: "warn", RevertSec: 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := as.RPC_SetLogLevel(&SetLogLevelReq{Level: "error"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	if log.GetLogLevel() != zapcore.ErrorLevel {
+		t.Fatalf("log level set later should not be reverted, %s", log.GetLogLevel())
+	}
+
+	if err := as.RPC_SetLogLevel(&SetLogLevelReq{}, nil); err == nil {
+		t.Fatal("empty request should fail")
+	}
+}
@@ -0,0 +1,551 @@
+package cronservice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/cluster"
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/rpc"
+	"github.com/duanhf2012/origin/v2/service"
+	"github.com/duanhf2012/origin/v2/util/timer"
+)
+
+const CronServiceName = "CronService"
+
+const (
+	leaderCheckInterval  = time.Second
+	defaultHistoryLimit  = 20
+	pendingRetryInterval = 10 * time.Second //调用目标方法失败后重试的间隔
+
+	//存储读写的协程池
+	storeMinGoroutineNum = 1
+	storeMaxGoroutineNum = 4
+	storeTaskChannelNum  = 10000
+)
+
+// CronService 集群分布式定时任务服务，可以部署在多个结点上，由NodeId最小的未退休结点作为主结点触发任务
+// 每次触发先在存储中认领并记为待完成，目标方法成功返回后才标记完成。调用失败或超时时由主结点定时重试，
+// 主结点切换或崩溃后由新的主结点加载状态时重试，因此同一次触发可能调用目标方法多次，
+// 目标方法以(JobName,FireTime)去重后即可做到恰好执行一次(exactly-once)
+// 存储的读写在协程池中执行，同一任务的读写按顺序执行，不阻塞服务协程
+// 使用时需要node.Setup(cronService)，Setup存储并AddJob或在服务配置的JobList中配置任务，所有结点的任务配置需要一致
+type CronService struct {
+	service.Service
+
+	store        IStore
+	jobList      []*Job
+	mapJob       map[string]*cronJob
+	isLeader     bool
+	leaderNodeId string
+	tickerId     uint64
+
+	callTarget func(job *cronJob, req *JobFireReq, cb func(*service.Empty, error)) error //调用目标方法，测试时替换
+}
+
+// JobNameReq 按任务名操作的请求
+type JobNameReq struct {
+	Name string
+}
+
+// JobInfo 任务与状态
+type JobInfo struct {
+	Job
+	Paused       bool
+	LastFireTime time.Time
+	PendingList  []time.Time //已认领未完成的触发
+	NextFireTime time.Time
+}
+
+// GetJobListRes 查询任务列表返回
+type GetJobListRes struct {
+	LeaderNodeId string
+	JobList      []JobInfo
+}
+
+// GetJobHistoryReq 查询任务执行记录请求
+type GetJobHistoryReq struct {
+	Name  string
+	Limit int //为0时使用默认值
+}
+
+// GetJobHistoryRes 查询任务执行记录返回，按触发时间从新到旧
+type GetJobHistoryRes struct {
+	HistoryList []*JobHistory
+}
+
+func getLocalNodeId() string {
+	return cluster.GetCluster().GetLocalNodeInfo().NodeId
+}
+
+// Setup 设置存储，需要在服务初始化前调用
+func (cs *CronService) Setup(store IStore) {
+	cs.store = store
+}
+
+// AddJob 添加任务，需要在服务初始化前调用
+func (cs *CronService) AddJob(job Job) {
+	cs.jobList = append(cs.jobList, &job)
+}
+
+func (cs *CronService) OnInit() error {
+	if cs.store == nil {
+		return errors.New("CronService store is not setup")
+	}
+
+	if err := cs.readCfg(); err != nil {
+		return err
+	}
+
+	cs.mapJob = make(map[string]*cronJob, len(cs.jobList))
+	for i, job := range cs.jobList {
+		if _, ok := cs.mapJob[job.Name]; ok == true {
+			return fmt.Errorf("cron job %s is repeated", job.Name)
+		}
+
+		cj, err := newCronJob(job)
+		if err != nil {
+			return fmt.Errorf("cron job %s is invalid: %w", job.Name, err)
+		}
+		cj.queueId = int64(i + 1)
+		cs.mapJob[job.Name] = cj
+	}
+
+	if cs.callTarget == nil {
+		cs.callTarget = cs.callRpc
+	}
+	cs.OpenConcurrent(storeMinGoroutineNum, storeMaxGoroutineNum, storeTaskChannelNum)
+	cs.RegDiscoverListener(cs)
+	cs.SafeNewTicker(&cs.tickerId, leaderCheckInterval, nil, cs.onCheckLeader)
+	return nil
+}
+
+// readCfg 读取服务配置中的JobList
+func (cs *CronService) readCfg() error {
+	mapCfg, ok := cs.GetServiceCfg().(map[string]interface{})
+	if ok == false {
+		return nil
+	}
+
+	jobListCfg, ok := mapCfg["JobList"]
+	if ok == false {
+		return nil
+	}
+
+	byteData, err := json.Marshal(jobListCfg)
+	if err != nil {
+		return err
+	}
+
+	var jobList []*Job
+	if err = json.Unmarshal(byteData, &jobList); err != nil {
+		return fmt.Errorf("CronService JobList config is error: %w", err)
+	}
+
+	cs.jobList = append(cs.jobList, jobList...)
+	return nil
+}
+
+// getLeaderNodeId 部署了本服务且未退休的结点中NodeId最小的结点
+func (cs *CronService) getLeaderNodeId() string {
+	mapNode := cluster.GetNodeByServiceName(cs.GetName())
+	if mapNode == nil {
+		mapNode = map[string]struct{}{}
+	}
+	mapNode[getLocalNodeId()] = struct{}{}
+
+	var leaderNodeId string
+	for nodeId := range mapNode {
+		if cluster.GetCluster().IsNodeRetire(nodeId) == true {
+			continue
+		}
+
+		if leaderNodeId == "" || nodeId < leaderNodeId {
+			leaderNodeId = nodeId
+		}
+	}
+
+	return leaderNodeId
+}
+
+func (cs *CronService) OnDiscoveryService(nodeId string, serviceName []string) {
+	cs.checkLeader()
+}
+
+func (cs *CronService) OnUnDiscoveryService(nodeId string, serviceName []string) {
+	cs.checkLeader()
+}
+
+func (cs *CronService) onCheckLeader(tickerId uint64, _ interface{}) {
+	cs.checkLeader()
+}
+
+func (cs *CronService) checkLeader() {
+	cs.leaderNodeId = cs.getLeaderNodeId()
+	isLeader := cs.leaderNodeId == getLocalNodeId()
+	if isLeader == cs.isLeader {
+		if isLeader == true {
+			//重试加载失败的任务与调用失败的触发
+			cs.loadJob(false)
+			cs.retryPending()
+		}
+		return
+	}
+
+	cs.isLeader = isLeader
+	if isLeader == true {
+		log.Info("cron service become leader", log.String("nodeId", getLocalNodeId()), log.Int("jobNum", len(cs.mapJob)))
+		cs.loadJob(true)
+		return
+	}
+
+	log.Info("cron service lose leader", log.String("nodeId", getLocalNodeId()), log.String("leaderNodeId", cs.leaderNodeId))
+	for _, job := range cs.mapJob {
+		cs.stopJob(job)
+	}
+}
+
+// stopJob 失去主结点时停止调度，未完成的触发由新的主结点重试
+func (cs *CronService) stopJob(job *cronJob) {
+	if job.timerId != 0 {
+		cs.CancelTimerId(&job.timerId)
+	}
+	job.loaded = false
+	clear(job.mapPending)
+}
+
+// loadJob 成为主结点时加载任务状态，按错过触发的处理方式补触发后开始调度
+func (cs *CronService) loadJob(all bool) {
+	for _, job := range cs.mapJob {
+		if job.loading == true || (job.loaded == true && all == false) {
+			continue
+		}
+
+		cs.loadJobState(job)
+	}
+}
+
+// loadJobState 在协程池中读取任务状态，读取后在服务协程中重试未完成的触发，补触发并开始调度
+func (cs *CronService) loadJobState(job *cronJob) {
+	var state *JobState
+	job.loading = true
+	cs.doStore(job.queueId, func() error {
+		var err error
+		state, err = cs.store.LoadState(job.Name)
+		return err
+	}, func(err error) {
+		job.loading = false
+		if err != nil {
+			log.Error("load cron job state fail", log.String("jobName", job.Name), log.ErrorField("err", err))
+			return
+		}
+
+		//读取期间失去主结点或已经加载
+		if cs.isLeader == false || job.loaded == true {
+			return
+		}
+
+		now := timer.Now()
+		if state != nil {
+			//之前的主结点认领后未成功完成的触发，暂停前已认领的也需要完成
+			for _, fireTime := range state.PendingList {
+				log.Info("retry pending cron job", log.String("jobName", job.Name), log.String("fireTime", fireTime.String()))
+				cs.callPending(job, fireTime)
+			}
+		}
+		if state != nil && state.Paused == false {
+			for _, fireTime := range getMissedFireTime(job.expr, job.MissedFirePolicy, state.LastFireTime, now) {
+				log.Info("fire missed cron job", log.String("jobName", job.Name), log.String("fireTime", fireTime.String()))
+				cs.fire(job, fireTime)
+			}
+		}
+
+		job.loaded = true
+		cs.schedule(job, now)
+	})
+}
+
+// doStore 在协程池中执行存储读写，相同queueId的按顺序执行，cb在服务协程中回调
+func (cs *CronService) doStore(queueId int64, fn func() error, cb func(err error)) {
+	var err error
+	cs.AsyncDoByQueue(queueId, func() bool {
+		err = fn()
+		return cb != nil
+	}, func(taskErr error) {
+		if cb == nil {
+			return
+		}
+
+		if taskErr != nil {
+			err = taskErr
+		}
+		cb(err)
+	})
+}
+
+// schedule 创建from之后下一次触发的定时器
+func (cs *CronService) schedule(job *cronJob, from time.Time) {
+	job.nextFireTime = job.expr.Next(from)
+	if job.nextFireTime.IsZero() {
+		log.Warn("cron job will never fire", log.String("jobName", job.Name), log.String("cronExpr", job.CronExpr))
+		return
+	}
+
+	d := max(job.nextFireTime.Sub(timer.Now()), 0)
+	cs.SafeAfterFunc(&job.timerId, d, job.Name, cs.onJobTimer)
+}
+
+func (cs *CronService) onJobTimer(timerId uint64, additionData interface{}) {
+	job, ok := cs.mapJob[additionData.(string)]
+	if ok == false || job.timerId != timerId || cs.isLeader == false {
+		return
+	}
+	job.timerId = 0
+
+	fireTime := job.nextFireTime
+	cs.fire(job, fireTime)
+	cs.schedule(job, fireTime)
+}
+
+// fire 认领成功后调用目标方法，已被其他结点认领或任务已暂停时不调用
+func (cs *CronService) fire(job *cronJob, fireTime time.Time) {
+	var ok bool
+	cs.doStore(job.queueId, func() error {
+		var err error
+		ok, err = cs.store.ClaimFire(job.Name, fireTime)
+		return err
+	}, func(err error) {
+		if err != nil {
+			log.Error("claim cron job fail", log.String("jobName", job.Name), log.String("fireTime", fireTime.String()), log.ErrorField("err", err))
+			return
+		}
+
+		if ok == false {
+			log.Debug("cron job is paused or has been fired", log.String("jobName", job.Name), log.String("fireTime", fireTime.String()))
+			return
+		}
+
+		cs.callPending(job, fireTime)
+	})
+}
+
+// callPending 调用已认领的触发，成功后在存储中标记完成，失败时等待重试
+func (cs *CronService) callPending(job *cronJob, fireTime time.Time) {
+	key := fireTime.UnixNano()
+	pending, ok := job.mapPending[key]
+	if ok == false {
+		pending = &pendingFire{fireTime: fireTime}
+		job.mapPending[key] = pending
+	}
+
+	if pending.calling == true {
+		return
+	}
+
+	pending.calling = true
+	cs.call(job, fireTime, false, func(err error) {
+		pending.calling = false
+		if err != nil {
+			pending.retryTime = timer.Now().Add(pendingRetryInterval)
+			return
+		}
+
+		//失去主结点时已清空，不能移除之后重新加载的待完成触发
+		if job.mapPending[key] == pending {
+			delete(job.mapPending, key)
+		}
+		cs.doStore(job.queueId, func() error {
+			return cs.store.DoneFire(job.Name, fireTime)
+		}, func(err error) {
+			if err != nil {
+				log.Error("done cron job fail", log.String("jobName", job.Name), log.String("fireTime", fireTime.String()), log.ErrorField("err", err))
+			}
+		})
+	})
+}
+
+// retryPending 主结点重试调用失败的触发
+func (cs *CronService) retryPending() {
+	now := timer.Now()
+	for _, job := range cs.mapJob {
+		if job.loaded == false {
+			continue
+		}
+
+		for _, pending := range job.mapPending {
+			if pending.calling == false && pending.retryTime.After(now) == false {
+				log.Info("retry pending cron job", log.String("jobName", job.Name), log.String("fireTime", pending.fireTime.String()))
+				cs.callPending(job, pending.fireTime)
+			}
+		}
+	}
+}
+
+// call 调用目标方法，返回后记录执行结果再回调cb
+func (cs *CronService) call(job *cronJob, fireTime time.Time, manual bool, cb func(err error)) {
+	history := &JobHistory{Name: job.Name, FireTime: fireTime, TriggerTime: timer.Now(), NodeId: getLocalNodeId(), Manual: manual}
+	req := &JobFireReq{JobName: job.Name, FireTime: fireTime, Manual: manual}
+	err := cs.callTarget(job, req, func(_ *service.Empty, err error) {
+		cs.addHistory(job, history, err)
+		if cb != nil {
+			cb(err)
+		}
+	})
+
+	if err != nil {
+		cs.addHistory(job, history, err)
+		if cb != nil {
+			cb(err)
+		}
+	}
+}
+
+// callRpc 以Rpc调用目标方法
+func (cs *CronService) callRpc(job *cronJob, req *JobFireReq, cb func(*service.Empty, error)) error {
+	if job.NodeId != "" {
+		return cs.AsyncCallNode(job.NodeId, job.ServiceMethod, req, cb)
+	}
+
+	return cs.AsyncCall(job.ServiceMethod, req, cb)
+}
+
+func (cs *CronService) addHistory(job *cronJob, history *JobHistory, err error) {
+	history.FinishTime = timer.Now()
+	if err != nil {
+		history.Err = err.Error()
+		log.Error("call cron job fail", log.String("jobName", history.Name), log.String("fireTime", history.FireTime.String()), log.ErrorField("err", err))
+	}
+
+	cs.doStore(job.queueId, func() error {
+		if err := cs.store.AddHistory(history); err != nil {
+			log.Error("add cron job history fail", log.String("jobName", history.Name), log.ErrorField("err", err))
+		}
+		return nil
+	}, nil)
+}
+
+func (cs *CronService) getJob(name string) (*cronJob, error) {
+	job, ok := cs.mapJob[name]
+	if ok == false {
+		return nil, fmt.Errorf("cron job %s is not found", name)
+	}
+
+	return job, nil
+}
+
+// RPC_TriggerJob 手动触发任务，在收到请求的结点立即调用，不影响计划触发
+func (cs *CronService) RPC_TriggerJob(req *JobNameReq, _ *service.Empty) error {
+	job, err := cs.getJob(req.Name)
+	if err != nil {
+		return err
+	}
+
+	log.Info("trigger cron job manually", log.String("jobName", req.Name))
+	cs.call(job, timer.Now(), true, nil)
+	return nil
+}
+
+// replyStoreResult 存储读写完成后回复Rpc
+func replyStoreResult(responder rpc.Responder, res interface{}, err error) {
+	if err != nil {
+		responder(res, rpc.ConvertError(err))
+		return
+	}
+
+	responder(res, rpc.NilError)
+}
+
+// RPC_PauseJob 暂停任务，暂停期间的触发不会执行
+func (cs *CronService) RPC_PauseJob(responder rpc.Responder, req *JobNameReq) {
+	job, err := cs.getJob(req.Name)
+	if err != nil {
+		replyStoreResult(responder, &service.Empty{}, err)
+		return
+	}
+
+	log.Info("pause cron job", log.String("jobName", req.Name))
+	cs.doStore(job.queueId, func() error {
+		return cs.store.Pause(req.Name)
+	}, func(err error) {
+		replyStoreResult(responder, &service.Empty{}, err)
+	})
+}
+
+// RPC_ResumeJob 恢复任务，暂停期间错过的触发不会补触发
+func (cs *CronService) RPC_ResumeJob(responder rpc.Responder, req *JobNameReq) {
+	job, err := cs.getJob(req.Name)
+	if err != nil {
+		replyStoreResult(responder, &service.Empty{}, err)
+		return
+	}
+
+	log.Info("resume cron job", log.String("jobName", req.Name))
+	now := timer.Now()
+	cs.doStore(job.queueId, func() error {
+		return cs.store.Resume(req.Name, now)
+	}, func(err error) {
+		replyStoreResult(responder, &service.Empty{}, err)
+	})
+}
+
+// RPC_GetJobList 查询所有任务及状态，在一个任务中读取所有任务的状态
+func (cs *CronService) RPC_GetJobList(responder rpc.Responder, _ *service.Empty) {
+	now := timer.Now()
+	res := &GetJobListRes{LeaderNodeId: cs.leaderNodeId}
+	for _, job := range cs.mapJob {
+		res.JobList = append(res.JobList, JobInfo{Job: job.Job, NextFireTime: job.expr.Next(now)})
+	}
+
+	slices.SortFunc(res.JobList, func(a, b JobInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	cs.doStore(0, func() error {
+		for i := range res.JobList {
+			state, err := cs.store.LoadState(res.JobList[i].Name)
+			if err != nil {
+				return err
+			}
+
+			if state != nil {
+				res.JobList[i].Paused = state.Paused
+				res.JobList[i].LastFireTime = state.LastFireTime
+				res.JobList[i].PendingList = state.PendingList
+			}
+		}
+		return nil
+	}, func(err error) {
+		if err != nil {
+			replyStoreResult(responder, &GetJobListRes{}, err)
+			return
+		}
+		replyStoreResult(responder, res, nil)
+	})
+}
+
+// RPC_GetJobHistory 查询任务执行记录
+func (cs *CronService) RPC_GetJobHistory(responder rpc.Responder, req *GetJobHistoryReq) {
+	job, err := cs.getJob(req.Name)
+	if err != nil {
+		replyStoreResult(responder, &GetJobHistoryRes{}, err)
+		return
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+
+	res := &GetJobHistoryRes{}
+	cs.doStore(job.queueId, func() error {
+		var err error
+		res.HistoryList, err = cs.store.LoadHistory(req.Name, limit)
+		return err
+	}, func(err error) {
+		replyStoreResult(responder, res, err)
+	})
+}
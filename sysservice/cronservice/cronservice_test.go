@@ -0,0 +1,195 @@
+package cronservice
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/event"
+	"github.com/duanhf2012/origin/v2/service"
+	"github.com/duanhf2012/origin/v2/util/timer"
+)
+
+// testTarget 模拟目标服务方法，按调用顺序返回预设的结果
+type testTarget struct {
+	locker     sync.Mutex
+	errList    []error
+	reqList    []JobFireReq
+	mapSuccess map[time.Time]int //每次触发成功执行的次数
+}
+
+func (tt *testTarget) call(req *JobFireReq) error {
+	tt.locker.Lock()
+	defer tt.locker.Unlock()
+
+	tt.reqList = append(tt.reqList, *req)
+	var err error
+	if len(tt.errList) > 0 {
+		err = tt.errList[0]
+		tt.errList = tt.errList[1:]
+	}
+	if err != nil {
+		return err
+	}
+
+	//实际的目标方法需要以(JobName,FireTime)去重，这里记录成功的次数用于检查
+	tt.mapSuccess[req.FireTime]++
+	return nil
+}
+
+func (tt *testTarget) getCallNum() int {
+	tt.locker.Lock()
+	defer tt.locker.Unlock()
+
+	return len(tt.reqList)
+}
+
+func (tt *testTarget) getSuccessNum(fireTime time.Time) int {
+	tt.locker.Lock()
+	defer tt.locker.Unlock()
+
+	return tt.mapSuccess[fireTime]
+}
+
+func newTestCronService(t *testing.T, name string, store IStore, target *testTarget) (*CronService, *cronJob) {
+	t.Helper()
+	cs := &CronService{}
+	cs.SetName(name)
+	cs.Init(cs, nil, nil, nil)
+	cs.Setup(store)
+
+	job, err := newCronJob(&Job{Name: "DailyReset", CronExpr: "0 0 5 * * *", ServiceMethod: "GameService.RPC_DailyReset"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.queueId = 1
+	cs.mapJob = map[string]*cronJob{job.Name: job}
+
+	//目标方法的结果与Rpc一样在服务协程中回调
+	cs.callTarget = func(job *cronJob, req *JobFireReq, cb func(*service.Empty, error)) error {
+		err := target.call(req)
+		return cs.PushEvent(&event.Event{Type: event.Sys_Event_User_Define, Data: func() {
+			cb(&service.Empty{}, err)
+		}})
+	}
+	cs.RegEventReceiverFunc(event.Sys_Event_User_Define, cs.GetEventHandler(), func(ev event.IEvent) {
+		ev.(*event.Event).Data.(func())()
+	})
+	cs.OpenConcurrent(storeMinGoroutineNum, storeMaxGoroutineNum, storeTaskChannelNum)
+
+	cs.Start()
+	t.Cleanup(cs.Stop)
+	return cs, job
+}
+
+// runOnService 在服务协程中执行fn并等待完成
+func runOnService(t *testing.T, cs *CronService, fn func()) {
+	t.Helper()
+	done := make(chan struct{})
+	if err := cs.PushEvent(&event.Event{Type: event.Sys_Event_User_Define, Data: func() {
+		fn()
+		close(done)
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("service goroutine is not running")
+	}
+}
+
+func waitFor(t *testing.T, desc string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for cond() == false {
+		if time.Now().After(deadline) {
+			t.Fatalf("wait for %s timeout", desc)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func getPendingNum(t *testing.T, store IStore) int {
+	state, err := store.LoadState("DailyReset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state == nil {
+		return 0
+	}
+	return len(state.PendingList)
+}
+
+func TestCronFireExactlyOnce(t *testing.T) {
+	start := time.Date(2024, 1, 1, 4, 0, 0, 0, time.Local)
+	timer.EnableVirtualClock(start)
+	timer.StartTimer(time.Millisecond, 100)
+	defer timer.DisableVirtualClock()
+
+	store := NewMemoryStore(10)
+	fireTime := start.Add(time.Hour)
+	oldTarget := &testTarget{errList: []error{errors.New("GameService is overloaded")}, mapSuccess: map[time.Time]int{}}
+	oldLeader, oldJob := newTestCronService(t, "OldCronService", store, oldTarget)
+
+	//认领后调用失败，触发保留为待完成
+	runOnService(t, oldLeader, func() {
+		oldLeader.isLeader = true
+		oldJob.loaded = true
+		oldLeader.fire(oldJob, fireTime)
+	})
+	waitFor(t, "first call", func() bool { return oldTarget.getCallNum() == 1 })
+	runOnService(t, oldLeader, func() {})
+	if getPendingNum(t, store) != 1 {
+		t.Fatal("failed fire should be pending")
+	}
+
+	//同一次触发不能再次认领
+	if ok, _ := store.ClaimFire("DailyReset", fireTime); ok == true {
+		t.Fatal("pending fire should not be claimed again")
+	}
+
+	//主结点在重试间隔后重试，仍然失败
+	oldTarget.locker.Lock()
+	oldTarget.errList = []error{errors.New("call timeout")}
+	oldTarget.locker.Unlock()
+	runOnService(t, oldLeader, oldLeader.retryPending)
+	if oldTarget.getCallNum() != 1 {
+		t.Fatal("retry before retry interval")
+	}
+	timer.Advance(pendingRetryInterval)
+	runOnService(t, oldLeader, oldLeader.retryPending)
+	waitFor(t, "retry call", func() bool { return oldTarget.getCallNum() == 2 })
+
+	//切换主结点，新主结点加载状态时重试未完成的触发
+	runOnService(t, oldLeader, func() {
+		oldLeader.isLeader = false
+		oldLeader.stopJob(oldJob)
+	})
+	newTarget := &testTarget{mapSuccess: map[time.Time]int{}}
+	newLeader, newJob := newTestCronService(t, "NewCronService", store, newTarget)
+	runOnService(t, newLeader, func() {
+		newLeader.isLeader = true
+		newLeader.loadJobState(newJob)
+	})
+	waitFor(t, "pending fire done", func() bool { return getPendingNum(t, store) == 0 })
+
+	if newTarget.getSuccessNum(fireTime) != 1 || oldTarget.getSuccessNum(fireTime) != 0 {
+		t.Fatal("fire should succeed exactly once")
+	}
+
+	//旧主结点不再重试
+	timer.Advance(pendingRetryInterval)
+	runOnService(t, oldLeader, oldLeader.retryPending)
+	if oldTarget.getCallNum() != 2 {
+		t.Fatal("old leader should not retry after losing leader")
+	}
+
+	//执行记录包含失败与成功的调用
+	historyList, _ := store.LoadHistory("DailyReset", 10)
+	if len(historyList) != 3 || historyList[0].Err != "" || historyList[2].Err == "" {
+		t.Fatalf("unexpected history %+v", historyList)
+	}
+}
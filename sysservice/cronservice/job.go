@@ -0,0 +1,240 @@
+package cronservice
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/util/timer"
+)
+
+// MissedFirePolicy 主结点切换或全部停止期间错过的触发的处理方式
+type MissedFirePolicy string
+
+const (
+	MissedSkip     MissedFirePolicy = "Skip"     //跳过错过的触发，默认
+	MissedFireOnce MissedFirePolicy = "FireOnce" //只补触发最近一次
+	MissedCatchUp  MissedFirePolicy = "CatchUp"  //按顺序补触发所有错过的，最多maxCatchUpNum次
+)
+
+const maxCatchUpNum = 100
+
+// Job 分布式定时任务，触发时以Rpc调用目标服务方法，参数为*JobFireReq，返回值为*service.Empty
+// 目标方法返回错误、超时或主结点切换时会重试同一次触发，目标方法需要以(JobName,FireTime)去重，重复的触发直接返回成功
+type Job struct {
+	Name             string           //任务名，集群中唯一
+	CronExpr         string           //Cron表达式
+	ServiceMethod    string           //目标服务方法，如GameService.RPC_DailyReset
+	NodeId           string           //目标结点，为空时由Rpc选择一个提供该服务的结点
+	MissedFirePolicy MissedFirePolicy //错过的触发的处理方式
+}
+
+// JobFireReq 触发目标服务方法的参数
+type JobFireReq struct {
+	JobName  string
+	FireTime time.Time //计划触发时间，手动触发时为触发时的时间，与JobName一起作为去重的键
+	Manual   bool      //是否手动触发
+}
+
+// JobState 任务在存储中的状态
+type JobState struct {
+	Name         string
+	LastFireTime time.Time   //最近一次已认领的计划触发时间
+	PendingList  []time.Time //已认领但目标方法尚未成功返回的计划触发时间
+	Paused       bool
+}
+
+// JobHistory 任务的执行记录
+type JobHistory struct {
+	Name        string
+	FireTime    time.Time //计划触发时间
+	TriggerTime time.Time //实际触发时间
+	FinishTime  time.Time //目标方法返回的时间
+	NodeId      string    //触发的结点
+	Manual      bool
+	Err         string //为空时执行成功
+}
+
+// IStore 任务状态与执行记录的存储，集群中所有CronService需要使用同一个存储才能保证每次触发只被认领一次
+// 在协程池中调用，实现需要保证协程安全
+type IStore interface {
+	LoadState(name string) (*JobState, error) //不存在时返回nil,nil
+	// ClaimFire 认领一次触发，任务未暂停且LastFireTime早于fireTime时更新为fireTime，加入PendingList并返回true
+	ClaimFire(name string, fireTime time.Time) (bool, error)
+	// DoneFire 目标方法成功返回后从PendingList中移除
+	DoneFire(name string, fireTime time.Time) error
+	Pause(name string) error
+	// Resume 恢复任务，暂停期间的触发不再补触发
+	Resume(name string, now time.Time) error
+	AddHistory(history *JobHistory) error
+	LoadHistory(name string, limit int) ([]*JobHistory, error) //按触发时间从新到旧
+}
+
+// cronJob 本结点加载的任务
+type cronJob struct {
+	Job
+	expr         *timer.CronExpr
+	queueId      int64 //存储读写的队列，同一任务的读写按顺序执行
+	timerId      uint64
+	nextFireTime time.Time              //下次计划触发时间
+	loading      bool                   //是否正在读取状态
+	loaded       bool                   //主结点是否已经加载状态并开始调度
+	mapPending   map[int64]*pendingFire //已认领未完成的触发，按计划触发时间的UnixNano索引
+}
+
+// pendingFire 已认领但目标方法尚未成功返回的触发
+type pendingFire struct {
+	fireTime  time.Time
+	retryTime time.Time //调用失败后下次重试的时间
+	calling   bool      //是否正在调用目标方法
+}
+
+func newCronJob(job *Job) (*cronJob, error) {
+	if job.Name == "" || job.ServiceMethod == "" {
+		return nil, fmt.Errorf("job name and service method can not be empty")
+	}
+
+	expr, err := timer.NewCronExpr(job.CronExpr)
+	if err != nil {
+		return nil, err
+	}
+
+	switch job.MissedFirePolicy {
+	case "":
+		job.MissedFirePolicy = MissedSkip
+	case MissedSkip, MissedFireOnce, MissedCatchUp:
+	default:
+		return nil, fmt.Errorf("job %s has invalid missed fire policy %s", job.Name, job.MissedFirePolicy)
+	}
+
+	return &cronJob{Job: *job, expr: expr, mapPending: map[int64]*pendingFire{}}, nil
+}
+
+// getMissedFireTime 获取lastFireTime之后到now为止错过的需要补触发的时间，从now向前查找，只保留最近的
+func getMissedFireTime(expr *timer.CronExpr, policy MissedFirePolicy, lastFireTime time.Time, now time.Time) []time.Time {
+	if policy == MissedSkip || lastFireTime.IsZero() {
+		return nil
+	}
+
+	maxNum := maxCatchUpNum
+	if policy == MissedFireOnce {
+		maxNum = 1
+	}
+
+	var missedList []time.Time
+	for t := expr.Prev(now.Add(time.Nanosecond)); t.After(lastFireTime) && len(missedList) < maxNum; t = expr.Prev(t) {
+		missedList = append(missedList, t)
+	}
+
+	slices.Reverse(missedList)
+	return missedList
+}
+
+// MemoryStore 内存存储，只适用于集群中只有一个CronService的场景，重启后状态丢失
+type MemoryStore struct {
+	locker     sync.Mutex
+	mapState   map[string]*JobState
+	mapHistory map[string][]*JobHistory
+	maxHistory int
+}
+
+// NewMemoryStore 每个任务最多保留maxHistory条执行记录
+func NewMemoryStore(maxHistory int) *MemoryStore {
+	return &MemoryStore{mapState: map[string]*JobState{}, mapHistory: map[string][]*JobHistory{}, maxHistory: maxHistory}
+}
+
+func (ms *MemoryStore) getState(name string) *JobState {
+	state, ok := ms.mapState[name]
+	if ok == false {
+		state = &JobState{Name: name}
+		ms.mapState[name] = state
+	}
+
+	return state
+}
+
+func (ms *MemoryStore) LoadState(name string) (*JobState, error) {
+	ms.locker.Lock()
+	defer ms.locker.Unlock()
+
+	state, ok := ms.mapState[name]
+	if ok == false {
+		return nil, nil
+	}
+
+	stateCopy := *state
+	stateCopy.PendingList = slices.Clone(state.PendingList)
+	return &stateCopy, nil
+}
+
+func (ms *MemoryStore) ClaimFire(name string, fireTime time.Time) (bool, error) {
+	ms.locker.Lock()
+	defer ms.locker.Unlock()
+
+	state := ms.getState(name)
+	if state.Paused || state.LastFireTime.Before(fireTime) == false {
+		return false, nil
+	}
+
+	state.LastFireTime = fireTime
+	state.PendingList = append(state.PendingList, fireTime)
+	return true, nil
+}
+
+func (ms *MemoryStore) DoneFire(name string, fireTime time.Time) error {
+	ms.locker.Lock()
+	defer ms.locker.Unlock()
+
+	state := ms.getState(name)
+	state.PendingList = slices.DeleteFunc(state.PendingList, func(t time.Time) bool {
+		return t.Equal(fireTime)
+	})
+	return nil
+}
+
+func (ms *MemoryStore) Pause(name string) error {
+	ms.locker.Lock()
+	defer ms.locker.Unlock()
+
+	ms.getState(name).Paused = true
+	return nil
+}
+
+func (ms *MemoryStore) Resume(name string, now time.Time) error {
+	ms.locker.Lock()
+	defer ms.locker.Unlock()
+
+	state := ms.getState(name)
+	state.Paused = false
+	if state.LastFireTime.Before(now) {
+		state.LastFireTime = now
+	}
+
+	return nil
+}
+
+func (ms *MemoryStore) AddHistory(history *JobHistory) error {
+	ms.locker.Lock()
+	defer ms.locker.Unlock()
+
+	historyList := append(ms.mapHistory[history.Name], history)
+	if ms.maxHistory > 0 && len(historyList) > ms.maxHistory {
+		historyList = historyList[len(historyList)-ms.maxHistory:]
+	}
+	ms.mapHistory[history.Name] = historyList
+	return nil
+}
+
+func (ms *MemoryStore) LoadHistory(name string, limit int) ([]*JobHistory, error) {
+	ms.locker.Lock()
+	defer ms.locker.Unlock()
+
+	historyList := ms.mapHistory[name]
+	result := make([]*JobHistory, 0, min(len(historyList), limit))
+	for i := len(historyList) - 1; i >= 0 && len(result) < limit; i-- {
+		result = append(result, historyList[i])
+	}
+
+	return result, nil
+}
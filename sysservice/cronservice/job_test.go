@@ -0,0 +1,90 @@
+package cronservice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/util/timer"
+)
+
+func TestGetMissedFireTime(t *testing.T) {
+	expr, err := timer.NewCronExpr("0 0 5 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lastFireTime := time.Date(2024, 1, 1, 5, 0, 0, 0, time.UTC)
+	now := time.Date(2024, 1, 4, 6, 0, 0, 0, time.UTC)
+
+	if missedList := getMissedFireTime(expr, MissedSkip, lastFireTime, now); len(missedList) != 0 {
+		t.Fatalf("skip policy should not fire %v", missedList)
+	}
+
+	missedList := getMissedFireTime(expr, MissedFireOnce, lastFireTime, now)
+	if len(missedList) != 1 || missedList[0].Equal(time.Date(2024, 1, 4, 5, 0, 0, 0, time.UTC)) == false {
+		t.Fatalf("unexpected fire once %v", missedList)
+	}
+
+	missedList = getMissedFireTime(expr, MissedCatchUp, lastFireTime, now)
+	if len(missedList) != 3 {
+		t.Fatalf("unexpected catch up %v", missedList)
+	}
+	for i, fireTime := range missedList {
+		if fireTime.Equal(time.Date(2024, 1, 2+i, 5, 0, 0, 0, time.UTC)) == false {
+			t.Fatalf("unexpected catch up %v", missedList)
+		}
+	}
+
+	//刚好在触发时间
+	missedList = getMissedFireTime(expr, MissedCatchUp, lastFireTime, time.Date(2024, 1, 2, 5, 0, 0, 0, time.UTC))
+	if len(missedList) != 1 {
+		t.Fatalf("unexpected catch up %v", missedList)
+	}
+
+	//没有上次触发的状态时不补触发
+	if missedList = getMissedFireTime(expr, MissedCatchUp, time.Time{}, now); len(missedList) != 0 {
+		t.Fatalf("unexpected catch up without state %v", missedList)
+	}
+
+	//最多补触发maxCatchUpNum次，保留最近的
+	secondExpr, _ := timer.NewCronExpr("* * * * * *")
+	missedList = getMissedFireTime(secondExpr, MissedCatchUp, lastFireTime, now)
+	if len(missedList) != maxCatchUpNum || missedList[len(missedList)-1].Equal(now) == false {
+		t.Fatalf("unexpected catch up num %d", len(missedList))
+	}
+}
+
+func TestMemoryStoreClaimFire(t *testing.T) {
+	store := NewMemoryStore(2)
+	fireTime := time.Date(2024, 1, 1, 5, 0, 0, 0, time.UTC)
+
+	//同一次触发只能认领一次
+	if ok, _ := store.ClaimFire("DailyReset", fireTime); ok == false {
+		t.Fatal("first claim should succeed")
+	}
+	if ok, _ := store.ClaimFire("DailyReset", fireTime); ok == true {
+		t.Fatal("second claim should fail")
+	}
+
+	_ = store.Pause("DailyReset")
+	if ok, _ := store.ClaimFire("DailyReset", fireTime.Add(24*time.Hour)); ok == true {
+		t.Fatal("claim of paused job should fail")
+	}
+
+	//恢复后暂停期间的触发不再执行
+	_ = store.Resume("DailyReset", fireTime.Add(36*time.Hour))
+	if ok, _ := store.ClaimFire("DailyReset", fireTime.Add(24*time.Hour)); ok == true {
+		t.Fatal("claim of occurrence during pause should fail")
+	}
+	if ok, _ := store.ClaimFire("DailyReset", fireTime.Add(48*time.Hour)); ok == false {
+		t.Fatal("claim after resume should succeed")
+	}
+
+	for i := 0; i < 3; i++ {
+		_ = store.AddHistory(&JobHistory{Name: "DailyReset", FireTime: fireTime.Add(time.Duration(i) * time.Hour)})
+	}
+	historyList, _ := store.LoadHistory("DailyReset", 10)
+	if len(historyList) != 2 || historyList[0].FireTime.Equal(fireTime.Add(2*time.Hour)) == false {
+		t.Fatalf("unexpected history %v", historyList)
+	}
+}
@@ -0,0 +1,143 @@
+package cronservice
+
+import (
+	"errors"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/sysmodule/mongodbmodule"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoStore MongoDB存储，任务状态以任务名为主键，认领触发使用条件更新保证只有一个结点成功
+// 执行记录集合建议对(Name,FireTime)建立索引
+type MongoStore struct {
+	mongo             *mongodbmodule.MongoModule
+	db                string
+	stateCollection   string
+	historyCollection string
+}
+
+type mongoStateDoc struct {
+	Name         string      `bson:"_id"`
+	LastFireTime time.Time   `bson:"LastFireTime"`
+	PendingList  []time.Time `bson:"PendingList"`
+	Paused       bool        `bson:"Paused"`
+}
+
+type mongoHistoryDoc struct {
+	Name        string    `bson:"Name"`
+	FireTime    time.Time `bson:"FireTime"`
+	TriggerTime time.Time `bson:"TriggerTime"`
+	FinishTime  time.Time `bson:"FinishTime"`
+	NodeId      string    `bson:"NodeId"`
+	Manual      bool      `bson:"Manual"`
+	Err         string    `bson:"Err"`
+}
+
+// NewMongoStore mm需要已经Start
+func NewMongoStore(mm *mongodbmodule.MongoModule, db string, stateCollection string, historyCollection string) *MongoStore {
+	return &MongoStore{mongo: mm, db: db, stateCollection: stateCollection, historyCollection: historyCollection}
+}
+
+func (ms *MongoStore) LoadState(name string) (*JobState, error) {
+	s := ms.mongo.TakeSession()
+	ctxTimeout, cancel := s.GetDefaultContext()
+	defer cancel()
+
+	var doc mongoStateDoc
+	err := s.Collection(ms.db, ms.stateCollection).FindOne(ctxTimeout, bson.M{"_id": name}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &JobState{Name: doc.Name, LastFireTime: doc.LastFireTime, PendingList: doc.PendingList, Paused: doc.Paused}, nil
+}
+
+func (ms *MongoStore) ClaimFire(name string, fireTime time.Time) (bool, error) {
+	s := ms.mongo.TakeSession()
+	ctxTimeout, cancel := s.GetDefaultContext()
+	defer cancel()
+
+	//文档不存在时插入，已存在但条件不满足时插入会产生主键冲突，视为认领失败
+	filter := bson.M{"_id": name, "Paused": bson.M{"$ne": true}, "LastFireTime": bson.M{"$lt": fireTime}}
+	update := bson.M{"$set": bson.M{"LastFireTime": fireTime}, "$push": bson.M{"PendingList": fireTime}}
+	ret, err := s.Collection(ms.db, ms.stateCollection).UpdateOne(ctxTimeout, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return ret.ModifiedCount > 0 || ret.UpsertedCount > 0, nil
+}
+
+func (ms *MongoStore) DoneFire(name string, fireTime time.Time) error {
+	s := ms.mongo.TakeSession()
+	ctxTimeout, cancel := s.GetDefaultContext()
+	defer cancel()
+
+	update := bson.M{"$pull": bson.M{"PendingList": fireTime}}
+	_, err := s.Collection(ms.db, ms.stateCollection).UpdateOne(ctxTimeout, bson.M{"_id": name}, update)
+	return err
+}
+
+func (ms *MongoStore) Pause(name string) error {
+	s := ms.mongo.TakeSession()
+	ctxTimeout, cancel := s.GetDefaultContext()
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"Paused": true}, "$setOnInsert": bson.M{"LastFireTime": time.Time{}}}
+	_, err := s.Collection(ms.db, ms.stateCollection).UpdateOne(ctxTimeout, bson.M{"_id": name}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (ms *MongoStore) Resume(name string, now time.Time) error {
+	s := ms.mongo.TakeSession()
+	ctxTimeout, cancel := s.GetDefaultContext()
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"Paused": false}, "$max": bson.M{"LastFireTime": now}}
+	_, err := s.Collection(ms.db, ms.stateCollection).UpdateOne(ctxTimeout, bson.M{"_id": name}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (ms *MongoStore) AddHistory(history *JobHistory) error {
+	s := ms.mongo.TakeSession()
+	ctxTimeout, cancel := s.GetDefaultContext()
+	defer cancel()
+
+	doc := mongoHistoryDoc(*history)
+	_, err := s.Collection(ms.db, ms.historyCollection).InsertOne(ctxTimeout, &doc)
+	return err
+}
+
+func (ms *MongoStore) LoadHistory(name string, limit int) ([]*JobHistory, error) {
+	s := ms.mongo.TakeSession()
+	ctxTimeout, cancel := s.GetDefaultContext()
+	defer cancel()
+
+	findOpts := options.Find().SetSort(bson.D{{Key: "FireTime", Value: -1}}).SetLimit(int64(limit)).SetProjection(bson.M{"_id": 0})
+	cursor, err := s.Collection(ms.db, ms.historyCollection).Find(ctxTimeout, bson.M{"Name": name}, findOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	var docList []mongoHistoryDoc
+	if err = cursor.All(ctxTimeout, &docList); err != nil {
+		return nil, err
+	}
+
+	historyList := make([]*JobHistory, 0, len(docList))
+	for _, doc := range docList {
+		history := JobHistory(doc)
+		historyList = append(historyList, &history)
+	}
+
+	return historyList, nil
+}
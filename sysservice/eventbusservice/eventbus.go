@@ -0,0 +1,126 @@
+package eventbusservice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/event"
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/rpc"
+	"github.com/duanhf2012/origin/v2/service"
+)
+
+// DeliveryMode 事件投递模式
+type DeliveryMode int
+
+const (
+	AtMostOnce  DeliveryMode = 0 //通过Rpc广播投递，结点不可达时丢失
+	AtLeastOnce DeliveryMode = 1 //通过MessageQueueService持久化投递，订阅者可能收到重复消息，可以使用MsgId去重
+)
+
+// Message 事件总线消息
+type Message struct {
+	Topic      string
+	MsgId      string //结点Id-启动时间-序号，全局唯一
+	FromNodeId string
+	Mode       DeliveryMode
+	Payload    json.RawMessage
+}
+
+// ISubscriber 订阅者，Service与Module均满足
+type ISubscriber interface {
+	service.IModule
+	GetEventHandler() event.IEventHandler
+}
+
+var msgSeq uint64
+var startTime = time.Now().UnixNano()
+
+// Subscribe 订阅主题，payload解码为T，回调在订阅者所属服务的协程中执行，需要在订阅者所属服务协程中调用
+// 同一订阅者重复订阅同一主题时替换回调
+func Subscribe[T any](subscriber ISubscriber, topic string, mode DeliveryMode, cb func(msg *Message, payload *T)) error {
+	bus := getEventBus()
+	if bus == nil {
+		return errors.New("EventBusService is not setup")
+	}
+
+	bus.subscribe(subscriber, topic, mode, func(msg *Message) {
+		var payload T
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			log.Error("unmarshal event bus payload fail", log.String("topic", msg.Topic), log.String("msgId", msg.MsgId), log.ErrorField("err", err))
+			return
+		}
+
+		cb(msg, &payload)
+	})
+
+	return nil
+}
+
+// Unsubscribe 取消订阅主题
+func Unsubscribe(subscriber ISubscriber, topic string) {
+	bus := getEventBus()
+	if bus == nil {
+		return
+	}
+
+	bus.unsubscribe(subscriber, topic)
+}
+
+// Publish 以AtMostOnce模式发布事件，投递到集群中所有部署EventBusService的结点
+func Publish(rpcHandler rpc.IRpcHandler, topic string, payload interface{}) error {
+	msg, err := newMessage(topic, AtMostOnce, payload)
+	if err != nil {
+		return err
+	}
+
+	return rpcHandler.CastGo(EventBusServiceName+".RPC_Deliver", msg)
+}
+
+// PublishReliable 以AtLeastOnce模式发布事件，写入MessageQueueService后回调，err为nil时表示已持久化
+func PublishReliable(rpcHandler rpc.IRpcHandler, topic string, payload interface{}, cb func(err error)) error {
+	msg, err := newMessage(topic, AtLeastOnce, payload)
+	if err != nil {
+		return err
+	}
+
+	byteMsg, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req := rpc.DBQueuePublishReq{TopicName: getMQTopicName(topic), PushData: [][]byte{byteMsg}}
+	return rpcHandler.AsyncCall("MessageQueueService.RPC_Publish", &req, func(res *rpc.DBQueuePublishRes, err error) {
+		if err != nil {
+			log.Error("publish event bus message fail", log.String("topic", topic), log.String("msgId", msg.MsgId), log.ErrorField("err", err))
+		}
+
+		if cb != nil {
+			cb(err)
+		}
+	})
+}
+
+func newMessage(topic string, mode DeliveryMode, payload interface{}) (*Message, error) {
+	bytePayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &Message{
+		Topic:      topic,
+		MsgId:      fmt.Sprintf("%s-%d-%d", getLocalNodeId(), startTime, atomic.AddUint64(&msgSeq, 1)),
+		FromNodeId: getLocalNodeId(),
+		Mode:       mode,
+		Payload:    bytePayload,
+	}
+
+	return msg, nil
+}
+
+func getMQTopicName(topic string) string {
+	return "EventBus." + topic
+}
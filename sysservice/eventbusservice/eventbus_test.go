@@ -0,0 +1,222 @@
+package eventbusservice
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/event"
+	"github.com/duanhf2012/origin/v2/profiler"
+	"github.com/duanhf2012/origin/v2/rpc"
+	"github.com/duanhf2012/origin/v2/service"
+)
+
+type testPayload struct {
+	RoleId uint64
+	Level  int
+}
+
+type testSubscriber struct {
+	service.Service
+}
+
+// newTestEventBus 初始化事件总线，不注册服务发现与MQ订阅定时器
+func newTestEventBus(t *testing.T) *EventBusService {
+	t.Helper()
+	bus := &EventBusService{}
+	bus.OnSetup(bus)
+	bus.Init(bus, nil, nil, nil)
+	bus.mapTopic = map[string]map[event.IEventHandler]topicSubscriber{}
+	bus.mapSubscriber = map[event.IEventHandler]int{}
+	bus.mapMQTopic = map[string]int{}
+	if service.Setup(bus) == false {
+		t.Fatal("setup EventBusService fail")
+	}
+	t.Cleanup(func() { service.Remove(EventBusServiceName) })
+	return bus
+}
+
+func newTestSubscriber(name string, eventChannelNum int) *testSubscriber {
+	sub := &testSubscriber{}
+	sub.SetName(name)
+	if eventChannelNum > 0 {
+		sub.SetEventChannelNum(eventChannelNum)
+	}
+	sub.Init(sub, nil, nil, nil)
+
+	//事件注册不是协程安全的，启动后通过该事件在服务协程中执行
+	sub.RegEventReceiverFunc(event.Sys_Event_User_Define, sub.GetEventHandler(), func(ev event.IEvent) {
+		ev.(*event.Event).Data.(func())()
+	})
+	return sub
+}
+
+// runOnService 在服务协程中执行fn，返回服务协程的协程Id
+func runOnService(t *testing.T, sub *testSubscriber, fn func()) uint64 {
+	t.Helper()
+	chanGoid := make(chan uint64, 1)
+	err := sub.PushEvent(&event.Event{Type: event.Sys_Event_User_Define, Data: func() {
+		fn()
+		chanGoid <- profiler.GetGoroutineId()
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case goid := <-chanGoid:
+		return goid
+	case <-time.After(time.Second):
+		t.Fatal("service goroutine is not running")
+	}
+	return 0
+}
+
+func TestAtMostOnceDeliver(t *testing.T) {
+	bus := newTestEventBus(t)
+	sub := newTestSubscriber("EventBusTestSubscriber", 0)
+
+	type received struct {
+		msg     *Message
+		payload *testPayload
+		goid    uint64
+	}
+	chanReceived := make(chan received, 1)
+	err := Subscribe(sub, "RoleLevelUp", AtMostOnce, func(msg *Message, payload *testPayload) {
+		chanReceived <- received{msg: msg, payload: payload, goid: profiler.GetGoroutineId()}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//AtLeastOnce订阅的主题不接收AtMostOnce消息
+	if err = Subscribe(sub, "RoleLogin", AtLeastOnce, func(msg *Message, payload *testPayload) {
+		t.Error("AtLeastOnce subscriber received AtMostOnce message")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	sub.Start()
+	defer sub.Stop()
+	serviceGoid := runOnService(t, sub, func() {})
+
+	loginMsg, _ := newMessage("RoleLogin", AtMostOnce, &testPayload{RoleId: 1})
+	if err = bus.RPC_Deliver(loginMsg, nil); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := newMessage("RoleLevelUp", AtMostOnce, &testPayload{RoleId: 1, Level: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = bus.RPC_Deliver(msg, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	//payload解码为订阅的类型，回调在订阅者的服务协程中执行
+	select {
+	case r := <-chanReceived:
+		if r.msg.MsgId != msg.MsgId || r.payload.RoleId != 1 || r.payload.Level != 10 {
+			t.Fatalf("unexpected message %+v %+v", r.msg, r.payload)
+		}
+		if r.goid != serviceGoid {
+			t.Fatalf("callback goroutine %d, want service goroutine %d", r.goid, serviceGoid)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message is not delivered")
+	}
+
+	//取消订阅后不再接收
+	runOnService(t, sub, func() {
+		Unsubscribe(sub, "RoleLevelUp")
+	})
+	if err = bus.RPC_Deliver(msg, nil); err != nil {
+		t.Fatal(err)
+	}
+	runOnService(t, sub, func() {})
+	if len(chanReceived) != 0 {
+		t.Fatal("message delivered after unsubscribe")
+	}
+}
+
+func newMQDeliverReq(t *testing.T, topic string, payloadList ...*testPayload) *rpc.DBQueuePublishReq {
+	t.Helper()
+	req := &rpc.DBQueuePublishReq{TopicName: getMQTopicName(topic)}
+	for _, payload := range payloadList {
+		msg, err := newMessage(topic, AtLeastOnce, payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		byteMsg, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.PushData = append(req.PushData, byteMsg)
+	}
+
+	return req
+}
+
+func TestAtLeastOnceDeliver(t *testing.T) {
+	bus := newTestEventBus(t)
+	sub := newTestSubscriber("EventBusTestSubscriber", 0)
+
+	release := make(chan struct{})
+	chanReceived := make(chan *testPayload, 3)
+	err := Subscribe(sub, "RoleRecharge", AtLeastOnce, func(msg *Message, payload *testPayload) {
+		<-release
+		chanReceived <- payload
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub.Start()
+	defer sub.Stop()
+
+	chanAck := make(chan rpc.RpcError, 1)
+	responder := rpc.Responder(func(_ interface{}, err rpc.RpcError) {
+		chanAck <- err
+	})
+
+	//所有回调返回后才确认
+	bus.RPC_MQDeliver(responder, newMQDeliverReq(t, "RoleRecharge", &testPayload{RoleId: 1}, &testPayload{RoleId: 2}))
+	select {
+	case <-chanAck:
+		t.Fatal("acked before callbacks return")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case err := <-chanAck:
+		if err != rpc.NilError {
+			t.Fatalf("unexpected ack error %s", err.Error())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message is not acked")
+	}
+	if len(chanReceived) != 2 || (<-chanReceived).RoleId != 1 || (<-chanReceived).RoleId != 2 {
+		t.Fatal("messages are not delivered in order")
+	}
+
+	//订阅者队列已满时投递失败，其他订阅者处理完成后回复错误
+	fullSub := newTestSubscriber("EventBusFullSubscriber", 1)
+	if err = Subscribe(fullSub, "RoleRecharge", AtLeastOnce, func(msg *Message, payload *testPayload) {}); err != nil {
+		t.Fatal(err)
+	}
+	if err = fullSub.PushEvent(&event.Event{Type: event.Sys_Event_EventBus}); err != nil {
+		t.Fatal(err)
+	}
+
+	bus.RPC_MQDeliver(responder, newMQDeliverReq(t, "RoleRecharge", &testPayload{RoleId: 3}))
+	select {
+	case err := <-chanAck:
+		if err == rpc.NilError {
+			t.Fatal("ack should fail when the subscriber queue is full")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message is not acked")
+	}
+	if payload := <-chanReceived; payload.RoleId != 3 {
+		t.Fatalf("unexpected payload %+v", payload)
+	}
+}
@@ -0,0 +1,333 @@
+package eventbusservice
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/cluster"
+	"github.com/duanhf2012/origin/v2/event"
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/rpc"
+	"github.com/duanhf2012/origin/v2/service"
+)
+
+const EventBusServiceName = "EventBusService"
+
+const (
+	mqSubscribeInterval = time.Second
+	mqOneBatchQuantity  = 100
+)
+
+// MQ订阅状态
+const (
+	mqUnsubscribed = 0
+	mqSubscribing  = 1
+	mqSubscribed   = 2
+)
+
+type topicHandler func(msg *Message)
+
+// mqDelivery AtLeastOnce模式投递给单个订阅者的消息，订阅者回调返回后调用ack
+type mqDelivery struct {
+	msg    *Message
+	target event.IEventHandler
+	ack    *mqAck
+}
+
+// mqAck 一批AtLeastOnce消息的确认，所有订阅者回调返回后才回复MessageQueueService
+type mqAck struct {
+	pending   int32
+	failed    atomic.Bool
+	responder rpc.Responder
+}
+
+func (ack *mqAck) add() {
+	atomic.AddInt32(&ack.pending, 1)
+}
+
+func (ack *mqAck) done() {
+	if atomic.AddInt32(&ack.pending, -1) != 0 {
+		return
+	}
+
+	if ack.failed.Load() == true {
+		ack.responder(&rpc.DBQueuePublishRes{}, rpc.ConvertError(errors.New("event bus message is not delivered")))
+		return
+	}
+	ack.responder(&rpc.DBQueuePublishRes{}, rpc.NilError)
+}
+
+type topicSubscriber struct {
+	mode    DeliveryMode
+	handler topicHandler
+}
+
+// EventBusService 集群事件总线，订阅者所在的结点需要部署
+// 使用时需要node.Setup(&eventbusservice.EventBusService{})，并在结点的ServiceList中配置EventBusService
+// AtLeastOnce模式需要集群中部署MessageQueueService
+type EventBusService struct {
+	service.Service
+
+	locker        sync.Mutex
+	mapTopic      map[string]map[event.IEventHandler]topicSubscriber //主题->订阅者
+	mapSubscriber map[event.IEventHandler]int                        //订阅者->订阅的主题数
+	mapMQTopic    map[string]int                                     //AtLeastOnce主题->MQ订阅状态，只在服务协程中访问
+
+	tickerId uint64
+}
+
+func getEventBus() *EventBusService {
+	bus, _ := service.GetService(EventBusServiceName).(*EventBusService)
+	return bus
+}
+
+func getLocalNodeId() string {
+	return cluster.GetCluster().GetLocalNodeInfo().NodeId
+}
+
+func (bs *EventBusService) OnInit() error {
+	bs.mapTopic = map[string]map[event.IEventHandler]topicSubscriber{}
+	bs.mapSubscriber = map[event.IEventHandler]int{}
+	bs.mapMQTopic = map[string]int{}
+
+	bs.RegDiscoverListener(bs)
+	bs.SafeNewTicker(&bs.tickerId, mqSubscribeInterval, nil, bs.checkMQSubscribe)
+	return nil
+}
+
+func (bs *EventBusService) subscribe(subscriber ISubscriber, topic string, mode DeliveryMode, handler topicHandler) {
+	eventHandler := subscriber.GetEventHandler()
+
+	bs.locker.Lock()
+	defer bs.locker.Unlock()
+
+	if _, ok := bs.mapTopic[topic]; ok == false {
+		bs.mapTopic[topic] = map[event.IEventHandler]topicSubscriber{}
+	}
+	if _, ok := bs.mapTopic[topic][eventHandler]; ok == false {
+		bs.mapSubscriber[eventHandler]++
+	}
+	bs.mapTopic[topic][eventHandler] = topicSubscriber{mode: mode, handler: handler}
+
+	//订阅者首次订阅时注册事件接收
+	if bs.mapSubscriber[eventHandler] == 1 {
+		bs.GetEventProcessor().RegEventReceiverFunc(event.Sys_Event_EventBus, eventHandler, func(ev event.IEvent) {
+			bs.onEventBusEvent(eventHandler, ev)
+		})
+	}
+}
+
+func (bs *EventBusService) unsubscribe(subscriber ISubscriber, topic string) {
+	eventHandler := subscriber.GetEventHandler()
+
+	bs.locker.Lock()
+	defer bs.locker.Unlock()
+
+	if _, ok := bs.mapTopic[topic][eventHandler]; ok == false {
+		return
+	}
+
+	delete(bs.mapTopic[topic], eventHandler)
+	if len(bs.mapTopic[topic]) == 0 {
+		delete(bs.mapTopic, topic)
+	}
+
+	bs.mapSubscriber[eventHandler]--
+	if bs.mapSubscriber[eventHandler] <= 0 {
+		delete(bs.mapSubscriber, eventHandler)
+		bs.GetEventProcessor().UnRegEventReceiverFun(event.Sys_Event_EventBus, eventHandler)
+	}
+}
+
+func (bs *EventBusService) getTopicSubscriber(topic string, eventHandler event.IEventHandler) (topicSubscriber, bool) {
+	bs.locker.Lock()
+	defer bs.locker.Unlock()
+
+	sub, ok := bs.mapTopic[topic][eventHandler]
+	return sub, ok
+}
+
+func (bs *EventBusService) hasSubscriber(topic string) bool {
+	bs.locker.Lock()
+	defer bs.locker.Unlock()
+
+	return len(bs.mapTopic[topic]) > 0
+}
+
+// getMQSubscriberList 获取主题中AtLeastOnce模式的订阅者
+func (bs *EventBusService) getMQSubscriberList(topic string) []event.IEventHandler {
+	bs.locker.Lock()
+	defer bs.locker.Unlock()
+
+	var subscriberList []event.IEventHandler
+	for eventHandler, sub := range bs.mapTopic[topic] {
+		if sub.mode == AtLeastOnce {
+			subscriberList = append(subscriberList, eventHandler)
+		}
+	}
+
+	return subscriberList
+}
+
+// getMQTopicList 获取需要通过MQ订阅的主题
+func (bs *EventBusService) getMQTopicList() []string {
+	bs.locker.Lock()
+	defer bs.locker.Unlock()
+
+	var topicList []string
+	for topic, mapSub := range bs.mapTopic {
+		for _, sub := range mapSub {
+			if sub.mode == AtLeastOnce {
+				topicList = append(topicList, topic)
+				break
+			}
+		}
+	}
+
+	return topicList
+}
+
+// onEventBusEvent 在订阅者所属服务协程中回调，只投递与订阅模式相同的消息
+func (bs *EventBusService) onEventBusEvent(eventHandler event.IEventHandler, ev event.IEvent) {
+	switch data := ev.(*event.Event).Data.(type) {
+	case *Message:
+		bs.onMessage(eventHandler, data)
+	case *mqDelivery:
+		//同一服务中的其他订阅者也会收到该事件，只处理投递给自己的
+		if data.target != eventHandler {
+			return
+		}
+
+		//回调panic时不确认，由MessageQueueService超时后重新投递
+		bs.onMessage(eventHandler, data.msg)
+		data.ack.done()
+	}
+}
+
+func (bs *EventBusService) onMessage(eventHandler event.IEventHandler, msg *Message) {
+	sub, ok := bs.getTopicSubscriber(msg.Topic, eventHandler)
+	if ok == false || sub.mode != msg.Mode {
+		return
+	}
+
+	sub.handler(msg)
+}
+
+// deliver 投递到本结点所有订阅者，事件被多个订阅者共享，不能回收
+func (bs *EventBusService) deliver(msg *Message) {
+	if bs.hasSubscriber(msg.Topic) == false {
+		return
+	}
+
+	bs.NotifyEvent(&event.Event{Type: event.Sys_Event_EventBus, Data: msg})
+}
+
+// RPC_Deliver 接收AtMostOnce模式的消息
+func (bs *EventBusService) RPC_Deliver(msg *Message, _ *service.Empty) error {
+	bs.deliver(msg)
+	return nil
+}
+
+// RPC_MQDeliver 接收MessageQueueService推送的AtLeastOnce模式消息，所有订阅者回调返回后再确认
+// 订阅者队列已满时回复错误，未确认的消息由MessageQueueService重新投递
+func (bs *EventBusService) RPC_MQDeliver(responder rpc.Responder, req *rpc.DBQueuePublishReq) {
+	ack := &mqAck{pending: 1, responder: responder}
+	for _, data := range req.PushData {
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			log.Error("unmarshal event bus message fail", log.String("topicName", req.TopicName), log.ErrorField("err", err))
+			continue
+		}
+
+		for _, eventHandler := range bs.getMQSubscriberList(msg.Topic) {
+			ack.add()
+			ev := &event.Event{Type: event.Sys_Event_EventBus, Data: &mqDelivery{msg: &msg, target: eventHandler, ack: ack}}
+			if err := eventHandler.GetEventProcessor().PushEvent(ev); err != nil {
+				log.Error("deliver event bus message fail", log.String("topic", msg.Topic), log.String("msgId", msg.MsgId), log.ErrorField("err", err))
+				ack.failed.Store(true)
+				ack.done()
+			}
+		}
+	}
+
+	ack.done()
+}
+
+// checkMQSubscribe 定时向MessageQueueService订阅尚未订阅的AtLeastOnce主题
+func (bs *EventBusService) checkMQSubscribe(tickerId uint64, _ interface{}) {
+	topicList := bs.getMQTopicList()
+	for topic, status := range bs.mapMQTopic {
+		if slices.Contains(topicList, topic) == false {
+			//已无订阅者，取消MQ订阅
+			if status == mqSubscribed {
+				bs.subscribeMQ(topic, rpc.SubscribeType_Unsubscribe)
+			}
+			delete(bs.mapMQTopic, topic)
+		}
+	}
+
+	for _, topic := range topicList {
+		if bs.mapMQTopic[topic] != mqUnsubscribed {
+			continue
+		}
+
+		bs.mapMQTopic[topic] = mqSubscribing
+		bs.subscribeMQ(topic, rpc.SubscribeType_Subscribe)
+	}
+}
+
+func (bs *EventBusService) subscribeMQ(topic string, subType rpc.SubscribeType) {
+	req := rpc.DBQueueSubscribeReq{
+		SubType:          subType,
+		Method:           rpc.SubscribeMethod_Method_Last,
+		CustomerId:       "EventBus_" + getLocalNodeId(),
+		FromNodeId:       getLocalNodeId(),
+		RpcMethod:        EventBusServiceName + ".RPC_MQDeliver",
+		TopicName:        getMQTopicName(topic),
+		OneBatchQuantity: mqOneBatchQuantity,
+	}
+
+	err := bs.AsyncCall("MessageQueueService.RPC_Subscribe", &req, func(res *rpc.DBQueueSubscribeRes, err error) {
+		if subType == rpc.SubscribeType_Unsubscribe {
+			return
+		}
+
+		if _, ok := bs.mapMQTopic[topic]; ok == false {
+			return
+		}
+
+		if err != nil {
+			log.Warn("subscribe event bus topic fail", log.String("topic", topic), log.ErrorField("err", err))
+			bs.mapMQTopic[topic] = mqUnsubscribed
+			return
+		}
+
+		bs.mapMQTopic[topic] = mqSubscribed
+		log.Info("subscribe event bus topic", log.String("topic", topic))
+	})
+
+	if err != nil && subType == rpc.SubscribeType_Subscribe {
+		bs.mapMQTopic[topic] = mqUnsubscribed
+	}
+}
+
+// OnDiscoveryService MessageQueueService结点变化时重新订阅
+func (bs *EventBusService) OnDiscoveryService(nodeId string, serviceName []string) {
+	if slices.Contains(serviceName, "MessageQueueService") == false {
+		return
+	}
+
+	for topic, status := range bs.mapMQTopic {
+		if status == mqSubscribed {
+			bs.mapMQTopic[topic] = mqUnsubscribed
+		}
+	}
+}
+
+func (bs *EventBusService) OnUnDiscoveryService(nodeId string, serviceName []string) {
+	bs.OnDiscoveryService(nodeId, serviceName)
+}
@@ -14,9 +14,16 @@ import (
 // Seconds      | No         | 0-59           | * / , -
 // Minutes      | Yes        | 0-59           | * / , -
 // Hours        | Yes        | 0-23           | * / , -
-// Day of month | Yes        | 1-31           | * / , -
+// Day of month | Yes        | 1-31           | * / , - ? L W
 // Month        | Yes        | 1-12           | * / , -
-// Day of week  | Yes        | 0-6            | * / , -
+// Day of week  | Yes        | 0-7            | * / , - ? L #
+//
+// 表达式前可以加TZ=或CRON_TZ=指定IANA时区，如"TZ=Asia/Shanghai 0 0 5 * * *"，不指定时使用传入时间的时区
+// L: 日期中表示月末最后一天，L-n表示倒数第n+1天，LW表示月末最后一个工作日；星期中nL表示当月最后一个星期n
+// W: 日期中nW表示离n号最近的工作日，不会跨月
+// #: 星期中n#k表示当月第k个星期n
+// 日期与星期都有限制时满足其一即可
+// 夏令时跳过的时间在跳变时刻触发，回拨后重复的时间只在第一次出现时触发
 type CronExpr struct {
 	sec   uint64
 	min   uint64
@@ -24,21 +31,44 @@ type CronExpr struct {
 	dom   uint64
 	month uint64
 	dow   uint64
+
+	domBlank       bool     //日期不限制
+	dowBlank       bool     //星期不限制
+	lastDom        []int    //L与L-n，月末倒数的天数
+	lastWeekday    bool     //LW
+	nearestWeekday uint64   //nW
+	lastDow        uint64   //nL
+	nthDow         [][2]int //n#k，[星期,第几个]
+
+	loc  *time.Location //为nil时使用传入时间的时区
+	expr string
 }
 
+// cronSearchYears 查找触发时间的最大年数，覆盖2月29日等间隔多年的表达式
+const cronSearchYears = 10
+
 // goroutine safe
 func NewCronExpr(expr string) (cronExpr *CronExpr, err error) {
 	fields := strings.Fields(expr)
+	cronExpr = &CronExpr{expr: expr}
+	if len(fields) > 0 && (strings.HasPrefix(fields[0], "TZ=") || strings.HasPrefix(fields[0], "CRON_TZ=")) {
+		tz := fields[0][strings.Index(fields[0], "=")+1:]
+		cronExpr.loc, err = time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expr %v: unknown time zone %v", expr, tz)
+		}
+		fields = fields[1:]
+	}
+
 	if len(fields) != 5 && len(fields) != 6 {
 		err = fmt.Errorf("invalid expr %v: expected 5 or 6 fields, got %v", expr, len(fields))
-		return
+		return nil, err
 	}
 
 	if len(fields) == 5 {
 		fields = append([]string{"0"}, fields...)
 	}
 
-	cronExpr = new(CronExpr)
 	// Seconds
 	cronExpr.sec, err = parseCronField(fields[0], 0, 59)
 	if err != nil {
@@ -55,7 +85,7 @@ func NewCronExpr(expr string) (cronExpr *CronExpr, err error) {
 		goto onError
 	}
 	// Day of month
-	cronExpr.dom, err = parseCronField(fields[3], 1, 31)
+	err = cronExpr.parseDom(fields[3])
 	if err != nil {
 		goto onError
 	}
@@ -65,15 +95,115 @@ func NewCronExpr(expr string) (cronExpr *CronExpr, err error) {
 		goto onError
 	}
 	// Day of week
-	cronExpr.dow, err = parseCronField(fields[5], 0, 6)
+	err = cronExpr.parseDow(fields[5])
 	if err != nil {
 		goto onError
 	}
-	return
+	return cronExpr, nil
 
 onError:
 	err = fmt.Errorf("invalid expr %v: %v", expr, err)
-	return
+	return nil, err
+}
+
+// parseDom 解析日期，支持?、L、L-n、LW、nW
+func (e *CronExpr) parseDom(field string) (err error) {
+	if field == "?" {
+		field = "*"
+	}
+
+	var rangeList []string
+	for _, item := range strings.Split(field, ",") {
+		switch {
+		case item == "L":
+			e.lastDom = append(e.lastDom, 0)
+		case item == "LW":
+			e.lastWeekday = true
+		case strings.HasPrefix(item, "L-"):
+			offset, err := strconv.Atoi(item[2:])
+			if err != nil || offset < 0 || offset > 30 {
+				return fmt.Errorf("invalid last day offset: %v", item)
+			}
+			e.lastDom = append(e.lastDom, offset)
+		case strings.HasSuffix(item, "W"):
+			day, err := strconv.Atoi(item[:len(item)-1])
+			if err != nil || day < 1 || day > 31 {
+				return fmt.Errorf("invalid nearest weekday: %v", item)
+			}
+			e.nearestWeekday |= 1 << uint(day)
+		default:
+			rangeList = append(rangeList, item)
+		}
+	}
+
+	if len(rangeList) > 0 {
+		e.dom, err = parseCronField(strings.Join(rangeList, ","), 1, 31)
+		if err != nil {
+			return err
+		}
+	}
+
+	e.domBlank = e.dom == 0xfffffffe && len(e.lastDom) == 0 && e.lastWeekday == false && e.nearestWeekday == 0
+	return nil
+}
+
+// parseDow 解析星期，支持?、7(星期日)、nL、n#k
+func (e *CronExpr) parseDow(field string) (err error) {
+	if field == "?" {
+		field = "*"
+	}
+
+	var rangeList []string
+	for _, item := range strings.Split(field, ",") {
+		switch {
+		case strings.HasSuffix(item, "L"):
+			weekday, err := strconv.Atoi(item[:len(item)-1])
+			if err != nil || weekday < 0 || weekday > 7 {
+				return fmt.Errorf("invalid last weekday: %v", item)
+			}
+			e.lastDow |= 1 << uint(weekday%7)
+		case strings.Contains(item, "#"):
+			weekdayAndNth := strings.Split(item, "#")
+			if len(weekdayAndNth) != 2 {
+				return fmt.Errorf("invalid nth weekday: %v", item)
+			}
+			weekday, err := strconv.Atoi(weekdayAndNth[0])
+			if err != nil || weekday < 0 || weekday > 7 {
+				return fmt.Errorf("invalid nth weekday: %v", item)
+			}
+			nth, err := strconv.Atoi(weekdayAndNth[1])
+			if err != nil || nth < 1 || nth > 5 {
+				return fmt.Errorf("invalid nth weekday: %v", item)
+			}
+			e.nthDow = append(e.nthDow, [2]int{weekday % 7, nth})
+		default:
+			rangeList = append(rangeList, item)
+		}
+	}
+
+	if len(rangeList) > 0 {
+		e.dow, err = parseCronField(strings.Join(rangeList, ","), 0, 7)
+		if err != nil {
+			return err
+		}
+		//7与0都表示星期日
+		if e.dow&(1<<7) != 0 {
+			e.dow = e.dow&^(1<<7) | 1
+		}
+	}
+
+	e.dowBlank = e.dow == 0x7f && e.lastDow == 0 && len(e.nthDow) == 0
+	return nil
+}
+
+// String 返回原始表达式
+func (e *CronExpr) String() string {
+	return e.expr
+}
+
+// Location 返回表达式指定的时区，未指定时返回nil
+func (e *CronExpr) Location() *time.Location {
+	return e.loc
 }
 
 // 1. *
@@ -171,98 +301,237 @@ func parseCronField(field string, min int, max int) (cronField uint64, err error
 	return
 }
 
-func (e *CronExpr) matchDay(t time.Time) bool {
-	// day-of-month blank
-	if e.dom == 0xfffffffe {
-		return 1<<uint(t.Weekday())&e.dow != 0
-	}
+func daysInMonth(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 12, 0, 0, 0, time.UTC).Day()
+}
 
-	// day-of-week blank
-	if e.dow == 0x7f {
-		return 1<<uint(t.Day())&e.dom != 0
+func weekdayOf(year int, month time.Month, day int) time.Weekday {
+	return time.Date(year, month, day, 12, 0, 0, 0, time.UTC).Weekday()
+}
+
+// dayMask 计算某月满足日期与星期的天，第n位表示n号
+func (e *CronExpr) dayMask(year int, month time.Month) uint64 {
+	lastDay := daysInMonth(year, month)
+	monthMask := ^(uint64(math.MaxUint64) << uint(lastDay+1)) &^ 1
+	if e.domBlank && e.dowBlank {
+		return monthMask
 	}
 
-	return 1<<uint(t.Weekday())&e.dow != 0 ||
-		1<<uint(t.Day())&e.dom != 0
-}
+	var domMask uint64
+	if e.domBlank == false {
+		domMask = e.dom
+		for _, offset := range e.lastDom {
+			if day := lastDay - offset; day >= 1 {
+				domMask |= 1 << uint(day)
+			}
+		}
 
-// goroutine safe
-func (e *CronExpr) Next(t time.Time) time.Time {
-	// the upcoming second
-	t = t.Truncate(time.Second).Add(time.Second)
+		if e.lastWeekday {
+			day := lastDay
+			switch weekdayOf(year, month, day) {
+			case time.Saturday:
+				day -= 1
+			case time.Sunday:
+				day -= 2
+			}
+			domMask |= 1 << uint(day)
+		}
 
-	year := t.Year()
-	initFlag := false
+		for day := 1; day <= lastDay; day++ {
+			if e.nearestWeekday&(1<<uint(day)) == 0 {
+				continue
+			}
 
-retry:
-	// Year
-	if t.Year() > year+1 {
-		return time.Time{}
+			nearest := day
+			switch weekdayOf(year, month, day) {
+			case time.Saturday:
+				if day > 1 {
+					nearest = day - 1
+				} else {
+					nearest = day + 2
+				}
+			case time.Sunday:
+				if day < lastDay {
+					nearest = day + 1
+				} else {
+					nearest = day - 2
+				}
+			}
+			domMask |= 1 << uint(nearest)
+		}
+		domMask &= monthMask
 	}
 
-	// Month
-	for 1<<uint(t.Month())&e.month == 0 {
-		if !initFlag {
-			initFlag = true
-			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	var dowMask uint64
+	if e.dowBlank == false {
+		firstWeekday := int(weekdayOf(year, month, 1))
+		for day := 1; day <= lastDay; day++ {
+			if e.dow&(1<<uint((firstWeekday+day-1)%7)) != 0 {
+				dowMask |= 1 << uint(day)
+			}
+		}
+
+		lastWeekday := int(weekdayOf(year, month, lastDay))
+		for weekday := 0; weekday < 7; weekday++ {
+			if e.lastDow&(1<<uint(weekday)) != 0 {
+				dowMask |= 1 << uint(lastDay-(lastWeekday-weekday+7)%7)
+			}
 		}
 
-		t = t.AddDate(0, 1, 0)
-		if t.Month() == time.January {
-			goto retry
+		for _, nthDow := range e.nthDow {
+			day := 1 + (nthDow[0]-firstWeekday+7)%7 + (nthDow[1]-1)*7
+			if day <= lastDay {
+				dowMask |= 1 << uint(day)
+			}
 		}
 	}
 
-	// Day
-	for !e.matchDay(t) {
-		if !initFlag {
-			initFlag = true
-			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if e.domBlank {
+		return dowMask
+	}
+	if e.dowBlank {
+		return domMask
+	}
+
+	return domMask | dowMask
+}
+
+func (e *CronExpr) getLocation(t time.Time) *time.Location {
+	if e.loc != nil {
+		return e.loc
+	}
+
+	return t.Location()
+}
+
+// cronTime 墙上时间对应的触发时刻
+// 夏令时跳过的时间返回跳变时刻，回拨后重复的时间返回第一次出现的时刻
+func cronTime(year int, month time.Month, day int, hour int, min int, sec int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, hour, min, sec, 0, loc)
+	wall := time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+	tWall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	if tWall.Equal(wall) == false {
+		start, end := t.ZoneBounds()
+		if tWall.Before(wall) {
+			return end
 		}
+		return start
+	}
 
-		t = t.AddDate(0, 0, 1)
-		if t.Day() == 1 {
-			goto retry
+	for _, d := range []time.Duration{2 * time.Hour, time.Hour, 30 * time.Minute} {
+		earlier := t.Add(-d)
+		if earlier.Hour() == hour && earlier.Minute() == min && earlier.Second() == sec && earlier.Day() == day {
+			return earlier
 		}
 	}
 
-	// Hours
-	for 1<<uint(t.Hour())&e.hour == 0 {
-		if !initFlag {
-			initFlag = true
-			t = t.Truncate(time.Hour)
+	return t
+}
+
+func forEachBit(mask uint64, from int, to int, desc bool, fn func(i int) bool) bool {
+	if desc {
+		for i := to; i >= from; i-- {
+			if mask&(1<<uint(i)) != 0 && fn(i) {
+				return true
+			}
 		}
+		return false
+	}
 
-		t = t.Add(time.Hour)
-		if t.Hour() == 0 {
-			goto retry
+	for i := from; i <= to; i++ {
+		if mask&(1<<uint(i)) != 0 && fn(i) {
+			return true
 		}
 	}
+	return false
+}
 
-	// Minutes
-	for 1<<uint(t.Minute())&e.min == 0 {
-		if !initFlag {
-			initFlag = true
-			t = t.Truncate(time.Minute)
+// search 按墙上时间逐级查找第一个晚于(desc时早于)t的触发时间
+func (e *CronExpr) search(t time.Time, desc bool) time.Time {
+	loc := e.getLocation(t)
+	t = t.In(loc)
+	y0, mo0, d0 := t.Date()
+	h0, mi0, s0 := t.Clock()
+
+	//desc为false时从t开始向后查找，否则向前查找，与t相同的墙上时间之前(后)的部分直接跳过
+	bound := func(same bool, cur int, min int, max int) (int, int) {
+		if same == false {
+			return min, max
+		}
+		if desc {
+			return min, cur
 		}
+		return cur, max
+	}
 
-		t = t.Add(time.Minute)
-		if t.Minute() == 0 {
-			goto retry
+	var result time.Time
+	match := func(c time.Time) bool {
+		if (desc == false && c.After(t)) || (desc && c.Before(t)) {
+			result = c
+			return true
 		}
+		return false
 	}
 
-	// Seconds
-	for 1<<uint(t.Second())&e.sec == 0 {
-		if !initFlag {
-			initFlag = true
+	for i := 0; i <= cronSearchYears; i++ {
+		year := y0 + i
+		if desc {
+			year = y0 - i
 		}
 
-		t = t.Add(time.Second)
-		if t.Second() == 0 {
-			goto retry
+		sameYear := year == y0
+		from, to := bound(sameYear, int(mo0), 1, 12)
+		found := forEachBit(e.month, from, to, desc, func(month int) bool {
+			sameMonth := sameYear && month == int(mo0)
+			dayMask := e.dayMask(year, time.Month(month))
+			from, to := bound(sameMonth, d0, 1, 31)
+			return forEachBit(dayMask, from, to, desc, func(day int) bool {
+				sameDay := sameMonth && day == d0
+				from, to := bound(sameDay, h0, 0, 23)
+				return forEachBit(e.hour, from, to, desc, func(hour int) bool {
+					sameHour := sameDay && hour == h0
+					from, to := bound(sameHour, mi0, 0, 59)
+					return forEachBit(e.min, from, to, desc, func(min int) bool {
+						sameMin := sameHour && min == mi0
+						from, to := bound(sameMin, s0, 0, 59)
+						return forEachBit(e.sec, from, to, desc, func(sec int) bool {
+							return match(cronTime(year, time.Month(month), day, hour, min, sec, loc))
+						})
+					})
+				})
+			})
+		})
+
+		if found {
+			return result
 		}
 	}
 
-	return t
+	return time.Time{}
+}
+
+// Next 返回晚于t的下一个触发时间，没有时返回零值
+// goroutine safe
+func (e *CronExpr) Next(t time.Time) time.Time {
+	return e.search(t, false)
+}
+
+// Prev 返回早于t的上一个触发时间，没有时返回零值
+// goroutine safe
+func (e *CronExpr) Prev(t time.Time) time.Time {
+	return e.search(t, true)
+}
+
+// NextN 返回晚于t的n个触发时间，用于预览
+func (e *CronExpr) NextN(t time.Time, n int) []time.Time {
+	timeList := make([]time.Time, 0, n)
+	for i := 0; i < n; i++ {
+		t = e.Next(t)
+		if t.IsZero() {
+			break
+		}
+		timeList = append(timeList, t)
+	}
+
+	return timeList
 }
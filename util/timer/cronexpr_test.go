@@ -0,0 +1,242 @@
+package timer
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+const cronTestLayout = "2006-01-02 15:04:05 MST"
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestCronExprNext(t *testing.T) {
+	testList := []struct {
+		expr   string
+		from   string
+		expect []string
+	}{
+		//秒
+		{"*/20 * * * * *", "2024-01-01 00:00:10", []string{"2024-01-01 00:00:20", "2024-01-01 00:00:40", "2024-01-01 00:01:00"}},
+		{"0 0 5 * * *", "2024-01-01 05:00:00", []string{"2024-01-02 05:00:00", "2024-01-03 05:00:00"}},
+		//5个字段时秒为0
+		{"30 4 1,15 * *", "2024-01-01 04:30:00", []string{"2024-01-15 04:30:00", "2024-02-01 04:30:00"}},
+		//跨年
+		{"0 0 0 1 1 *", "2024-06-01 00:00:00", []string{"2025-01-01 00:00:00", "2026-01-01 00:00:00"}},
+		//2月29日
+		{"0 0 0 29 2 *", "2024-03-01 00:00:00", []string{"2028-02-29 00:00:00", "2032-02-29 00:00:00"}},
+		//日期与星期都有限制时满足其一
+		{"0 0 0 13 * 5", "2024-09-01 00:00:00", []string{"2024-09-06 00:00:00", "2024-09-13 00:00:00", "2024-09-20 00:00:00", "2024-09-27 00:00:00", "2024-10-04 00:00:00"}},
+		//?与7
+		{"0 0 12 ? * 7", "2024-09-01 12:00:00", []string{"2024-09-08 12:00:00", "2024-09-15 12:00:00"}},
+		{"0 0 12 * * 5-7", "2024-09-05 00:00:00", []string{"2024-09-06 12:00:00", "2024-09-07 12:00:00", "2024-09-08 12:00:00", "2024-09-13 12:00:00"}},
+		//月末
+		{"0 0 0 L * *", "2024-01-15 00:00:00", []string{"2024-01-31 00:00:00", "2024-02-29 00:00:00", "2024-03-31 00:00:00", "2024-04-30 00:00:00"}},
+		{"0 0 0 L-2 * *", "2023-01-31 00:00:00", []string{"2023-02-26 00:00:00", "2023-03-29 00:00:00"}},
+		//月末最后一个工作日，2024-03-31为星期日，2024-08-31为星期六
+		{"0 0 18 LW * *", "2024-03-01 00:00:00", []string{"2024-03-29 18:00:00", "2024-04-30 18:00:00", "2024-05-31 18:00:00", "2024-06-28 18:00:00", "2024-07-31 18:00:00", "2024-08-30 18:00:00"}},
+		//最近工作日，2024-06-15为星期六，2024-09-15为星期日，2024-06-01为星期六不跨月
+		{"0 0 9 15W * *", "2024-06-01 00:00:00", []string{"2024-06-14 09:00:00", "2024-07-15 09:00:00", "2024-08-15 09:00:00", "2024-09-16 09:00:00"}},
+		{"0 0 9 1W * *", "2024-05-31 00:00:00", []string{"2024-06-03 09:00:00", "2024-07-01 09:00:00"}},
+		//2024-03-31为星期日，最近工作日不跨月
+		{"0 0 9 31W 3 *", "2024-01-01 00:00:00", []string{"2024-03-29 09:00:00", "2025-03-31 09:00:00"}},
+		//最后一个星期五
+		{"0 0 20 * * 5L", "2024-01-01 00:00:00", []string{"2024-01-26 20:00:00", "2024-02-23 20:00:00", "2024-03-29 20:00:00", "2024-04-26 20:00:00", "2024-05-31 20:00:00"}},
+		//第二个星期一，第五个星期日
+		{"0 0 10 * * 1#2", "2024-01-01 00:00:00", []string{"2024-01-08 10:00:00", "2024-02-12 10:00:00", "2024-03-11 10:00:00"}},
+		{"0 0 10 * * 0#5", "2024-01-01 00:00:00", []string{"2024-03-31 10:00:00", "2024-06-30 10:00:00", "2024-09-29 10:00:00"}},
+		{"0 0 10 * * 1#1,5L", "2024-01-01 00:00:00", []string{"2024-01-01 10:00:00", "2024-01-26 10:00:00", "2024-02-05 10:00:00"}},
+	}
+
+	for _, test := range testList {
+		cronExpr, err := NewCronExpr(test.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", test.expr, err)
+		}
+
+		from, _ := time.ParseInLocation(time.DateTime, test.from, time.UTC)
+		timeList := cronExpr.NextN(from, len(test.expect))
+		if len(timeList) != len(test.expect) {
+			t.Fatalf("%s: expect %d times, got %v", test.expr, len(test.expect), timeList)
+		}
+
+		for i, expect := range test.expect {
+			if got := timeList[i].Format(time.DateTime); got != expect {
+				t.Fatalf("%s: #%d expect %s, got %s", test.expr, i, expect, got)
+			}
+		}
+	}
+}
+
+func TestCronExprPrev(t *testing.T) {
+	testList := []struct {
+		expr   string
+		from   string
+		expect string
+	}{
+		{"0 0 5 * * *", "2024-01-02 05:00:00", "2024-01-01 05:00:00"},
+		{"0 0 5 * * *", "2024-01-02 05:00:01", "2024-01-02 05:00:00"},
+		{"*/20 * * * * *", "2024-01-01 00:00:00", "2023-12-31 23:59:40"},
+		{"0 0 0 L * *", "2024-03-15 00:00:00", "2024-02-29 00:00:00"},
+		{"0 0 20 * * 5L", "2024-03-29 20:00:00", "2024-02-23 20:00:00"},
+		{"0 0 9 15W * *", "2024-07-01 00:00:00", "2024-06-14 09:00:00"},
+		{"0 0 0 29 2 *", "2024-02-28 00:00:00", "2020-02-29 00:00:00"},
+	}
+
+	for _, test := range testList {
+		cronExpr, err := NewCronExpr(test.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", test.expr, err)
+		}
+
+		from, _ := time.ParseInLocation(time.DateTime, test.from, time.UTC)
+		if got := cronExpr.Prev(from).Format(time.DateTime); got != test.expect {
+			t.Fatalf("%s: prev of %s expect %s, got %s", test.expr, test.from, test.expect, got)
+		}
+	}
+}
+
+// TestCronExprPrevNext 在各时区中Prev(Next(t))与Next(Prev(t))应当互逆
+func TestCronExprPrevNext(t *testing.T) {
+	exprList := []string{"0 30 2 * * *", "0 0 */3 * * *", "0 0 0 L * *", "0 15 1 * * 0#1", "0 0 12 LW * *"}
+	zoneList := []string{"UTC", "America/New_York", "Europe/London", "Australia/Sydney", "Asia/Shanghai"}
+	for _, zone := range zoneList {
+		loc := mustLoadLocation(t, zone)
+		for _, expr := range exprList {
+			cronExpr, err := NewCronExpr(expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, loc)
+			timeList := cronExpr.NextN(now, 50)
+			for i := 1; i < len(timeList); i++ {
+				if timeList[i].After(timeList[i-1]) == false {
+					t.Fatalf("%s %s: times are not increasing %v %v", zone, expr, timeList[i-1], timeList[i])
+				}
+				if prev := cronExpr.Prev(timeList[i]); prev.Equal(timeList[i-1]) == false {
+					t.Fatalf("%s %s: prev of %v expect %v, got %v", zone, expr, timeList[i], timeList[i-1], prev)
+				}
+			}
+		}
+	}
+}
+
+func TestCronExprTimeZone(t *testing.T) {
+	cronExpr, err := NewCronExpr("TZ=Asia/Shanghai 0 0 5 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	next := cronExpr.Next(from)
+	if next.Equal(time.Date(2024, 1, 1, 21, 0, 0, 0, time.UTC)) == false || next.Location().String() != "Asia/Shanghai" {
+		t.Fatalf("unexpected next time %v", next)
+	}
+
+	cronExpr, err = NewCronExpr("CRON_TZ=America/New_York 0 0 9 * * 1-5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//2024-11-04为星期一，夏令时已结束
+	next = cronExpr.Next(time.Date(2024, 11, 2, 0, 0, 0, 0, time.UTC))
+	if next.UTC().Equal(time.Date(2024, 11, 4, 14, 0, 0, 0, time.UTC)) == false {
+		t.Fatalf("unexpected next time %v", next)
+	}
+
+	if _, err = NewCronExpr("TZ=Mars/Olympus 0 0 5 * * *"); err == nil {
+		t.Fatal("unknown time zone should fail")
+	}
+}
+
+func TestCronExprDST(t *testing.T) {
+	loc := mustLoadLocation(t, "America/New_York")
+
+	testList := []struct {
+		expr   string
+		from   time.Time
+		expect []string
+	}{
+		//2024-03-10 02:30不存在，在跳变时刻03:00触发
+		{"0 30 2 * * *", time.Date(2024, 3, 9, 12, 0, 0, 0, loc), []string{"2024-03-10 03:00:00 EDT", "2024-03-11 02:30:00 EDT"}},
+		//跳过的整点
+		{"0 0 * * * *", time.Date(2024, 3, 10, 0, 30, 0, 0, loc), []string{"2024-03-10 01:00:00 EST", "2024-03-10 03:00:00 EDT", "2024-03-10 04:00:00 EDT"}},
+		//2024-11-03 01:30出现两次，只在第一次触发
+		{"0 30 1 * * *", time.Date(2024, 11, 2, 12, 0, 0, 0, loc), []string{"2024-11-03 01:30:00 EDT", "2024-11-04 01:30:00 EST"}},
+		{"0 0 * * * *", time.Date(2024, 11, 3, 0, 30, 0, 0, loc), []string{"2024-11-03 01:00:00 EDT", "2024-11-03 02:00:00 EST", "2024-11-03 03:00:00 EST"}},
+		//每分钟的表达式在跳变前后连续
+		{"0 59 1 * * *", time.Date(2024, 3, 10, 0, 0, 0, 0, loc), []string{"2024-03-10 01:59:00 EST", "2024-03-11 01:59:00 EDT"}},
+	}
+
+	for _, test := range testList {
+		cronExpr, err := NewCronExpr(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		timeList := cronExpr.NextN(test.from, len(test.expect))
+		for i, expect := range test.expect {
+			if i >= len(timeList) {
+				t.Fatalf("%s: missing #%d", test.expr, i)
+			}
+			if got := timeList[i].Format(cronTestLayout); got != expect {
+				t.Fatalf("%s: #%d expect %s, got %s", test.expr, i, expect, got)
+			}
+		}
+	}
+
+	//回拨后重复的时间段内调用Next不会再次触发
+	cronExpr, _ := NewCronExpr("0 30 1 * * *")
+	secondOneThirty := time.Date(2024, 11, 3, 5, 40, 0, 0, time.UTC).In(loc) //01:40 EDT
+	next := cronExpr.Next(secondOneThirty)
+	if got := next.Format(cronTestLayout); got != "2024-11-04 01:30:00 EST" {
+		t.Fatalf("unexpected next after fall back %s", got)
+	}
+}
+
+func TestCronExprInvalid(t *testing.T) {
+	exprList := []string{
+		"",
+		"* * * *",
+		"* * * * * * *",
+		"60 * * * * *",
+		"* * 24 * * *",
+		"* * * 0 * *",
+		"* * * 32 * *",
+		"* * * * 13 *",
+		"* * * * * 8",
+		"* * * L-31 * *",
+		"* * * 32W * *",
+		"* * * * * 8L",
+		"* * * * * 1#6",
+		"* * * * * 1#0",
+		"* * * * * 1#2#3",
+	}
+
+	for _, expr := range exprList {
+		if _, err := NewCronExpr(expr); err == nil {
+			t.Fatalf("%q should be invalid", expr)
+		}
+	}
+}
+
+func TestCronExprNever(t *testing.T) {
+	//2月30日不存在
+	cronExpr, err := NewCronExpr("0 0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if next := cronExpr.Next(time.Now()); next.IsZero() == false {
+		t.Fatalf("unexpected next time %v", next)
+	}
+	if timeList := cronExpr.NextN(time.Now(), 3); len(timeList) != 0 {
+		t.Fatalf("unexpected next times %v", timeList)
+	}
+}
@@ -12,6 +12,11 @@ func SetupTimer(timer ITimer) ITimer {
 	}
 
 	timer.Open(true)
+	if timerWheel != nil && IsVirtualClock() == false {
+		timerWheel.add(timer)
+		return timer
+	}
+
 	timerHeapLock.Lock() // 使用锁规避竞争条件
 	heap.Push(&timerHeap, timer)
 	timerHeapLock.Unlock()
@@ -56,13 +61,40 @@ func (h *_TimerHeap) Pop() (ret interface{}) {
 	return
 }
 
+// TimerBackend 定时器的调度实现
+type TimerBackend int
+
+const (
+	HeapBackend  TimerBackend = 0 //全局最小堆，默认
+	WheelBackend TimerBackend = 1 //分层时间轮，适合大量活跃定时器，精度为minTimerInterval
+)
+
 var (
-	timerHeap     _TimerHeap // 定时器heap对象
-	timerHeapLock sync.Mutex // 一个全局的锁
+	timerHeap     _TimerHeap   // 定时器heap对象
+	timerHeapLock sync.Mutex   // 一个全局的锁
+	timerWheel    *timingWheel // 使用WheelBackend时的时间轮
 	timeOffset    time.Duration
 )
 
 func StartTimer(minTimerInterval time.Duration, maxTimerNum int) {
+	StartTimerWithBackend(HeapBackend, minTimerInterval, maxTimerNum)
+}
+
+// StartTimerWithBackend 使用指定的调度实现启动定时器，需要在创建定时器前调用
+// 开启虚拟时钟时固定使用最小堆，且不启动驱动协程，由Advance推进
+func StartTimerWithBackend(backend TimerBackend, minTimerInterval time.Duration, maxTimerNum int) {
+	if IsVirtualClock() == true {
+		timerHeap.timers = make([]ITimer, 0, maxTimerNum)
+		heap.Init(&timerHeap)
+		return
+	}
+
+	if backend == WheelBackend {
+		timerWheel = newTimingWheel(Now(), minTimerInterval)
+		go wheelTickRoutine(timerWheel, minTimerInterval)
+		return
+	}
+
 	timerHeap.timers = make([]ITimer, 0, maxTimerNum)
 	heap.Init(&timerHeap) // 初始化定时器heap
 
@@ -80,6 +112,10 @@ func tickRoutine(minTimerInterval time.Duration) {
 }
 
 func tick() bool {
+	if IsVirtualClock() == true {
+		return false
+	}
+
 	now := Now()
 	timerHeapLock.Lock()
 	if timerHeap.Len() <= 0 { // 没有任何定时器，立刻返回
@@ -101,6 +137,10 @@ func tick() bool {
 }
 
 func Now() time.Time {
+	if IsVirtualClock() == true {
+		return getVirtualNow().Add(timeOffset)
+	}
+
 	if timeOffset == 0 {
 		return time.Now()
 	}
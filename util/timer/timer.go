@@ -174,14 +174,19 @@ func (t *Timer) IsActive() bool {
 
 func (t *Timer) GetName() string {
 	if t.cb != nil {
-		return runtime.FuncForPC(reflect.ValueOf(t.cb).Pointer()).Name()
+		return GetFuncName(t.cb)
 	} else if t.cbEx != nil {
-		return runtime.FuncForPC(reflect.ValueOf(t.cbEx).Pointer()).Name()
+		return GetFuncName(t.cbEx)
 	}
 
 	return ""
 }
 
+// GetFuncName 获取回调函数名，与定时器的GetName一致
+func GetFuncName(cb interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(cb).Pointer()).Name()
+}
+
 var emptyTimer Timer
 
 func (t *Timer) Reset() {
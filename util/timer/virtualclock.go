@@ -0,0 +1,221 @@
+package timer
+
+import (
+	"container/heap"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/log"
+)
+
+// 虚拟时钟，用于测试。开启后Now只在调用Advance时前进，定时器不再由后台协程驱动，
+// Advance按触发时间顺序逐个推进，每一步等待定时器回调执行完成后再继续，与正式环境的触发顺序一致
+
+// IVirtualClockListener 虚拟时钟的监听者，用于Rpc超时、帧定时器等不使用全局定时器的模块
+type IVirtualClockListener interface {
+	NextFireTime() (time.Time, bool) //下次需要处理的时间，没有时返回false
+	OnClock(now time.Time)           //时钟推进到now时调用
+}
+
+const defaultVirtualTaskTimeout = 10 * time.Second
+
+var (
+	virtualClock        atomic.Bool
+	virtualNowLock      sync.RWMutex
+	virtualNow          time.Time
+	virtualAdvanceLock  sync.Mutex
+	virtualTaskNum      atomic.Int64
+	virtualTaskTimeout  = defaultVirtualTaskTimeout
+	virtualListenerLock sync.Mutex
+	virtualListeners    []IVirtualClockListener
+)
+
+// virtualTimer 虚拟时钟下派发的定时器，回调执行完成后通知Advance
+type virtualTimer struct {
+	ITimer
+}
+
+func (vt virtualTimer) Do() {
+	defer DoneVirtualTask()
+	vt.ITimer.Do()
+}
+
+// EnableVirtualClock 开启虚拟时钟，时间从start开始，需要在StartTimer与创建定时器前调用
+func EnableVirtualClock(start time.Time) {
+	virtualNowLock.Lock()
+	virtualNow = start
+	virtualNowLock.Unlock()
+	virtualClock.Store(true)
+}
+
+// DisableVirtualClock 关闭虚拟时钟，恢复使用系统时间
+func DisableVirtualClock() {
+	virtualClock.Store(false)
+
+	virtualListenerLock.Lock()
+	virtualListeners = nil
+	virtualListenerLock.Unlock()
+}
+
+// IsVirtualClock 是否开启了虚拟时钟
+func IsVirtualClock() bool {
+	return virtualClock.Load()
+}
+
+// SetVirtualTaskTimeout 设置Advance等待回调执行完成的最长时间，超时后继续推进
+func SetVirtualTaskTimeout(timeout time.Duration) {
+	virtualTaskTimeout = timeout
+}
+
+// RegVirtualClockListener 注册虚拟时钟的监听者
+func RegVirtualClockListener(listener IVirtualClockListener) {
+	virtualListenerLock.Lock()
+	virtualListeners = append(virtualListeners, listener)
+	virtualListenerLock.Unlock()
+}
+
+// UnRegVirtualClockListener 取消注册虚拟时钟的监听者
+func UnRegVirtualClockListener(listener IVirtualClockListener) {
+	virtualListenerLock.Lock()
+	defer virtualListenerLock.Unlock()
+
+	for i, l := range virtualListeners {
+		if l == listener {
+			virtualListeners = append(virtualListeners[:i], virtualListeners[i+1:]...)
+			return
+		}
+	}
+}
+
+// AddVirtualTask 虚拟时钟下派发了异步执行的回调，执行完成后需要调用DoneVirtualTask
+func AddVirtualTask() {
+	virtualTaskNum.Add(1)
+}
+
+// DoneVirtualTask 异步回调执行完成
+func DoneVirtualTask() {
+	virtualTaskNum.Add(-1)
+}
+
+func getVirtualNow() time.Time {
+	virtualNowLock.RLock()
+	defer virtualNowLock.RUnlock()
+
+	return virtualNow
+}
+
+func setVirtualNow(now time.Time) {
+	virtualNowLock.Lock()
+	virtualNow = now
+	virtualNowLock.Unlock()
+}
+
+// Advance 虚拟时钟前进d，期间到期的定时器与监听者按时间顺序处理，返回时所有回调已经执行完成
+func Advance(d time.Duration) {
+	if IsVirtualClock() == false {
+		log.Error("virtual clock is not enabled")
+		return
+	}
+
+	virtualAdvanceLock.Lock()
+	defer virtualAdvanceLock.Unlock()
+
+	target := getVirtualNow().Add(d)
+	for {
+		//定时器按Now()计算触发时间，包含时间偏移
+		nextTime, ok := nextVirtualFireTime()
+		if ok == false {
+			break
+		}
+
+		nextTime = nextTime.Add(-timeOffset)
+		if nextTime.After(target) {
+			break
+		}
+
+		if nextTime.After(getVirtualNow()) {
+			setVirtualNow(nextTime)
+		}
+
+		fireVirtual(Now())
+		waitVirtualTask()
+	}
+
+	setVirtualNow(target)
+}
+
+// nextVirtualFireTime 定时器与监听者中最早需要处理的时间
+func nextVirtualFireTime() (time.Time, bool) {
+	var nextTime time.Time
+	found := false
+
+	timerHeapLock.Lock()
+	if timerHeap.Len() > 0 {
+		nextTime = timerHeap.timers[0].GetFireTime()
+		found = true
+	}
+	timerHeapLock.Unlock()
+
+	virtualListenerLock.Lock()
+	listenerList := append([]IVirtualClockListener{}, virtualListeners...)
+	virtualListenerLock.Unlock()
+
+	for _, listener := range listenerList {
+		fireTime, ok := listener.NextFireTime()
+		if ok == true && (found == false || fireTime.Before(nextTime)) {
+			nextTime = fireTime
+			found = true
+		}
+	}
+
+	return nextTime, found
+}
+
+// fireVirtual 派发所有已经到期的定时器，再通知到期的监听者
+func fireVirtual(now time.Time) {
+	for {
+		timerHeapLock.Lock()
+		if timerHeap.Len() == 0 || timerHeap.timers[0].GetFireTime().After(now) {
+			timerHeapLock.Unlock()
+			break
+		}
+
+		t := heap.Pop(&timerHeap).(ITimer)
+		timerHeapLock.Unlock()
+
+		t.Open(false)
+		AddVirtualTask()
+		t.AppendChannel(virtualTimer{ITimer: t})
+	}
+
+	virtualListenerLock.Lock()
+	listenerList := append([]IVirtualClockListener{}, virtualListeners...)
+	virtualListenerLock.Unlock()
+
+	for _, listener := range listenerList {
+		if fireTime, ok := listener.NextFireTime(); ok == true && fireTime.After(now) == false {
+			listener.OnClock(now)
+		}
+	}
+}
+
+// waitVirtualTask 等待派发的回调执行完成，回调中产生的新定时器在下一步处理
+func waitVirtualTask() {
+	deadline := time.Now().Add(virtualTaskTimeout)
+	for i := 0; virtualTaskNum.Load() > 0; i++ {
+		//回调通常很快执行完成，先让出调度再休眠
+		if i < 100 {
+			runtime.Gosched()
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			log.Warn("wait virtual clock task timeout", log.Int64("taskNum", virtualTaskNum.Load()))
+			virtualTaskNum.Store(0)
+			return
+		}
+		time.Sleep(50 * time.Microsecond)
+	}
+}
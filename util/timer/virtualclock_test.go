@@ -0,0 +1,123 @@
+package timer
+
+import (
+	"testing"
+	"time"
+)
+
+func startVirtualClock(t *testing.T) *Dispatcher {
+	EnableVirtualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	StartTimer(time.Millisecond, 100)
+
+	dispatcher := NewDispatcher(100)
+	chanClose := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case ti := <-dispatcher.ChanTimer:
+				ti.Do()
+			case <-chanClose:
+				return
+			}
+		}
+	}()
+
+	t.Cleanup(func() {
+		close(chanClose)
+		DisableVirtualClock()
+	})
+	return dispatcher
+}
+
+func TestVirtualClockAfterFunc(t *testing.T) {
+	dispatcher := startVirtualClock(t)
+	start := Now()
+
+	var fireList []string
+	var fireTime time.Time
+	dispatcher.AfterFunc(7*24*time.Hour, nil, func(*Timer) {
+		fireList = append(fireList, "expire")
+		fireTime = Now()
+	}, nil, nil)
+	dispatcher.AfterFunc(time.Hour, nil, func(*Timer) { fireList = append(fireList, "1h") }, nil, nil)
+	dispatcher.AfterFunc(time.Minute, nil, func(*Timer) {
+		fireList = append(fireList, "1m")
+		//回调中创建的定时器在同一次Advance中按时间顺序触发
+		dispatcher.AfterFunc(time.Minute, nil, func(*Timer) { fireList = append(fireList, "2m") }, nil, nil)
+	}, nil, nil)
+
+	Advance(7*24*time.Hour - time.Second)
+	if len(fireList) != 3 || fireList[0] != "1m" || fireList[1] != "2m" || fireList[2] != "1h" {
+		t.Fatalf("unexpected fire order %v", fireList)
+	}
+
+	Advance(time.Second)
+	if len(fireList) != 4 || fireList[3] != "expire" {
+		t.Fatalf("unexpected fire order %v", fireList)
+	}
+
+	if fireTime.Sub(start) != 7*24*time.Hour {
+		t.Fatalf("unexpected fire time %v", fireTime.Sub(start))
+	}
+
+	if Now().Sub(start) != 7*24*time.Hour {
+		t.Fatalf("unexpected now %v", Now().Sub(start))
+	}
+}
+
+func TestVirtualClockTickerAndCron(t *testing.T) {
+	dispatcher := startVirtualClock(t)
+
+	tickNum := 0
+	dispatcher.TickerFunc(time.Minute, nil, func(*Ticker) { tickNum++ }, nil, func(ITimer) {})
+
+	cronExpr, err := NewCronExpr("0 0 5 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cronList []time.Time
+	dispatcher.CronFunc(cronExpr, nil, func(*Cron) { cronList = append(cronList, Now()) }, nil, func(ITimer) {})
+
+	Advance(3 * 24 * time.Hour)
+	if tickNum != 3*24*60 {
+		t.Fatalf("unexpected tick num %d", tickNum)
+	}
+
+	if len(cronList) != 3 {
+		t.Fatalf("unexpected cron fire times %v", cronList)
+	}
+	for i, fireTime := range cronList {
+		if fireTime.Equal(time.Date(2024, 1, 1+i, 5, 0, 0, 0, time.UTC)) == false {
+			t.Fatalf("unexpected cron fire time %v", fireTime)
+		}
+	}
+}
+
+type testClockListener struct {
+	nextTime time.Time
+	fireList []time.Time
+}
+
+func (l *testClockListener) NextFireTime() (time.Time, bool) {
+	return l.nextTime, l.nextTime.IsZero() == false
+}
+
+func (l *testClockListener) OnClock(now time.Time) {
+	l.fireList = append(l.fireList, now)
+	l.nextTime = time.Time{}
+}
+
+func TestVirtualClockListener(t *testing.T) {
+	dispatcher := startVirtualClock(t)
+
+	var timerTime time.Time
+	dispatcher.AfterFunc(2*time.Second, nil, func(*Timer) { timerTime = Now() }, nil, nil)
+
+	listener := &testClockListener{nextTime: Now().Add(time.Second)}
+	RegVirtualClockListener(listener)
+
+	Advance(3 * time.Second)
+	if len(listener.fireList) != 1 || listener.fireList[0].Before(timerTime) == false {
+		t.Fatalf("listener should fire before timer %v %v", listener.fireList, timerTime)
+	}
+}
@@ -0,0 +1,187 @@
+package timer
+
+import (
+	"sync"
+	"time"
+)
+
+// 分层时间轮，第一层256个槽，其余四层各64个槽，与Linux内核定时器相同
+const (
+	wheelRootBits  = 8
+	wheelLevelBits = 6
+	wheelRootSize  = 1 << wheelRootBits
+	wheelLevelSize = 1 << wheelLevelBits
+	wheelRootMask  = wheelRootSize - 1
+	wheelLevelMask = wheelLevelSize - 1
+	wheelLevelNum  = 4
+	wheelMaxTicks  = 1<<(wheelRootBits+wheelLevelNum*wheelLevelBits) - 1
+)
+
+// timingWheel 分层时间轮，添加定时器的开销为O(1)，超出最大范围的定时器在最高层等待，降层时按触发时间重新放置
+type timingWheel struct {
+	locker    sync.Mutex
+	startTime time.Time
+	interval  time.Duration
+	curTick   uint64 //下一个待处理的刻度
+	timerNum  int
+	rootNum   int                //第一层中的定时器数量
+	levelNum  [wheelLevelNum]int //其余各层中的定时器数量
+	root      [wheelRootSize][]ITimer
+	levels    [wheelLevelNum][wheelLevelSize][]ITimer
+}
+
+func newTimingWheel(startTime time.Time, interval time.Duration) *timingWheel {
+	return &timingWheel{startTime: startTime, interval: interval}
+}
+
+// expireTick 触发时间所在的刻度，向上取整，保证不会提前触发
+func (tw *timingWheel) expireTick(fireTime time.Time) uint64 {
+	d := fireTime.Sub(tw.startTime)
+	if d <= 0 {
+		return 0
+	}
+
+	return uint64((d + tw.interval - 1) / tw.interval)
+}
+
+// nowTick 当前时间已经到达的刻度
+func (tw *timingWheel) nowTick(now time.Time) uint64 {
+	d := now.Sub(tw.startTime)
+	if d <= 0 {
+		return 0
+	}
+
+	return uint64(d / tw.interval)
+}
+
+func (tw *timingWheel) add(t ITimer) {
+	tw.locker.Lock()
+	tw.place(t)
+	tw.timerNum++
+	tw.locker.Unlock()
+}
+
+// place 按剩余刻度放入对应的层与槽，需要在锁中调用
+func (tw *timingWheel) place(t ITimer) {
+	expire := tw.expireTick(t.GetFireTime())
+	if expire < tw.curTick {
+		//已经到期，放入下一个待处理的槽
+		expire = tw.curTick
+	}
+
+	idx := expire - tw.curTick
+	if idx < wheelRootSize {
+		slot := expire & wheelRootMask
+		tw.root[slot] = append(tw.root[slot], t)
+		tw.rootNum++
+		return
+	}
+
+	if idx > wheelMaxTicks {
+		expire = tw.curTick + wheelMaxTicks
+		idx = wheelMaxTicks
+	}
+
+	for level := 0; level < wheelLevelNum; level++ {
+		shift := wheelRootBits + level*wheelLevelBits
+		if idx < 1<<(shift+wheelLevelBits) || level == wheelLevelNum-1 {
+			slot := (expire >> shift) & wheelLevelMask
+			tw.levels[level][slot] = append(tw.levels[level][slot], t)
+			tw.levelNum[level]++
+			return
+		}
+	}
+}
+
+// cascade 将高层槽中的定时器重新放入低层，返回槽索引，为0时需要继续处理更高一层
+func (tw *timingWheel) cascade(level int) uint64 {
+	slot := (tw.curTick >> (wheelRootBits + level*wheelLevelBits)) & wheelLevelMask
+	timerList := tw.levels[level][slot]
+	tw.levels[level][slot] = nil
+	tw.levelNum[level] -= len(timerList)
+	for _, t := range timerList {
+		tw.place(t)
+	}
+
+	return slot
+}
+
+// advance 处理到当前时间为止的所有刻度，返回到期的定时器
+func (tw *timingWheel) advance(now time.Time, expireList []ITimer) []ITimer {
+	target := tw.nowTick(now)
+
+	tw.locker.Lock()
+	defer tw.locker.Unlock()
+
+	//没有定时器时直接跳到目标刻度，避免时间偏移后空转
+	if tw.timerNum == 0 && target >= tw.curTick {
+		tw.curTick = target + 1
+		return expireList
+	}
+
+	for tw.curTick <= target {
+		tw.skipEmpty(target)
+		if tw.curTick > target {
+			break
+		}
+
+		slot := tw.curTick & wheelRootMask
+		if slot == 0 {
+			for level := 0; level < wheelLevelNum; level++ {
+				if tw.cascade(level) != 0 {
+					break
+				}
+			}
+		}
+
+		expireList = append(expireList, tw.root[slot]...)
+		tw.timerNum -= len(tw.root[slot])
+		tw.rootNum -= len(tw.root[slot])
+		clear(tw.root[slot])
+		tw.root[slot] = tw.root[slot][:0]
+		tw.curTick++
+	}
+
+	return expireList
+}
+
+// skipEmpty 低层没有定时器时直接跳到下一次需要降层的刻度，不会跳过目标刻度
+func (tw *timingWheel) skipEmpty(target uint64) {
+	if tw.rootNum > 0 {
+		return
+	}
+
+	shift := wheelRootBits
+	for level := 0; level < wheelLevelNum-1 && tw.levelNum[level] == 0; level++ {
+		shift += wheelLevelBits
+	}
+
+	next := (tw.curTick>>shift + 1) << shift
+	if tw.curTick&(1<<shift-1) == 0 {
+		//当前刻度需要降层
+		return
+	}
+
+	tw.curTick = min(next, target+1)
+}
+
+func (tw *timingWheel) len() int {
+	tw.locker.Lock()
+	defer tw.locker.Unlock()
+
+	return tw.timerNum
+}
+
+func wheelTickRoutine(tw *timingWheel, minTimerInterval time.Duration) {
+	var expireList []ITimer
+	for {
+		time.Sleep(minTimerInterval)
+
+		expireList = tw.advance(Now(), expireList[:0])
+		for i, t := range expireList {
+			t.Open(false)
+			t.AppendChannel(t)
+			expireList[i] = nil
+		}
+	}
+}
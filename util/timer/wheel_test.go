@@ -0,0 +1,215 @@
+package timer
+
+import (
+	"container/heap"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+const benchTimerNum = 1000000
+
+func newTestTimer(fireTime time.Time, id uint64) *Timer {
+	return &Timer{Id: id, fireTime: fireTime}
+}
+
+func TestTimingWheelExpire(t *testing.T) {
+	start := time.Now()
+	interval := 10 * time.Millisecond
+	tw := newTimingWheel(start, interval)
+
+	//覆盖各层以及超出最大范围的定时器
+	delayList := []time.Duration{
+		0,
+		15 * time.Millisecond,
+		2550 * time.Millisecond,
+		2570 * time.Millisecond,
+		3 * time.Minute,
+		2 * time.Hour,
+		50 * time.Hour,
+		30 * 24 * time.Hour,
+		600 * 24 * time.Hour,
+	}
+	for i, d := range delayList {
+		tw.add(newTestTimer(start.Add(d), uint64(i)))
+	}
+
+	for i, d := range delayList {
+		fireTime := start.Add(d)
+
+		//触发时间之前不会到期
+		if d > 0 {
+			if expireList := tw.advance(fireTime.Add(-time.Millisecond), nil); len(expireList) != 0 {
+				t.Fatalf("timer %d expired early at %s", expireList[0].GetId(), d)
+			}
+		}
+
+		//到达触发时间后的一个刻度内到期
+		expireList := tw.advance(fireTime.Add(interval), nil)
+		if len(expireList) != 1 || expireList[0].GetId() != uint64(i) {
+			t.Fatalf("timer %d not expired at %s, %d expired", i, d, len(expireList))
+		}
+	}
+
+	if tw.len() != 0 {
+		t.Fatalf("unexpected timer num %d", tw.len())
+	}
+}
+
+func TestTimingWheelAddExpired(t *testing.T) {
+	start := time.Now()
+	tw := newTimingWheel(start, 10*time.Millisecond)
+	tw.advance(start.Add(time.Second), nil)
+
+	//已经过期的定时器在下一个刻度触发
+	tw.add(newTestTimer(start, 1))
+	expireList := tw.advance(start.Add(time.Second+10*time.Millisecond), nil)
+	if len(expireList) != 1 {
+		t.Fatalf("expired timer is not fired")
+	}
+}
+
+func TestTimingWheelRandom(t *testing.T) {
+	start := time.Now()
+	interval := 10 * time.Millisecond
+	tw := newTimingWheel(start, interval)
+
+	const timerNum = 10000
+	mapFireTime := map[uint64]time.Time{}
+	for i := uint64(0); i < timerNum; i++ {
+		fireTime := start.Add(time.Duration(rand.Int63n(int64(10 * time.Hour))))
+		mapFireTime[i] = fireTime
+		tw.add(newTestTimer(fireTime, i))
+	}
+
+	for now := start; tw.len() > 0; now = now.Add(time.Minute) {
+		for _, expire := range tw.advance(now, nil) {
+			fireTime := mapFireTime[expire.GetId()]
+			if fireTime.After(now) || now.Sub(fireTime) > time.Minute+interval {
+				t.Fatalf("timer %d fire time %s expired at %s", expire.GetId(), fireTime, now)
+			}
+			delete(mapFireTime, expire.GetId())
+		}
+	}
+
+	if len(mapFireTime) != 0 {
+		t.Fatalf("%d timers not expired", len(mapFireTime))
+	}
+}
+
+func newBenchTimerList(start time.Time, num int) []*Timer {
+	timerList := make([]*Timer, 0, num)
+	for i := 0; i < num; i++ {
+		fireTime := start.Add(time.Duration(rand.Int63n(int64(time.Hour))))
+		timerList = append(timerList, newTestTimer(fireTime, uint64(i)))
+	}
+
+	return timerList
+}
+
+// BenchmarkHeapAdd 已有1M活跃定时器时向堆中添加定时器
+func BenchmarkHeapAdd(b *testing.B) {
+	start := time.Now()
+	var locker sync.Mutex
+	h := _TimerHeap{timers: make([]ITimer, 0, benchTimerNum+b.N)}
+	for _, t := range newBenchTimerList(start, benchTimerNum) {
+		heap.Push(&h, t)
+	}
+	timerList := newBenchTimerList(start, b.N)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		locker.Lock()
+		heap.Push(&h, timerList[i])
+		locker.Unlock()
+	}
+}
+
+// BenchmarkWheelAdd 已有1M活跃定时器时向时间轮中添加定时器
+func BenchmarkWheelAdd(b *testing.B) {
+	start := time.Now()
+	tw := newTimingWheel(start, 10*time.Millisecond)
+	for _, t := range newBenchTimerList(start, benchTimerNum) {
+		tw.add(t)
+	}
+	timerList := newBenchTimerList(start, b.N)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tw.add(timerList[i])
+	}
+}
+
+// BenchmarkHeapExpire 1M活跃定时器在一小时内全部到期
+func BenchmarkHeapExpire(b *testing.B) {
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		h := _TimerHeap{timers: make([]ITimer, 0, benchTimerNum)}
+		for _, t := range newBenchTimerList(start, benchTimerNum) {
+			heap.Push(&h, t)
+		}
+		b.StartTimer()
+
+		now := start.Add(time.Hour)
+		for h.Len() > 0 && h.timers[0].GetFireTime().After(now) == false {
+			heap.Pop(&h)
+		}
+	}
+}
+
+// BenchmarkWheelExpire 1M活跃定时器在一小时内全部到期
+func BenchmarkWheelExpire(b *testing.B) {
+	start := time.Now()
+	interval := 10 * time.Millisecond
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		tw := newTimingWheel(start, interval)
+		for _, t := range newBenchTimerList(start, benchTimerNum) {
+			tw.add(t)
+		}
+		b.StartTimer()
+
+		var expireList []ITimer
+		for now := start; now.Before(start.Add(time.Hour + interval)); now = now.Add(interval) {
+			expireList = tw.advance(now, expireList[:0])
+		}
+	}
+}
+
+// BenchmarkHeapAddParallel 多协程并发添加
+func BenchmarkHeapAddParallel(b *testing.B) {
+	start := time.Now()
+	var locker sync.Mutex
+	h := _TimerHeap{timers: make([]ITimer, 0, benchTimerNum)}
+	for _, t := range newBenchTimerList(start, benchTimerNum) {
+		heap.Push(&h, t)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			t := newTestTimer(start.Add(time.Duration(rand.Int63n(int64(time.Hour)))), 0)
+			locker.Lock()
+			heap.Push(&h, t)
+			locker.Unlock()
+		}
+	})
+}
+
+// BenchmarkWheelAddParallel 多协程并发添加
+func BenchmarkWheelAddParallel(b *testing.B) {
+	start := time.Now()
+	tw := newTimingWheel(start, 10*time.Millisecond)
+	for _, t := range newBenchTimerList(start, benchTimerNum) {
+		tw.add(t)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			tw.add(newTestTimer(start.Add(time.Duration(rand.Int63n(int64(time.Hour)))), 0))
+		}
+	})
+}